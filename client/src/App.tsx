@@ -1,14 +1,17 @@
 import { useState, useEffect } from "react";
 import "./App.css";
-import type { User } from "./types/user";
+import { type User, type Language, resolveLanguage } from "./types/user";
+import { authFetch } from "./services/auth";
 import PokerTable from "./components/PokerTable";
 import UserInfoCompact from "./components/UserInfoCompact";
 import GameHistory from "./components/GameHistory";
+import ChatPanel from "./components/ChatPanel";
 import {
   wsService,
   type GameState,
   type Player as WSPlayer,
   getHandName,
+  setHandNameLanguage,
 } from "./services/websocket";
 import ShowdownModal from "./components/ShowdownModal";
 
@@ -34,6 +37,9 @@ const convertWSPlayerToLocal = (wsPlayer: WSPlayer, _: number) => {
     winAmount: wsPlayer.winAmount,
     status: wsPlayer.status,
     isReady: wsPlayer.isReady,
+    stats: wsPlayer.stats,
+    sittingOut: wsPlayer.sittingOut,
+    disconnected: wsPlayer.disconnected,
   };
 };
 
@@ -50,7 +56,7 @@ function App() {
   // 获取用户信息
   const fetchUser = async () => {
     try {
-      const response = await fetch("/api/user");
+      const response = await authFetch("/api/user");
       const data = await response.json();
       setUser(data);
     } catch (error) {
@@ -62,7 +68,7 @@ function App() {
   // 更新用户名
   const updateUserName = async (name: string) => {
     try {
-      const response = await fetch("/api/user/name", {
+      const response = await authFetch("/api/user/name", {
         method: "PUT",
         headers: {
           "Content-Type": "application/json",
@@ -77,13 +83,36 @@ function App() {
     }
   };
 
+  // 更新语言偏好
+  const updateUserLanguage = async (language: Language) => {
+    try {
+      const response = await authFetch("/api/user/language", {
+        method: "PUT",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({ language }),
+      });
+      const data = await response.json();
+      setUser(data);
+    } catch (error) {
+      console.error("Failed to update user language:", error);
+      setErrorMessage("更新语言失败，请重试");
+    }
+  };
+
+  // 牌型名称跟随用户的语言
+  useEffect(() => {
+    setHandNameLanguage(resolveLanguage(user));
+  }, [user]);
+
   // 更新用户头像
   const updateUserAvatar = async (file: File) => {
     try {
       const formData = new FormData();
       formData.append('avatar', file);
 
-      const response = await fetch("/api/user/avatar", {
+      const response = await authFetch("/api/user/avatar", {
         method: "PUT",
         body: formData,
       });
@@ -178,7 +207,7 @@ function App() {
 
   const handleRaise = () => {
     // 显示加注输入框
-    const minRaise = (gameState?.currentBet || 0) + (gameState?.bigBlind || 20);
+    const minRaise = getMinRaise();
     setRaiseAmount(minRaise.toString());
     setShowRaiseInput(true);
   };
@@ -193,7 +222,7 @@ function App() {
       return;
     }
 
-    const minRaise = (gameState?.currentBet || 0) + (gameState?.bigBlind || 20);
+    const minRaise = getMinRaise();
     if (amount < minRaise) {
       setErrorMessage(`加注金额至少需要 ${minRaise}`);
       // 重置为最小加注金额
@@ -236,6 +265,9 @@ function App() {
 
   // 获取最小加注金额
   const getMinRaise = () => {
+    if (gameState?.minRaiseTo) {
+      return gameState.minRaiseTo;
+    }
     return (gameState?.currentBet || 0) + (gameState?.bigBlind || 20);
   };
 
@@ -274,6 +306,15 @@ function App() {
     return userPlayer?.isReady || false;
   };
 
+  // 检查当前用户是否暂时离开
+  const getCurrentUserSittingOut = () => {
+    if (!gameState || !user) return false;
+    const userPlayer = gameState.players.find(
+      (player) => player.userId === user.id
+    );
+    return userPlayer?.sittingOut || false;
+  };
+
   // 获取当前用户的筹码数量
   const getCurrentUserChipsInSeat = () => {
     if (!gameState || !user) return 0;
@@ -293,6 +334,21 @@ function App() {
     wsService.unready();
   };
 
+  // 暂时离开，保留座位
+  const handleSitOut = () => {
+    wsService.sitOut();
+  };
+
+  // 回到座位
+  const handleSitIn = () => {
+    wsService.sitIn();
+  };
+
+  // 补码，补足到最大买入
+  const handleRebuy = () => {
+    wsService.rebuy();
+  };
+
   // 检查是否可以显示准备按钮
   const canShowReadyButton = () => {
     if (!gameState || !currentUserSeat) return false;
@@ -463,7 +519,9 @@ function App() {
         user={user} 
         onUpdateName={updateUserName} 
         onUpdateAvatar={updateUserAvatar}
+        onUpdateLanguage={updateUserLanguage}
       />
+      <ChatPanel user={user} hostId={gameState?.table?.hostId} />
 
       {/* 倒计时显示 - 使用flexbox居中 */}
       {canShowReadyButton() &&
@@ -561,6 +619,24 @@ function App() {
               >
                 ⚠️ 筹码不足，无法准备
               </div>
+              <button
+                onClick={handleRebuy}
+                style={{
+                  padding: "10px 20px",
+                  fontSize: "14px",
+                  fontWeight: "bold",
+                  backgroundColor: "#4CAF50",
+                  color: "white",
+                  border: "none",
+                  borderRadius: "8px",
+                  cursor: "pointer",
+                  boxShadow: "0 2px 6px rgba(0,0,0,0.3)",
+                  transition: "all 0.2s ease",
+                  minWidth: "120px",
+                }}
+              >
+                补码
+              </button>
               <button
                 onClick={() => handleLeave(currentUserSeat!)}
                 style={{
@@ -586,14 +662,14 @@ function App() {
                 离开座位
               </button>
             </div>
-          ) : !getCurrentUserReady() ? (
+          ) : getCurrentUserSittingOut() ? (
             <button
-              onClick={handleReady}
+              onClick={handleSitIn}
               style={{
                 padding: "12px 24px",
                 fontSize: "16px",
                 fontWeight: "bold",
-                backgroundColor: "#4CAF50",
+                backgroundColor: "#17a2b8",
                 color: "white",
                 border: "none",
                 borderRadius: "8px",
@@ -603,8 +679,50 @@ function App() {
                 minWidth: "120px",
               }}
             >
-              准备
+              回到座位
             </button>
+          ) : !getCurrentUserReady() ? (
+            <div
+              style={{
+                display: "flex",
+                flexDirection: "column",
+                alignItems: "center",
+                gap: "8px",
+              }}
+            >
+              <button
+                onClick={handleReady}
+                style={{
+                  padding: "12px 24px",
+                  fontSize: "16px",
+                  fontWeight: "bold",
+                  backgroundColor: "#4CAF50",
+                  color: "white",
+                  border: "none",
+                  borderRadius: "8px",
+                  cursor: "pointer",
+                  boxShadow: "0 4px 8px rgba(0,0,0,0.3)",
+                  transition: "all 0.2s ease",
+                  minWidth: "120px",
+                }}
+              >
+                准备
+              </button>
+              <button
+                onClick={handleSitOut}
+                style={{
+                  padding: "6px 16px",
+                  fontSize: "13px",
+                  backgroundColor: "#6c757d",
+                  color: "white",
+                  border: "none",
+                  borderRadius: "6px",
+                  cursor: "pointer",
+                }}
+              >
+                暂时离开
+              </button>
+            </div>
           ) : (
             <button
               onClick={handleUnready}
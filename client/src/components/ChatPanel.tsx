@@ -0,0 +1,141 @@
+import { useEffect, useRef, useState } from 'react'
+import type { User } from '../types/user'
+import { wsService, type ChatMessage } from '../services/websocket'
+
+interface ChatPanelProps {
+  user: User
+  hostId?: string // 房主可以禁言其他用户
+}
+
+export default function ChatPanel({ user, hostId }: ChatPanelProps) {
+  const [messages, setMessages] = useState<ChatMessage[]>([])
+  const [text, setText] = useState('')
+  const [error, setError] = useState<string | null>(null)
+  const [muted, setMuted] = useState<Set<string>>(new Set()) // 房主本次禁言的用户
+  const listRef = useRef<HTMLDivElement>(null)
+  const isHost = hostId === user.id
+
+  useEffect(() => wsService.onChat(setMessages), [])
+
+  // 新消息到达时滚动到底部
+  useEffect(() => {
+    listRef.current?.scrollTo({ top: listRef.current.scrollHeight })
+  }, [messages])
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (text.trim() === '') return
+    const result = await wsService.chat(text)
+    if (result.ok) {
+      setText('')
+      setError(null)
+    } else {
+      setError(result.message)
+    }
+  }
+
+  const toggleMute = async (userId: string) => {
+    const mute = !muted.has(userId)
+    const result = await wsService.mutePlayer(userId, mute)
+    if (!result.ok) {
+      setError(result.message)
+      return
+    }
+    setMuted(prev => {
+      const next = new Set(prev)
+      if (mute) {
+        next.add(userId)
+      } else {
+        next.delete(userId)
+      }
+      return next
+    })
+  }
+
+  return (
+    <div
+      style={{
+        position: 'fixed',
+        left: '20px',
+        bottom: '20px',
+        width: '280px',
+        display: 'flex',
+        flexDirection: 'column',
+        gap: '8px',
+        background: 'rgba(0, 0, 0, 0.8)',
+        padding: '12px',
+        borderRadius: '12px',
+        backdropFilter: 'blur(10px)',
+        border: '1px solid rgba(255, 255, 255, 0.1)',
+        zIndex: 10,
+      }}
+    >
+      <div ref={listRef} style={{ height: '180px', overflowY: 'auto', fontSize: '13px' }}>
+        {messages.map((message, index) => (
+          <div key={`${message.time}-${index}`} style={{ marginBottom: '4px', wordBreak: 'break-word' }}>
+            {message.dealer ? (
+              <span style={{ color: '#ffd54f' }}>🃏 {message.text}</span>
+            ) : (
+              <>
+                <span style={{ color: '#81c784' }}>{message.name}: </span>
+                <span style={{ color: 'white' }}>{message.text}</span>
+                {isHost && message.userId && message.userId !== user.id && (
+                  <button
+                    type="button"
+                    onClick={() => toggleMute(message.userId!)}
+                    style={{
+                      marginLeft: '6px',
+                      background: 'transparent',
+                      color: '#aaa',
+                      border: 'none',
+                      cursor: 'pointer',
+                      fontSize: '12px',
+                    }}
+                  >
+                    {muted.has(message.userId) ? '解除禁言' : '禁言'}
+                  </button>
+                )}
+              </>
+            )}
+          </div>
+        ))}
+      </div>
+
+      {error && <div style={{ color: '#f44336', fontSize: '12px' }}>{error}</div>}
+
+      <form onSubmit={handleSubmit} style={{ display: 'flex', gap: '8px' }}>
+        <input
+          type="text"
+          value={text}
+          maxLength={200}
+          onChange={(e) => setText(e.target.value)}
+          placeholder="说点什么..."
+          style={{
+            flex: 1,
+            background: 'rgba(255, 255, 255, 0.1)',
+            border: '1px solid rgba(255, 255, 255, 0.2)',
+            borderRadius: '4px',
+            padding: '4px 8px',
+            color: 'white',
+            fontSize: '14px',
+            outline: 'none',
+          }}
+        />
+        <button
+          type="submit"
+          style={{
+            background: '#4CAF50',
+            color: 'white',
+            border: 'none',
+            borderRadius: '4px',
+            padding: '4px 8px',
+            cursor: 'pointer',
+            fontSize: '14px',
+          }}
+        >
+          发送
+        </button>
+      </form>
+    </div>
+  )
+}
@@ -1,4 +1,4 @@
-import type { Card } from "../services/websocket";
+import type { Card, SeatStats } from "../services/websocket";
 import { useEffect } from "react";
 
 interface Player {
@@ -13,6 +13,9 @@ interface Player {
   };
   winAmount?: number;
   status?: string;
+  stats?: SeatStats;
+  sittingOut?: boolean;
+  disconnected?: boolean;
 }
 
 // 获取花色符号
@@ -250,7 +253,12 @@ export default function PlayerSeat({
               color: "#FFD700",
               fontSize: "14px",
               fontWeight: "bold",
-              opacity: player.status === "folded" ? 0.5 : 1,
+              opacity:
+                player.status === "folded" ||
+                player.sittingOut ||
+                player.disconnected
+                  ? 0.5
+                  : 1,
             }}
           >
             {player.name}
@@ -262,8 +270,24 @@ export default function PlayerSeat({
               marginTop: "2px",
             }}
           >
-            {player.chips}
+            {player.disconnected
+              ? `${player.chips} · 断线`
+              : player.sittingOut
+              ? `${player.chips} · 暂离`
+              : player.chips}
           </div>
+          {player.stats && player.stats.hands > 0 && (
+            <div
+              title={`${player.stats.hands} 手 · 3-bet ${player.stats.threeBet}% · ${player.stats.bbPer100} bb/100`}
+              style={{
+                color: "#aaa",
+                fontSize: "10px",
+                marginTop: "2px",
+              }}
+            >
+              {player.stats.vpip}/{player.stats.pfr}/{player.stats.af}
+            </div>
+          )}
         </div>
       )}
     </div>
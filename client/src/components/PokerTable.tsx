@@ -1,7 +1,7 @@
 import React, { useEffect } from "react";
 import CommunityCards from "./CommunityCards";
 import PlayerSeat from "./PlayerSeat";
-import type { Card } from "../services/websocket";
+import type { Card, SeatStats } from "../services/websocket";
 import { getHandName } from "../services/websocket";
 
 // 获取花色符号
@@ -51,6 +51,9 @@ interface Player {
   winAmount?: number;
   status?: string;
   isReady?: boolean;
+  stats?: SeatStats;
+  sittingOut?: boolean;
+  disconnected?: boolean;
 }
 
 export default function PokerTable({
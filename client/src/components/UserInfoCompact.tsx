@@ -1,14 +1,15 @@
 import { useState } from 'react'
-import type { User } from '../types/user'
+import { type User, type Language, resolveLanguage } from '../types/user'
 import Avatar from './Avatar'
 
 interface UserInfoCompactProps {
   user: User
   onUpdateName: (name: string) => Promise<void>
   onUpdateAvatar: (file: File) => Promise<void>
+  onUpdateLanguage: (language: Language) => Promise<void>
 }
 
-export default function UserInfoCompact({ user, onUpdateName, onUpdateAvatar }: UserInfoCompactProps) {
+export default function UserInfoCompact({ user, onUpdateName, onUpdateAvatar, onUpdateLanguage }: UserInfoCompactProps) {
   const [isEditing, setIsEditing] = useState(false)
   const [newName, setNewName] = useState(user.name)
 
@@ -110,6 +111,22 @@ export default function UserInfoCompact({ user, onUpdateName, onUpdateAvatar }:
           >
             {user.name}
           </span>
+          <button
+            type="button"
+            title="切换语言 / Switch language"
+            onClick={() => onUpdateLanguage(resolveLanguage(user) === 'en' ? 'zh' : 'en')}
+            style={{
+              background: 'rgba(255, 255, 255, 0.1)',
+              color: 'white',
+              border: '1px solid rgba(255, 255, 255, 0.2)',
+              borderRadius: '4px',
+              padding: '2px 6px',
+              cursor: 'pointer',
+              fontSize: '12px',
+            }}
+          >
+            {resolveLanguage(user) === 'en' ? 'EN' : '中'}
+          </button>
         </div>
       )}
     </div>
@@ -0,0 +1,77 @@
+import type { User } from '../types/user';
+
+const TOKEN_KEY = 'holdem_token';
+
+// 登录、注册和游客接口的返回值
+export interface AuthResponse {
+  token: string;
+  user: User;
+}
+
+// 获取本地保存的会话令牌
+export function getToken(): string | null {
+  return localStorage.getItem(TOKEN_KEY);
+}
+
+// 保存会话令牌
+export function setToken(token: string) {
+  localStorage.setItem(TOKEN_KEY, token);
+}
+
+// 清除会话令牌
+export function clearToken() {
+  localStorage.removeItem(TOKEN_KEY);
+}
+
+// 确保有可用的会话令牌，没有时以游客身份获取
+export async function ensureSession(): Promise<string> {
+  const token = getToken();
+  if (token) return token;
+
+  const response = await fetch('/api/auth/guest', { method: 'POST' });
+  const data = await response.json();
+  if (!response.ok) throw new Error(data.error);
+  setToken(data.token);
+  return data.token;
+}
+
+// 带会话令牌的 fetch，令牌失效时重新获取游客令牌并重试一次
+export async function authFetch(input: string, init: RequestInit = {}): Promise<Response> {
+  const send = async (token: string) => {
+    const headers = new Headers(init.headers);
+    headers.set('Authorization', `Bearer ${token}`);
+    return fetch(input, { ...init, headers });
+  };
+
+  const response = await send(await ensureSession());
+  if (response.status !== 401) return response;
+
+  clearToken();
+  return send(await ensureSession());
+}
+
+// 注册账号，当前游客的数据会保留到新账号；填写管理员签发的认领码时继承旧版本用户的数据
+export async function register(username: string, password: string, claimCode = ''): Promise<AuthResponse> {
+  const response = await authFetch('/api/auth/register', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ username, password, claimCode }),
+  });
+  const data = await response.json();
+  if (!response.ok) throw new Error(data.error);
+  setToken(data.token);
+  return data;
+}
+
+// 账号密码登录
+export async function login(username: string, password: string): Promise<AuthResponse> {
+  const response = await fetch('/api/auth/login', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ username, password }),
+  });
+  const data = await response.json();
+  if (!response.ok) throw new Error(data.error);
+  setToken(data.token);
+  return data;
+}
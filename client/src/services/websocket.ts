@@ -1,9 +1,15 @@
+import { ensureSession } from './auth';
+import { type Language, resolveLanguage } from '../types/user';
+
 // WebSocket消息类型
 export type MessageType = 
   | 'game_state'
+  | 'game_delta'
+  | 'session'
   | 'player_action'
   | 'game_update'
   | 'error'
+  | 'ack'
   | 'sit_down'
   | 'leave_seat'
   | 'start_game'
@@ -11,16 +17,43 @@ export type MessageType =
   | 'call'
   | 'raise'
   | 'check'
+  | 'allin'
   | 'end_game'
+  | 'rebuy'
+  | 'top_up'
+  | 'sit_out'
+  | 'sit_in'
+  | 'resync'
   | 'ready'
-  | 'unready';
+  | 'unready'
+  | 'pause_game'
+  | 'resume_game'
+  | 'set_blinds'
+  | 'kick_player'
+  | 'mute_player'
+  | 'chat'
+  | 'chat_history';
 
 // WebSocket消息结构
 export interface WSMessage {
   type: MessageType;
+  id?: string; // 请求ID，服务器在 ack 或 error 中带回
   data: any;
 }
 
+// 错误消息数据，对应某个请求时带有请求ID和消息类型
+export interface ErrorData {
+  id?: string;
+  type?: MessageType;
+  code: string;    // 错误码，如 NOT_YOUR_TURN、SEAT_TAKEN
+  message: string; // 错误描述
+}
+
+// 请求的处理结果
+export type RequestResult =
+  | { ok: true }
+  | { ok: false; code: string; message: string };
+
 // 扑克牌类型
 export interface Card {
   suit: string;  // 花色: hearts, diamonds, clubs, spades
@@ -47,6 +80,51 @@ export interface Player {
   handRank?: HandRank;  // 牌型（摊牌时显示）
   winAmount?: number;   // 本局赢得的金额
   isReady?: boolean;    // 是否已准备
+  raiseLocked?: boolean; // 不完整加注后只能跟注或弃牌
+  timeBank?: number;     // 剩余时间银行（秒）
+  stats?: SeatStats;     // 历史统计数据
+  rebuys?: number;       // 本次落座的补码次数
+  sittingOut?: boolean;  // 是否暂时离开
+  missedBlinds?: number; // 回来后需要补交的盲注
+  orbitsAway?: number;   // 已离开的圈数
+  disconnected?: boolean; // 是否断线，宽限期内保留座位和行动机会
+  additions?: ChipAddition[]; // 上一局结束后补充的筹码
+}
+
+// 两局之间补充的一笔筹码
+export interface ChipAddition {
+  type: 'rebuy' | 'top_up';
+  amount: number;
+}
+
+// 随座位广播的关键统计数据
+export interface SeatStats {
+  hands: number;     // 手数
+  vpip: number;      // 主动入池率（%）
+  pfr: number;       // 翻前加注率（%）
+  af: number;        // 激进度
+  threeBet: number;  // 3-bet 率（%）
+  bbPer100: number;  // 每百手赢得的大盲数
+}
+
+// 玩家的完整统计数据，由 /users/:id/stats 返回
+export interface PlayerStats extends SeatStats {
+  userId: string;
+  vpipHands: number;
+  pfrHands: number;
+  threeBetOpps: number;
+  threeBets: number;
+  postflopAggr: number;
+  postflopCall: number;
+  sawFlop: number;
+  showdowns: number;
+  showdownsWon: number;
+  handsWon: number;
+  chipsWon: number;
+  bigBlindsWon: number;
+  wtsd: number;     // 看到翻牌后摊牌的比例（%）
+  wsd: number;      // 摊牌胜率（%）
+  winRate: number;  // 赢得底池的比例（%）
 }
 
 // 对局记录中的玩家信息
@@ -61,6 +139,7 @@ export interface PlayerRoundInfo {
   status: string;
   holeCards: Card[];
   handRank: string;
+  additions?: ChipAddition[]; // 这一局开始前补充的筹码
 }
 
 // 获胜者信息
@@ -73,16 +152,76 @@ export interface PlayerWinningInfo {
   holeCards: Card[];
 }
 
+// 底池（主池或边池）
+export interface Pot {
+  amount: number;       // 底池金额
+  eligible: number[];   // 有资格争夺该底池的玩家座位索引
+  winners?: { position: number; amount: number }[]; // 该底池的获胜者
+}
+
 // 对局记录
 export interface GameRound {
   roundId: string;
+  tableName: string;        // 牌桌名称
   startTime: number;
   endTime: number;
   dealerPos: number;
   pot: number;
+  pots: Pot[];              // 主池和边池明细
   communityCards: Card[];
   players: PlayerRoundInfo[];
   winners: PlayerWinningInfo[];
+  actions: Action[];        // 按顺序记录的所有行动
+}
+
+// 回放中某一步的玩家状态
+export interface ReplayPlayer {
+  position: number;
+  userId: string;
+  name: string;
+  chips: number;
+  currentBet: number;
+  totalBet: number;
+  status: string;
+  holeCards: Card[];        // 不可见时为空
+  handRank?: string;
+}
+
+// 回放中的一步
+export interface ReplayStep {
+  index: number;
+  action?: Action;          // 初始状态和摊牌步骤为空
+  phase: string;
+  pot: number;
+  pots: Pot[];
+  currentBet: number;
+  currentPlayer: number;
+  communityCards: Card[];
+  players: ReplayPlayer[];
+}
+
+// GET /game/records/:roundId/replay 的返回值
+export interface Replay {
+  roundId: string;
+  dealerPos: number;
+  smallBlindPos: number;
+  bigBlindPos: number;
+  steps: ReplayStep[];
+}
+
+// 一次行动记录
+export interface Action {
+  street: string;
+  position: number;
+  userId: string;
+  name: string;
+  type: 'ante' | 'small_blind' | 'big_blind' | 'fold' | 'check' | 'call' | 'bet' | 'raise' | 'uncalled';
+  amount: number;           // 本次投入的筹码（退还时为退还的筹码）
+  raiseTo?: number;         // 下注或加注后本轮的总注额
+  allIn: boolean;
+  auto?: boolean;           // 超时自动行动
+  pot: number;              // 行动后的底池
+  time: number;             // 毫秒时间戳
 }
 
 // 游戏状态类型
@@ -92,12 +231,23 @@ export interface GameState {
   gamePhase: string;        // 当前游戏阶段
   communityCards: Card[];   // 公共牌
   pot: number;              // 底池
+  pots: Pot[];              // 主池和边池明细
+  tableId: string;          // 牌桌ID
+  roundId: string;          // 本局的对局ID
+  actions: Action[];        // 本局的行动记录
+  handStartTime: number;    // 本局开始时间
   currentBet: number;       // 当前下注额
+  lastRaise: number;        // 本轮上一次完整加注的增量
+  minRaiseTo: number;       // 当前行动玩家最少加注到的金额（0表示不能加注）
+  maxRaiseTo: number;       // 当前行动玩家最多加注到的金额（全下）
   dealerPos: number;        // 庄家位置
   currentPlayer: number;    // 当前行动玩家
   smallBlind: number;       // 小盲注
   bigBlind: number;         // 大盲注
+  ante: number;             // 前注
   countdownTimer: number;    // 倒计时（秒）
+  actionTimer: number;       // 当前行动玩家的剩余行动时间（秒）
+  usingTimeBank: boolean;    // 当前行动玩家是否正在消耗时间银行
   spectators: number;        // 观众数量
   
   // 摊牌相关字段
@@ -107,17 +257,116 @@ export interface GameState {
 
   // 对局记录
   currentRound?: GameRound; // 当前对局记录，用于结算展示
+
+  // 牌桌配置
+  config: TableConfig;
+  paused: boolean;
+  table?: TableState;
+}
+
+// 游戏状态中附带的牌桌信息
+export interface TableState {
+  id: string;
+  name: string;
+  hostId: string;
+  private: boolean;
+  hasPassword: boolean;
+  inviteCode?: string; // 只有房主能看到
+}
+
+// 牌桌配置
+export interface TableConfig {
+  name: string;
+  seats: number;
+  minPlayers: number;
+  smallBlind: number;
+  bigBlind: number;
+  ante: number;
+  minBuyIn: number;
+  maxBuyIn: number;
+  maxRebuys: number; // 每次落座允许的补码次数，0 表示不允许
+  missedBlinds: boolean; // 暂时离开错过大盲的玩家回来时是否补交
+  sitOutOrbits: number;  // 暂时离开超过多少圈后自动离座，0 表示不自动离座
+  disconnectGrace: number; // 断线后保留座位和行动机会的宽限期（秒）
+  disconnectAction: 'fold' | 'sit_out'; // 宽限期结束后的处理方式
+  bettingStructure: string; // no_limit / pot_limit
+  actionTimeout: number;
+  timeBank: number;
+  startCountdown: number;
+  showdownInterval: number;
+}
+
+// 增量协议版本，加入时收到完整状态，之后只收到变化的事件
+const PROTOCOL_VERSION = 2;
+
+// 本地保留的最大聊天消息数，与服务器保留的最近消息数一致
+const MAX_CHAT_MESSAGES = 50;
+
+// 会话恢复令牌在 sessionStorage 中的 key
+const RESUME_TOKEN_KEY = 'holdem_resume_token';
+
+// 增量更新中的一个事件
+export type GameEvent =
+  | { type: 'player_acted'; data: Action }
+  | { type: 'card_dealt'; data: { cards: Card[] } }
+  | { type: 'pot_updated'; data: { pot: number; pots: Pot[] } }
+  | { type: 'player_updated'; data: { seat: number; fields: Record<string, any> } }
+  | { type: 'state_changed'; data: { fields: Record<string, any> } }
+  | { type: 'table_updated'; data: TableState };
+
+// 增量更新消息，seq 与上一条游戏状态或增量连续
+export interface GameDelta {
+  seq: number;
+  events: GameEvent[];
+}
+
+// 把字段变化合并到对象上，值为 null 的字段被服务器省略，直接删除
+const mergeFields = <T extends object>(target: T, fields: Record<string, any>): T => {
+  const result: Record<string, any> = { ...target };
+  Object.entries(fields).forEach(([key, value]) => {
+    if (value === null) {
+      delete result[key];
+    } else {
+      result[key] = value;
+    }
+  });
+  return result as T;
+};
+
+// 连接建立后服务器发送的会话信息
+export interface SessionData {
+  resumeToken: string; // 下次重连使用的恢复令牌
+  resumed: boolean;    // 是否恢复了上一次的会话
+  seat: number;        // 所在座位，未落座为 -1
+  holeCards?: Card[];  // 自己的手牌
+}
+
+// 牌桌聊天消息，荷官消息没有 userId，内容已按自己的语言生成
+export interface ChatMessage {
+  time: number;     // 发送时间（毫秒时间戳）
+  userId?: string;  // 发言的用户
+  name?: string;    // 发言的用户名称
+  text: string;
+  dealer?: boolean; // 是否为荷官消息
 }
 
 // 回调函数类型
 type GameStateCallback = (gameState: GameState) => void;
-type ErrorCallback = (error: string) => void;
+type ErrorCallback = (error: string, code: string, type?: MessageType) => void;
+type ChatCallback = (messages: ChatMessage[]) => void;
 
 class WebSocketService {
     private static instance: WebSocketService;
     private ws: WebSocket | null = null;
+    private resumeToken: string | null = sessionStorage.getItem(RESUME_TOKEN_KEY); // 刷新页面后仍能接管座位，其他标签页拿不到
+    private gameState: GameState | null = null; // 增量更新的基准
+    private seq = 0;                            // 最后收到的游戏状态序号
+    private nextRequestId = 1;
+    private pendingRequests = new Map<string, (result: RequestResult) => void>();
     private gameStateCallbacks: GameStateCallback[] = [];
     private errorCallbacks: ErrorCallback[] = [];
+    private chatCallbacks: ChatCallback[] = [];
+    private chatMessages: ChatMessage[] = []; // 加入牌桌后收到的聊天记录
 
     private constructor() {
         this.connect();
@@ -130,12 +379,25 @@ class WebSocketService {
         return WebSocketService.instance;
     }
 
-    private connect() {
+    private async connect() {
         if (this.ws?.readyState === WebSocket.OPEN) return;
 
+        // 浏览器的 WebSocket 不能设置请求头，通过查询参数携带会话令牌
+        let token: string;
+        try {
+            token = await ensureSession();
+        } catch (error) {
+            console.error('Failed to get session token:', error);
+            setTimeout(() => this.connect(), 3000);
+            return;
+        }
+
         // 使用相对路径，让 WebSocket 也通过 Vite 代理
-        // 这样 HTTP API 和 WebSocket 请求都会有相同的源 IP
-        const wsUrl = `ws://${window.location.host}/ws`;
+        // 断线重连时带上恢复令牌，恢复原来的座位和手牌
+        let wsUrl = `ws://${window.location.host}/ws?token=${encodeURIComponent(token)}&protocol=${PROTOCOL_VERSION}`;
+        if (this.resumeToken) {
+            wsUrl += `&resume=${encodeURIComponent(this.resumeToken)}`;
+        }
         this.ws = new WebSocket(wsUrl);
 
         this.ws.onopen = () => {
@@ -144,6 +406,9 @@ class WebSocketService {
 
         this.ws.onclose = () => {
             console.log('WebSocket closed');
+            // 连接断开后不会再收到回复
+            this.pendingRequests.forEach(resolve => resolve({ ok: false, code: 'NOT_CONNECTED', message: '连接已断开' }));
+            this.pendingRequests.clear();
             // 重连逻辑
             setTimeout(() => this.connect(), 3000);
         };
@@ -167,20 +432,95 @@ class WebSocketService {
 
         switch (message.type) {
             case 'game_state':
-                this.gameStateCallbacks.forEach(callback => {
-                    callback(message.data.game);
-                });
+                this.seq = message.data.seq ?? 0;
+                this.gameState = { ...message.data.game, table: message.data.table };
+                this.notifyGameState();
+                break;
+            case 'game_delta':
+                this.applyDelta(message.data);
+                break;
+            case 'session': {
+                const session: SessionData = message.data;
+                this.resumeToken = session.resumeToken;
+                sessionStorage.setItem(RESUME_TOKEN_KEY, session.resumeToken);
+                if (session.resumed) {
+                    console.log('Session resumed, seat:', session.seat);
+                }
+                break;
+            }
+            case 'ack':
+                this.resolveRequest(message.data.id, { ok: true });
+                break;
+            case 'chat_history':
+                // 每次连接都会收到完整的最近记录，替换本地的记录
+                this.chatMessages = message.data.messages ?? [];
+                this.notifyChat();
                 break;
-            case 'error':
+            case 'chat':
+                this.chatMessages = [...this.chatMessages, message.data].slice(-MAX_CHAT_MESSAGES);
+                this.notifyChat();
+                break;
+            case 'error': {
+                const error: ErrorData = message.data;
+                if (error.id) {
+                    this.resolveRequest(error.id, { ok: false, code: error.code, message: error.message });
+                }
                 this.errorCallbacks.forEach(callback => {
-                    callback(message.data.message);
+                    callback(error.message, error.code, error.type);
                 });
                 break;
+            }
             default:
                 console.log('Unhandled message type:', message.type);
         }
     }
 
+    // 应用增量更新，序号不连续时丢弃并请求重新同步
+    private applyDelta(delta: GameDelta) {
+        if (!this.gameState || delta.seq !== this.seq + 1) {
+            console.warn(`Game state gap: expected ${this.seq + 1}, got ${delta.seq}, resyncing`);
+            this.gameState = null;
+            this.sendMessage('resync', {});
+            return;
+        }
+        this.seq = delta.seq;
+
+        let state = this.gameState;
+        delta.events.forEach(event => {
+            switch (event.type) {
+                case 'player_acted':
+                    state = { ...state, actions: [...(state.actions || []), event.data] };
+                    break;
+                case 'card_dealt':
+                    state = { ...state, communityCards: [...(state.communityCards || []), ...event.data.cards] };
+                    break;
+                case 'pot_updated':
+                    state = { ...state, pot: event.data.pot, pots: event.data.pots };
+                    break;
+                case 'player_updated': {
+                    const players = [...state.players];
+                    players[event.data.seat] = mergeFields(players[event.data.seat] || ({} as Player), event.data.fields);
+                    state = { ...state, players };
+                    break;
+                }
+                case 'state_changed':
+                    state = mergeFields(state, event.data.fields);
+                    break;
+                case 'table_updated':
+                    state = { ...state, table: event.data };
+                    break;
+            }
+        });
+        this.gameState = state;
+        this.notifyGameState();
+    }
+
+    private notifyGameState() {
+        const state = this.gameState;
+        if (!state) return;
+        this.gameStateCallbacks.forEach(callback => callback(state));
+    }
+
     // 注册游戏状态回调
     public onGameState(callback: GameStateCallback) {
         this.gameStateCallbacks.push(callback);
@@ -191,69 +531,165 @@ class WebSocketService {
         this.errorCallbacks.push(callback);
     }
 
-    // 发送消息
-    public sendMessage(type: MessageType, data: any) {
-        if (this.ws?.readyState === WebSocket.OPEN) {
-            const message: WSMessage = { type, data };
-            this.ws.send(JSON.stringify(message));
-        } else {
+    private notifyChat() {
+        const messages = this.chatMessages;
+        this.chatCallbacks.forEach(callback => callback(messages));
+    }
+
+    // 注册聊天回调，每次变化时收到全部聊天记录，返回取消注册的函数
+    public onChat(callback: ChatCallback) {
+        this.chatCallbacks.push(callback);
+        callback(this.chatMessages);
+        return () => {
+            this.chatCallbacks = this.chatCallbacks.filter(cb => cb !== callback);
+        };
+    }
+
+    private resolveRequest(id: string, result: RequestResult) {
+        const resolve = this.pendingRequests.get(id);
+        if (resolve) {
+            this.pendingRequests.delete(id);
+            resolve(result);
+        }
+    }
+
+    // 发送消息，返回服务器对该请求的处理结果
+    public sendMessage(type: MessageType, data: any): Promise<RequestResult> {
+        if (this.ws?.readyState !== WebSocket.OPEN) {
             console.error('WebSocket is not connected');
+            return Promise.resolve({ ok: false, code: 'NOT_CONNECTED', message: '未连接到服务器' });
         }
+
+        const id = String(this.nextRequestId++);
+        const message: WSMessage = { type, id, data };
+        this.ws.send(JSON.stringify(message));
+        return new Promise(resolve => this.pendingRequests.set(id, resolve));
     }
 
-    // 发送落座消息
-    public sitDown(seatId: number) {
-        this.sendMessage('sit_down', { seatId });
+    // 发送落座消息，不指定买入金额时按最大买入
+    public sitDown(seatId: number, buyIn?: number) {
+        return this.sendMessage('sit_down', buyIn ? { seatId, buyIn } : { seatId });
     }
 
     // 发送离开座位消息
     public leaveSeat(seatId: number) {
-        this.sendMessage('leave_seat', { seatId });
+        return this.sendMessage('leave_seat', { seatId });
     }
 
     // 发送开始游戏消息
     public startGame() {
-        this.sendMessage('start_game', {});
+        return this.sendMessage('start_game', {});
     }
 
     // 发送弃牌消息
     public fold() {
-        this.sendMessage('fold', {});
+        return this.sendMessage('fold', {});
     }
 
     // 发送跟注消息
     public call() {
-        this.sendMessage('call', {});
+        return this.sendMessage('call', {});
     }
 
     // 发送过牌消息
     public check() {
-        this.sendMessage('check', {});
+        return this.sendMessage('check', {});
     }
 
     // 发送加注消息
     public raise(amount: number) {
-        this.sendMessage('raise', { amount });
+        return this.sendMessage('raise', { amount });
+    }
+
+    // 发送全下消息
+    public allIn() {
+        return this.sendMessage('allin', {});
     }
 
     // 发送结束游戏消息
     public endGame() {
-        this.sendMessage('end_game', {});
+        return this.sendMessage('end_game', {});
+    }
+
+    // 房主暂停游戏
+    public pauseGame() {
+        return this.sendMessage('pause_game', {});
+    }
+
+    // 房主恢复游戏
+    public resumeGame() {
+        return this.sendMessage('resume_game', {});
+    }
+
+    // 房主修改盲注（两局之间）
+    public setBlinds(smallBlind: number, bigBlind: number, ante: number) {
+        return this.sendMessage('set_blinds', { smallBlind, bigBlind, ante });
+    }
+
+    // 房主踢出玩家，ban 为 true 时同时封禁
+    public kickPlayer(seatId: number, ban: boolean = false) {
+        return this.sendMessage('kick_player', { seatId, ban });
+    }
+
+    // 房主禁言或解除禁言，观众也可以被禁言
+    public mutePlayer(userId: string, mute: boolean = true) {
+        return this.sendMessage('mute_player', { userId, mute });
+    }
+
+    // 发送聊天消息
+    public chat(text: string) {
+        return this.sendMessage('chat', { text });
     }
 
     // 准备
     public ready() {
-        this.sendMessage('ready', {});
+        return this.sendMessage('ready', {});
     }
 
     // 取消准备
     public unready() {
-        this.sendMessage('unready', {});
+        return this.sendMessage('unready', {});
     }
+
+    // 暂时离开，保留座位
+    public sitOut() {
+        return this.sendMessage('sit_out', {});
+    }
+
+    // 回到座位
+    public sitIn() {
+        return this.sendMessage('sit_in', {});
+    }
+
+    // 补码，不指定数量时补足到最大买入
+    public rebuy(amount?: number) {
+        return this.sendMessage('rebuy', amount ? { amount } : {});
+    }
+
+    // 加码，不指定数量时补足到最大买入
+    public topUp(amount?: number) {
+        return this.sendMessage('top_up', amount ? { amount } : {});
+    }
+}
+
+// 英文牌型名称，下标为牌型大小
+const HAND_NAMES_EN = [
+  "Unknown", "High Card", "One Pair", "Two Pair", "Three of a Kind", "Straight",
+  "Flush", "Full House", "Four of a Kind", "Straight Flush", "Royal Flush",
+];
+
+// 牌型名称使用的语言，登录后按用户的语言设置
+let handNameLanguage: Language = resolveLanguage();
+
+export function setHandNameLanguage(language: Language) {
+  handNameLanguage = language;
 }
 
 // 获取牌型名称
-export function getHandName(rank: number): string {
+export function getHandName(rank: number, language: Language = handNameLanguage): string {
+  if (language === 'en') {
+    return HAND_NAMES_EN[rank] ?? HAND_NAMES_EN[0];
+  }
   switch (rank) {
     case 10: return "皇家同花顺";
     case 9: return "同花顺";
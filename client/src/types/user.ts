@@ -1,8 +1,30 @@
 export interface User {
   id: string
   name: string
+  username?: string // 登录账号，游客为空
   ip: string
   user_agent: string
   created_at: string
   avatar?: string // 用户头像URL
+  bankroll?: number // 资金
+  language?: Language // 语言偏好，为空时按浏览器语言
+}
+
+// 支持的语言
+export type Language = 'zh' | 'en'
+
+// 用户的语言：有偏好时使用偏好，否则按浏览器语言
+export function resolveLanguage(user?: User | null): Language {
+  if (user?.language) return user.language
+  return navigator.language.toLowerCase().startsWith('en') ? 'en' : 'zh'
+}
+
+// 资金流水
+export interface Transaction {
+  userId: string
+  type: 'grant' | 'buy_in' | 'cash_out'
+  amount: number // 资金变化，买入为负数
+  balance: number // 变化后的资金
+  tableId?: string
+  time: number // 毫秒时间戳
 } 
\ No newline at end of file
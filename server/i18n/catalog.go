@@ -0,0 +1,92 @@
+package i18n
+
+// catalog 所有文本按 key 和语言保存的模板，模板中的参数使用 fmt 格式
+var catalog = map[string]map[Lang]string{
+	// 通用
+	string(ErrBadRequest):          {ZH: "消息数据格式错误", EN: "Malformed message data"},
+	string(ErrUnknownType):         {ZH: "未知的消息类型: %s", EN: "Unknown message type: %s"},
+	string(ErrUnsupportedProtocol): {ZH: "不支持的协议版本", EN: "Unsupported protocol version"},
+	string(ErrInternal):            {ZH: "服务器内部错误，请重试", EN: "Internal server error, please retry"},
+	string(ErrUserNotFound):        {ZH: "用户不存在", EN: "User not found"},
+	string(ErrClaimGuestData):      {ZH: "当前游客已有资金和对局记录，认领旧用户会丢失这些数据，请退出游客身份后再认领", EN: "Your guest account already has a bankroll and hand history that claiming would discard; sign out of it before claiming"},
+
+	// 牌桌和座位
+	string(ErrNotHost):        {ZH: "只有房主可以执行该操作", EN: "Only the host can do this"},
+	string(ErrBanned):         {ZH: "你已被房主移出该牌桌", EN: "You have been removed from this table by the host"},
+	string(ErrInvalidSeat):    {ZH: "座位号无效: %d", EN: "Invalid seat: %d"},
+	string(ErrSeatEmpty):      {ZH: "该座位没有玩家", EN: "That seat is empty"},
+	string(ErrSeatTaken):      {ZH: "座位 %d 已被占用", EN: "Seat %d is already taken"},
+	string(ErrAlreadySeated):  {ZH: "您已经坐在座位 %d", EN: "You are already sitting in seat %d"},
+	string(ErrNotSeated):      {ZH: "请先落座", EN: "Please take a seat first"},
+	string(ErrNotYourSeat):    {ZH: "您不在座位 %d", EN: "You are not sitting in seat %d"},
+	string(ErrSeatDetached):   {ZH: "您的座位由原来的连接保留，断线宽限期结束后才能在此操作", EN: "Your seat is held for your previous session until its reconnect grace period ends"},
+	string(ErrCannotKickSelf): {ZH: "不能踢出自己", EN: "You cannot kick yourself"},
+	string(ErrInvalidBlinds):  {ZH: "盲注设置不合法: %d/%d，前注 %d", EN: "Invalid blinds: %d/%d, ante %d"},
+	string(ErrTableClosing):   {ZH: "牌桌正在关闭", EN: "The table is closing"},
+
+	// 游戏状态
+	string(ErrGameInProgress): {ZH: "游戏进行中，请在本局结束后操作", EN: "A hand is in progress, please wait until it ends"},
+	string(ErrGameNotPlaying): {ZH: "游戏未开始", EN: "The game has not started"},
+	string(ErrGamePaused):     {ZH: "游戏已暂停", EN: "The game is paused"},
+	string(ErrAlreadyPaused):  {ZH: "游戏已经暂停", EN: "The game is already paused"},
+	string(ErrNotPaused):      {ZH: "游戏没有暂停", EN: "The game is not paused"},
+	string(ErrCannotStart):    {ZH: "游戏无法开始，需要至少%d个已准备的玩家", EN: "Cannot start: at least %d ready players are needed"},
+	string(ErrCannotReady):    {ZH: "无法设置准备状态", EN: "Cannot get ready right now"},
+	string(ErrCannotUnready):  {ZH: "无法取消准备状态", EN: "Cannot cancel ready right now"},
+	string(ErrSittingOut):     {ZH: "暂时离开中，请先回到座位", EN: "You are sitting out, please sit back in first"},
+
+	// 行动
+	string(ErrNotInGame):         {ZH: "您未在游戏中", EN: "You are not in this hand"},
+	string(ErrNotYourTurn):       {ZH: "还没轮到您行动", EN: "It is not your turn"},
+	string(ErrCannotAct):         {ZH: "您当前无法行动", EN: "You cannot act right now"},
+	string(ErrCannotCheck):       {ZH: "有人下注，无法过牌", EN: "You cannot check facing a bet"},
+	string(ErrRaiseLocked):       {ZH: "对手不足额全下，您只能跟注或弃牌", EN: "After an incomplete all-in raise you may only call or fold"},
+	string(ErrInsufficientChips): {ZH: "筹码不足以加注，只能跟注全下", EN: "Not enough chips to raise, you can only call all-in"},
+	string(ErrNoChips):           {ZH: "没有筹码可以全下", EN: "You have no chips to go all-in with"},
+	string(ErrInvalidAction):     {ZH: "无效的行动", EN: "Invalid action"},
+	string(ErrActionFailed):      {ZH: "行动失败，请重试", EN: "Action failed, please retry"},
+	string(ErrExceedsPotLimit):   {ZH: "底池限注，全下金额超过底池上限", EN: "Pot limit: this all-in exceeds the pot limit"},
+	string(ErrRaiseTooSmall):     {ZH: "加注金额至少需要 %d", EN: "You must raise to at least %d"},
+	string(ErrRaiseTooLarge):     {ZH: "加注金额最多为 %d", EN: "You can raise to at most %d"},
+
+	// 资金和筹码
+	string(ErrInvalidBuyIn):      {ZH: "买入金额必须在 %d 到 %d 之间", EN: "Buy-in must be between %d and %d"},
+	string(ErrInvalidAddChips):   {ZH: "补充的筹码必须在 %d 到 %d 之间", EN: "Added chips must be between %d and %d"},
+	string(ErrInsufficientFunds): {ZH: "资金不足，当前资金 %d", EN: "Insufficient bankroll, you have %d"},
+	string(ErrOutOfChips):        {ZH: "筹码不足，请补码或离开座位", EN: "You are out of chips, please rebuy or leave the seat"},
+	string(ErrRebuyNotAllowed):   {ZH: "筹码不少于最小买入 %d，不能补码，可以加码", EN: "You have at least the minimum buy-in of %d, top up instead of rebuying"},
+	string(ErrRebuyLimit):        {ZH: "已达到补码次数上限 %d", EN: "You have reached the rebuy limit of %d"},
+	string(ErrTopUpNotAllowed):   {ZH: "筹码少于最小买入 %d，请补码", EN: "You are below the minimum buy-in of %d, rebuy instead"},
+	string(ErrAtMaxBuyIn):        {ZH: "筹码已达到最大买入 %d", EN: "You already have the maximum buy-in of %d"},
+
+	// 聊天
+	string(ErrInvalidChat):    {ZH: "消息不能为空，且不能超过 %d 个字", EN: "Messages must be 1 to %d characters long"},
+	string(ErrMuted):          {ZH: "你已被房主禁言", EN: "You have been muted by the host"},
+	string(ErrRateLimited):    {ZH: "发言太频繁，请稍后再试", EN: "You are sending messages too fast, please slow down"},
+	string(ErrCannotMuteSelf): {ZH: "不能禁言自己", EN: "You cannot mute yourself"},
+
+	// 荷官消息
+	"DEALER_FOLD":     {ZH: "%s 弃牌", EN: "%s folds"},
+	"DEALER_CHECK":    {ZH: "%s 过牌", EN: "%s checks"},
+	"DEALER_CALL":     {ZH: "%s 跟注 %d", EN: "%s calls %d"},
+	"DEALER_BET":      {ZH: "%s 下注 %d", EN: "%s bets %d"},
+	"DEALER_RAISE":    {ZH: "%s 加注到 %d", EN: "%s raises to %d"},
+	"DEALER_ALL_IN":   {ZH: "%s 全下 %d", EN: "%s is all-in for %d"},
+	"DEALER_WIN":      {ZH: "%s 赢得 %d", EN: "%s wins %d"},
+	"DEALER_WIN_HAND": {ZH: "%[1]s 以%[3]s赢得 %[2]d", EN: "%[1]s wins %[2]d with %[3]s"},
+	"DEALER_MUTED":    {ZH: "%s 已被房主禁言", EN: "%s has been muted by the host"},
+	"DEALER_UNMUTED":  {ZH: "%s 已被解除禁言", EN: "%s has been unmuted"},
+
+	// 牌型
+	"HAND_UNKNOWN":         {ZH: "未知", EN: "Unknown"},
+	"HAND_HIGH_CARD":       {ZH: "高牌", EN: "High Card"},
+	"HAND_ONE_PAIR":        {ZH: "一对", EN: "One Pair"},
+	"HAND_TWO_PAIR":        {ZH: "两对", EN: "Two Pair"},
+	"HAND_THREE_OF_A_KIND": {ZH: "三条", EN: "Three of a Kind"},
+	"HAND_STRAIGHT":        {ZH: "顺子", EN: "Straight"},
+	"HAND_FLUSH":           {ZH: "同花", EN: "Flush"},
+	"HAND_FULL_HOUSE":      {ZH: "葫芦", EN: "Full House"},
+	"HAND_FOUR_OF_A_KIND":  {ZH: "四条", EN: "Four of a Kind"},
+	"HAND_STRAIGHT_FLUSH":  {ZH: "同花顺", EN: "Straight Flush"},
+	"HAND_ROYAL_FLUSH":     {ZH: "皇家同花顺", EN: "Royal Flush"},
+}
@@ -0,0 +1,91 @@
+package i18n
+
+// Code 稳定的错误码，客户端据此识别错误类型，同时也是错误消息在目录中的 key
+type Code string
+
+// 错误码
+const (
+	ErrBadRequest          Code = "BAD_REQUEST"          // 消息格式或参数类型错误
+	ErrUnknownType         Code = "UNKNOWN_TYPE"         // 未知的消息类型
+	ErrUnsupportedProtocol Code = "UNSUPPORTED_PROTOCOL" // 不支持的协议版本
+	ErrInternal            Code = "INTERNAL"             // 服务器内部错误
+	ErrUserNotFound        Code = "USER_NOT_FOUND"       // 用户不存在
+	ErrClaimGuestData      Code = "CLAIM_GUEST_DATA"     // 游客已有资金和记录，不能认领旧用户
+
+	// 牌桌和座位
+	ErrNotHost        Code = "NOT_HOST"         // 只有房主可以执行
+	ErrBanned         Code = "BANNED"           // 被房主移出牌桌
+	ErrInvalidSeat    Code = "INVALID_SEAT"     // 座位号无效
+	ErrSeatEmpty      Code = "SEAT_EMPTY"       // 座位上没有玩家
+	ErrSeatTaken      Code = "SEAT_TAKEN"       // 座位已被占用
+	ErrAlreadySeated  Code = "ALREADY_SEATED"   // 已经坐在其他座位
+	ErrNotSeated      Code = "NOT_SEATED"       // 没有落座
+	ErrNotYourSeat    Code = "NOT_YOUR_SEAT"    // 不是自己的座位
+	ErrSeatDetached   Code = "SEAT_DETACHED"    // 座位由持有恢复令牌的会话控制
+	ErrCannotKickSelf Code = "CANNOT_KICK_SELF" // 不能踢出自己
+	ErrInvalidBlinds  Code = "INVALID_BLINDS"   // 盲注设置不合法
+	ErrTableClosing   Code = "TABLE_CLOSING"    // 牌桌正在关闭
+
+	// 游戏状态
+	ErrGameInProgress Code = "GAME_IN_PROGRESS" // 游戏进行中不能执行
+	ErrGameNotPlaying Code = "GAME_NOT_PLAYING" // 游戏未开始
+	ErrGamePaused     Code = "GAME_PAUSED"      // 游戏已暂停
+	ErrAlreadyPaused  Code = "ALREADY_PAUSED"   // 游戏已经暂停
+	ErrNotPaused      Code = "NOT_PAUSED"       // 游戏没有暂停
+	ErrCannotStart    Code = "CANNOT_START"     // 不满足开始游戏的条件
+	ErrCannotReady    Code = "CANNOT_READY"     // 无法准备
+	ErrCannotUnready  Code = "CANNOT_UNREADY"   // 无法取消准备
+	ErrSittingOut     Code = "SITTING_OUT"      // 暂时离开中
+
+	// 行动
+	ErrNotInGame         Code = "NOT_IN_GAME"        // 不在本局游戏中
+	ErrNotYourTurn       Code = "NOT_YOUR_TURN"      // 还没轮到行动
+	ErrCannotAct         Code = "CANNOT_ACT"         // 当前无法行动
+	ErrCannotCheck       Code = "CANNOT_CHECK"       // 有人下注，不能过牌
+	ErrRaiseLocked       Code = "RAISE_LOCKED"       // 不完整加注后只能跟注或弃牌
+	ErrInsufficientChips Code = "INSUFFICIENT_CHIPS" // 筹码不足以加注
+	ErrNoChips           Code = "NO_CHIPS"           // 没有筹码可以全下
+	ErrInvalidAction     Code = "INVALID_ACTION"     // 无效的行动
+	ErrActionFailed      Code = "ACTION_FAILED"      // 行动失败
+	ErrExceedsPotLimit   Code = "EXCEEDS_POT_LIMIT"  // 全下超过底池限注
+	ErrRaiseTooSmall     Code = "RAISE_TOO_SMALL"    // 加注金额不足
+	ErrRaiseTooLarge     Code = "RAISE_TOO_LARGE"    // 加注金额过大
+
+	// 资金和筹码
+	ErrInvalidBuyIn      Code = "INVALID_BUY_IN"     // 买入金额超出范围
+	ErrInvalidAddChips   Code = "INVALID_ADD_CHIPS"  // 补充的筹码超出范围
+	ErrInsufficientFunds Code = "INSUFFICIENT_FUNDS" // 资金不足
+	ErrOutOfChips        Code = "OUT_OF_CHIPS"       // 筹码输光，需要补码
+	ErrRebuyNotAllowed   Code = "REBUY_NOT_ALLOWED"  // 筹码不少于最小买入，不能补码
+	ErrRebuyLimit        Code = "REBUY_LIMIT"        // 补码次数已达上限
+	ErrTopUpNotAllowed   Code = "TOP_UP_NOT_ALLOWED" // 筹码少于最小买入，不能加码
+	ErrAtMaxBuyIn        Code = "AT_MAX_BUY_IN"      // 筹码已达到最大买入
+
+	// 聊天
+	ErrInvalidChat    Code = "INVALID_CHAT"     // 消息为空或过长
+	ErrMuted          Code = "MUTED"            // 被房主禁言
+	ErrRateLimited    Code = "RATE_LIMITED"     // 发言太频繁
+	ErrCannotMuteSelf Code = "CANNOT_MUTE_SELF" // 不能禁言自己
+)
+
+// Error 带错误码的错误，Args 是消息模板的参数
+// 同一个 *Error 可以作为哨兵错误与 errors.Is 比较
+type Error struct {
+	Code Code
+	Args []interface{}
+}
+
+// NewError 创建错误
+func NewError(code Code, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+// Error 返回默认语言的错误消息
+func (e *Error) Error() string {
+	return e.Message(Default)
+}
+
+// Message 返回指定语言的错误消息
+func (e *Error) Message(lang Lang) string {
+	return T(lang, string(e.Code), e.Args...)
+}
@@ -0,0 +1,135 @@
+// Package i18n 提供错误消息和牌型名称的多语言文本
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang 语言
+type Lang string
+
+// 支持的语言
+const (
+	ZH Lang = "zh" // 中文
+	EN Lang = "en" // 英文
+
+	Default = ZH // 没有偏好时使用的语言
+)
+
+// Supported 检查是否支持该语言，支持 zh-CN、en-US 这样带地区的写法
+func Supported(tag string) (Lang, bool) {
+	base := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base = base[:i]
+	}
+	switch Lang(base) {
+	case ZH:
+		return ZH, true
+	case EN:
+		return EN, true
+	}
+	return "", false
+}
+
+// Negotiate 按 Accept-Language 中的权重选择第一个支持的语言，都不支持时使用默认语言
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{tag, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.q <= 0 {
+			continue
+		}
+		if lang, ok := Supported(c.tag); ok {
+			return lang
+		}
+	}
+	return Default
+}
+
+// Resolve 用户设置了语言偏好时优先使用，否则按 Accept-Language 选择
+func Resolve(preference, acceptLanguage string) Lang {
+	if lang, ok := Supported(preference); ok {
+		return lang
+	}
+	return Negotiate(acceptLanguage)
+}
+
+// Key 目录中文本的 key，作为 T 的参数时按同一语言翻译后再格式化
+type Key string
+
+// T 按语言取出 key 对应的文本并用 args 格式化
+// 该语言没有这条文本时使用默认语言，目录中没有时返回 key 本身
+func T(lang Lang, key string, args ...interface{}) string {
+	templates, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	template, ok := templates[lang]
+	if !ok {
+		template = templates[Default]
+	}
+	if len(args) == 0 {
+		return template
+	}
+
+	translated := make([]interface{}, len(args))
+	for i, arg := range args {
+		if k, ok := arg.(Key); ok {
+			arg = T(lang, string(k))
+		}
+		translated[i] = arg
+	}
+	return fmt.Sprintf(template, translated...)
+}
+
+// 牌型名称在目录中的 key，下标为牌型大小（1 高牌 ~ 10 皇家同花顺）
+var handKeys = []string{
+	"HAND_UNKNOWN",
+	"HAND_HIGH_CARD",
+	"HAND_ONE_PAIR",
+	"HAND_TWO_PAIR",
+	"HAND_THREE_OF_A_KIND",
+	"HAND_STRAIGHT",
+	"HAND_FLUSH",
+	"HAND_FULL_HOUSE",
+	"HAND_FOUR_OF_A_KIND",
+	"HAND_STRAIGHT_FLUSH",
+	"HAND_ROYAL_FLUSH",
+}
+
+// HandKey 返回牌型名称的 key，rank 为 1（高牌）到 10（皇家同花顺）
+func HandKey(rank int) Key {
+	if rank < 1 || rank >= len(handKeys) {
+		return Key(handKeys[0])
+	}
+	return Key(handKeys[rank])
+}
+
+// HandName 返回牌型名称
+func HandName(lang Lang, rank int) string {
+	return T(lang, string(HandKey(rank)))
+}
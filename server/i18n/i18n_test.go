@@ -0,0 +1,158 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name           string
+		preference     string
+		acceptLanguage string
+		want           Lang
+	}{
+		{name: "没有偏好时使用默认语言", want: ZH},
+		{name: "带地区的语言", acceptLanguage: "en-US", want: EN},
+		{name: "按权重选择", acceptLanguage: "zh-CN;q=0.8, en;q=0.9", want: EN},
+		{name: "跳过不支持的语言", acceptLanguage: "fr-FR, de;q=0.9, en;q=0.5", want: EN},
+		{name: "权重为零的语言不使用", acceptLanguage: "en;q=0, fr", want: ZH},
+		{name: "都不支持时使用默认语言", acceptLanguage: "ja, ko", want: ZH},
+		{name: "用户偏好优先", preference: "en", acceptLanguage: "zh-CN", want: EN},
+		{name: "不支持的偏好按浏览器选择", preference: "fr", acceptLanguage: "en", want: EN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.preference, tt.acceptLanguage); got != tt.want {
+				t.Errorf("Resolve(%q, %q) = %s, 期望 %s", tt.preference, tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		lang Lang
+		err  *Error
+		want string
+	}{
+		{name: "中文", lang: ZH, err: NewError(ErrInsufficientFunds, 300), want: "资金不足，当前资金 300"},
+		{name: "英文", lang: EN, err: NewError(ErrInsufficientFunds, 300), want: "Insufficient bankroll, you have 300"},
+		{name: "多个参数", lang: EN, err: NewError(ErrInvalidBuyIn, 400, 1000), want: "Buy-in must be between 400 and 1000"},
+		{name: "不支持的语言使用默认语言", lang: "fr", err: NewError(ErrNotSeated), want: "请先落座"},
+		{name: "目录中没有的错误码", lang: EN, err: NewError("NO_SUCH_CODE"), want: "NO_SUCH_CODE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Message(tt.lang); got != tt.want {
+				t.Errorf("Message(%s) = %q, 期望 %q", tt.lang, got, tt.want)
+			}
+		})
+	}
+
+	// Error 返回默认语言的消息
+	if got := NewError(ErrNotSeated).Error(); got != "请先落座" {
+		t.Errorf("Error() = %q, 期望 %q", got, "请先落座")
+	}
+}
+
+func TestHandName(t *testing.T) {
+	tests := []struct {
+		rank int
+		lang Lang
+		want string
+	}{
+		{rank: 1, lang: EN, want: T(EN, "HAND_HIGH_CARD")},
+		{rank: 10, lang: ZH, want: T(ZH, "HAND_ROYAL_FLUSH")},
+		{rank: 0, lang: EN, want: T(EN, "HAND_UNKNOWN")},
+		{rank: 11, lang: ZH, want: T(ZH, "HAND_UNKNOWN")},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.rank)+string(tt.lang), func(t *testing.T) {
+			if got := HandName(tt.lang, tt.rank); got != tt.want {
+				t.Errorf("HandName(%s, %d) = %q, 期望 %q", tt.lang, tt.rank, got, tt.want)
+			}
+		})
+	}
+
+	// 作为参数的 Key 按同一语言翻译
+	want := "Bob wins 450 with " + T(EN, "HAND_FLUSH")
+	if got := T(EN, "DEALER_WIN_HAND", "Bob", 450, HandKey(6)); got != want {
+		t.Errorf("T = %q, 期望 %q", got, want)
+	}
+}
+
+// verbs 匹配模板中的 fmt 参数，包括 %[2]d 这样指定位置的参数
+var verbs = regexp.MustCompile(`%(\[\d+\])?[-+# 0-9.]*[a-zA-Z]`)
+
+// errorCodes 从 errors.go 中找出所有 Code 类型的常量
+func errorCodes(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	if err != nil {
+		t.Fatalf("解析 errors.go 失败: %v", err)
+	}
+
+	codes := make([]string, 0)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "Code" {
+				continue
+			}
+			for _, v := range value.Values {
+				if lit, ok := v.(*ast.BasicLit); ok {
+					code, _ := strconv.Unquote(lit.Value)
+					codes = append(codes, code)
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func TestCatalogComplete(t *testing.T) {
+	codes := errorCodes(t)
+	if len(codes) == 0 {
+		t.Fatalf("没有找到错误码")
+	}
+	for _, code := range codes {
+		if _, ok := catalog[code]; !ok {
+			t.Errorf("错误码 %s 没有文本", code)
+		}
+	}
+	for _, key := range handKeys {
+		if _, ok := catalog[key]; !ok {
+			t.Errorf("牌型 %s 没有文本", key)
+		}
+	}
+
+	// 每条文本都有所有语言的模板，参数的个数和类型一致，指定位置的参数在不同语言中可以换序
+	for key, templates := range catalog {
+		for _, lang := range []Lang{ZH, EN} {
+			if templates[lang] == "" {
+				t.Errorf("%s 缺少 %s 文本", key, lang)
+			}
+		}
+		zh, en := verbs.FindAllString(templates[ZH], -1), verbs.FindAllString(templates[EN], -1)
+		sort.Strings(zh)
+		sort.Strings(en)
+		if !reflect.DeepEqual(zh, en) {
+			t.Errorf("%s 的参数不一致: %v, %v", key, zh, en)
+		}
+	}
+}
@@ -1,28 +1,184 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/poker"
 	"github.com/lllllan02/holdem/service"
+	"github.com/lllllan02/holdem/storage"
+)
+
+// 存储相关的命令行参数
+var (
+	storageKind = flag.String("storage", "file", "存储方式（file/bolt）")
+	dbPath      = flag.String("db", storage.DefaultPath, "bolt 数据库文件路径")
+	importFiles = flag.Bool("import", false, "把 JSON 文件中的用户和对局记录导入 bolt 数据库后退出")
+	claimUser   = flag.String("claim-code", "", "为指定ID的旧版本用户签发认领码后退出，用户注册时填写认领码即可继承数据")
 )
 
 func main() {
+	config := loadTableConfig()
+
+	// 认领码只依赖签名密钥，服务运行期间也可以签发
+	if *claimUser != "" {
+		log.Printf("旧用户 %s 的认领码: %s", *claimUser, service.IssueClaimCode(*claimUser))
+		os.Exit(0)
+	}
+
+	// 必须在加载用户和创建牌桌之前设置存储
+	closeStorage := openStorage()
+	defer closeStorage()
+
+	// 根据牌桌配置创建默认牌桌
+	if err := service.InitTables(config); err != nil {
+		log.Fatalf("牌桌配置无效: %v", err)
+	}
+
 	r := gin.Default()
 
 	// 配置可信任的代理，确保 ClientIP 获取的一致性
 	r.SetTrustedProxies([]string{"127.0.0.1", "::1", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"})
 
+	// 登录注册
+	r.POST("/auth/guest", service.GuestHandler)
+	r.POST("/auth/register", service.RegisterHandler)
+	r.POST("/auth/login", service.LoginHandler)
+
+	// 需要会话令牌的接口
+	auth := service.AuthRequired()
+
 	// 注册路由
-	r.GET("/user", service.GetUserHandler)
-	r.PUT("/user/name", service.UpdateUserNameHandler)
-	r.PUT("/user/avatar", service.UpdateUserAvatarHandler)
+	r.GET("/user", auth, service.GetUserHandler)
+	r.PUT("/user/name", auth, service.UpdateUserNameHandler)
+	r.PUT("/user/language", auth, service.UpdateUserLanguageHandler)
+	r.PUT("/user/avatar", auth, service.UpdateUserAvatarHandler)
+	r.GET("/user/ledger", auth, service.GetLedgerHandler)
 	r.GET("/avatar/:userId", service.GetAvatarHandler)
+	r.GET("/users/:id/stats", service.UserStatsHandler)
 	r.GET("/game/records", service.GetGameRecordsHandler)
+	r.GET("/game/records/export", service.ExportGameRecordsHandler)
+	r.GET("/game/records/:roundId", service.ExportGameRecordHandler)
+	r.GET("/game/records/:roundId/replay", service.GetReplayHandler)
+	r.GET("/game/records/:roundId/chat", service.GetRoundChatHandler)
+
+	// 大厅
+	r.GET("/tables", service.ListTablesHandler)
+	r.POST("/tables", auth, service.CreateTableHandler)
+	r.GET("/tables/:id", service.GetTableHandler)
+	r.GET("/tables/:id/settlement", auth, service.SettlementHandler)
+	r.GET("/tables/invite/:code", service.GetTableByInviteCodeHandler)
+	r.DELETE("/tables/:id", auth, service.CloseTableHandler)
 
 	// WebSocket 连接
-	r.GET("/ws", service.WebSocketHandler)
+	r.GET("/ws", auth, service.WebSocketHandler)
+	r.GET("/ws/:tableId", auth, service.WebSocketHandler)
 
-	log.Printf("Server started")
+	log.Printf("Server started - 牌桌: %s, 盲注: %d/%d, 座位: %d",
+		config.Name, config.SmallBlind, config.BigBlind, config.Seats)
 	r.Run(":8080")
 }
+
+// openStorage 根据命令行参数设置用户和对局记录的存储，返回关闭存储的函数
+func openStorage() func() {
+	switch *storageKind {
+	case "file":
+		if *importFiles {
+			log.Fatalf("导入需要指定 -storage bolt")
+		}
+		if err := service.InitUsers(service.NewDefaultUserStore()); err != nil {
+			log.Fatalf("加载用户数据失败: %v", err)
+		}
+		return func() {}
+
+	case "bolt":
+		db, err := storage.OpenBolt(*dbPath)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		if *importFiles {
+			result, err := storage.ImportFiles(db, service.NewDefaultUserStore(), poker.NewDefaultRecordStore())
+			db.Close()
+			if err != nil {
+				log.Fatalf("导入失败: %v", err)
+			}
+			log.Printf("导入完成 - 用户: %d, 资金流水: %d, 对局: %d, 聊天消息: %d", result.Users, result.Txs, result.Rounds, result.Chat)
+			os.Exit(0)
+		}
+
+		if err := service.InitUsers(db); err != nil {
+			log.Fatalf("加载用户数据失败: %v", err)
+		}
+		poker.SetRecordStore(db)
+		log.Printf("使用数据库存储: %s", *dbPath)
+		return func() { db.Close() }
+
+	default:
+		log.Fatalf("未知的存储方式: %s", *storageKind)
+		return nil
+	}
+}
+
+// loadTableConfig 从配置文件和命令行参数加载牌桌配置，命令行参数优先
+func loadTableConfig() poker.TableConfig {
+	defaults := poker.DefaultTableConfig()
+
+	configFile := flag.String("config", "", "牌桌配置文件路径（.yaml/.yml/.json）")
+	seats := flag.Int("seats", defaults.Seats, "座位数量（2-10）")
+	smallBlind := flag.Int("small-blind", defaults.SmallBlind, "小盲注")
+	bigBlind := flag.Int("big-blind", defaults.BigBlind, "大盲注")
+	ante := flag.Int("ante", defaults.Ante, "前注")
+	minBuyIn := flag.Int("min-buyin", defaults.MinBuyIn, "最小买入")
+	maxBuyIn := flag.Int("max-buyin", defaults.MaxBuyIn, "最大买入")
+	maxRebuys := flag.Int("max-rebuys", defaults.MaxRebuys, "每次落座允许的补码次数（0表示不允许）")
+	disconnectGrace := flag.Int("disconnect-grace", defaults.DisconnectGrace, "断线后保留座位和行动机会的宽限期（秒）")
+	disconnectAction := flag.String("disconnect-action", defaults.DisconnectAction, "宽限期结束后的处理方式（fold/sit_out）")
+	betting := flag.String("betting", defaults.BettingStructure, "下注结构（no_limit/pot_limit）")
+	actionTimeout := flag.Int("action-timeout", defaults.ActionTimeout, "每次行动的时限（秒）")
+	timeBank := flag.Int("time-bank", defaults.TimeBank, "每个座位的时间银行（秒）")
+	flag.Parse()
+
+	config := defaults
+	if *configFile != "" {
+		loaded, err := poker.LoadTableConfig(*configFile)
+		if err != nil {
+			log.Fatalf("加载牌桌配置失败: %v", err)
+		}
+		config = loaded
+	}
+
+	// 只覆盖命令行中显式指定的参数
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seats":
+			config.Seats = *seats
+		case "small-blind":
+			config.SmallBlind = *smallBlind
+		case "big-blind":
+			config.BigBlind = *bigBlind
+		case "ante":
+			config.Ante = *ante
+		case "min-buyin":
+			config.MinBuyIn = *minBuyIn
+		case "max-buyin":
+			config.MaxBuyIn = *maxBuyIn
+		case "max-rebuys":
+			config.MaxRebuys = *maxRebuys
+		case "disconnect-grace":
+			config.DisconnectGrace = *disconnectGrace
+		case "disconnect-action":
+			config.DisconnectAction = *disconnectAction
+		case "betting":
+			config.BettingStructure = *betting
+		case "action-timeout":
+			config.ActionTimeout = *actionTimeout
+		case "time-bank":
+			config.TimeBank = *timeBank
+		}
+	})
+
+	return config
+}
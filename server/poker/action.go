@@ -0,0 +1,74 @@
+package poker
+
+import "time"
+
+// 行动记录类型
+const (
+	ActionAnte        = "ante"         // 前注
+	ActionSmallBlind  = "small_blind"  // 小盲注
+	ActionBigBlind    = "big_blind"    // 大盲注
+	ActionMissedBlind = "missed_blind" // 暂时离开后回来补交的盲注，与大盲注一样计入本轮下注
+	ActionFold        = "fold"         // 弃牌
+	ActionCheck       = "check"        // 过牌
+	ActionCall        = "call"         // 跟注
+	ActionBet         = "bet"          // 下注（本轮第一个下注）
+	ActionRaise       = "raise"        // 加注
+	ActionUncalled    = "uncalled"     // 无人跟注的下注退还
+)
+
+// Action 一局中的一次行动记录，按发生顺序追加
+// 全下不是单独的类型，而是跟注、下注或加注时 AllIn 为 true
+type Action struct {
+	Street   string `json:"street"`            // 行动发生的阶段
+	Position int    `json:"position"`          // 座位位置
+	UserId   string `json:"userId"`            // 用户ID
+	Name     string `json:"name"`              // 玩家名称
+	Type     string `json:"type"`              // 行动类型
+	Amount   int    `json:"amount"`            // 本次投入的筹码（退还时为退还的筹码）
+	RaiseTo  int    `json:"raiseTo,omitempty"` // 下注或加注后本轮的总注额
+	AllIn    bool   `json:"allIn"`             // 本次行动后是否全下
+	Auto     bool   `json:"auto,omitempty"`    // 是否为超时自动行动
+	Pot      int    `json:"pot"`               // 行动后的底池
+	Time     int64  `json:"time"`              // 行动时间（毫秒时间戳）
+}
+
+// recordAction 追加一条行动记录
+func (g *Game) recordAction(pos int, actionType string, amount int, raiseTo int, auto bool) {
+	// 退还发生在结算时，归入最后一个下注的阶段
+	street := g.GamePhase
+	if actionType == ActionUncalled && len(g.Actions) > 0 {
+		street = g.Actions[len(g.Actions)-1].Street
+	}
+
+	player := &g.Players[pos]
+	g.Actions = append(g.Actions, Action{
+		Street:   street,
+		Position: pos,
+		UserId:   player.UserId,
+		Name:     player.Name,
+		Type:     actionType,
+		Amount:   amount,
+		RaiseTo:  raiseTo,
+		AllIn:    player.Status == PlayerStatusAllIn,
+		Auto:     auto,
+		Pot:      g.Pot,
+		Time:     time.Now().UnixMilli(),
+	})
+}
+
+// classifyAction 根据行动前后的下注情况确定记录的行动类型
+// 全下和加注请求最终可能只是跟注，所以按实际效果而不是请求的行动来记录
+func classifyAction(action string, betBefore int, currentBetBefore int, player *Player) string {
+	switch {
+	case action == "fold":
+		return ActionFold
+	case player.CurrentBet > currentBetBefore && currentBetBefore == 0:
+		return ActionBet
+	case player.CurrentBet > currentBetBefore:
+		return ActionRaise
+	case player.CurrentBet > betBefore:
+		return ActionCall
+	default:
+		return ActionCheck
+	}
+}
@@ -0,0 +1,26 @@
+package poker
+
+// ChatMessage 牌桌聊天消息，与对局记录保存在同一个存储中，便于事后核查争议
+type ChatMessage struct {
+	TableID string `json:"tableId"`           // 牌桌ID
+	RoundID string `json:"roundId,omitempty"` // 发送时进行中或刚结束的一局，第一局开始前为空
+	Time    int64  `json:"time"`              // 发送时间（毫秒时间戳）
+	UserId  string `json:"userId,omitempty"`  // 发言的用户，荷官消息为空
+	Name    string `json:"name,omitempty"`    // 发言的用户名称
+	Text    string `json:"text"`              // 内容，荷官消息为默认语言的文本
+	Dealer  bool   `json:"dealer,omitempty"`  // 是否为荷官播报的消息
+
+	// 荷官消息的文本 key 和参数，发送时按客户端的语言生成内容
+	Key  string        `json:"key,omitempty"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// SaveChatMessage 保存一条聊天消息
+func SaveChatMessage(message *ChatMessage) error {
+	return recordStore.SaveChat(message)
+}
+
+// GetRoundChat 获取一局期间以及结束后到下一局开始前的聊天消息，按发送顺序排列
+func GetRoundChat(roundID string) ([]*ChatMessage, error) {
+	return recordStore.ListChat(roundID)
+}
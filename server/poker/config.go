@@ -0,0 +1,160 @@
+package poker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 下注结构常量
+const (
+	BettingNoLimit  = "no_limit"  // 无限注
+	BettingPotLimit = "pot_limit" // 底池限注
+)
+
+// 断线宽限期结束后的处理方式
+const (
+	DisconnectFold   = "fold"    // 保留座位，轮到时自动过牌或弃牌，两局之间自动准备
+	DisconnectSitOut = "sit_out" // 转为暂时离开
+)
+
+// 座位数量范围
+const (
+	MinSeatCount = 2
+	MaxSeatCount = 10
+)
+
+// TableConfig 牌桌配置
+type TableConfig struct {
+	Name             string `json:"name" yaml:"name"`                         // 牌桌名称
+	Seats            int    `json:"seats" yaml:"seats"`                       // 座位数量（2-10）
+	MinPlayers       int    `json:"minPlayers" yaml:"minPlayers"`             // 开始游戏所需的最少玩家数
+	SmallBlind       int    `json:"smallBlind" yaml:"smallBlind"`             // 小盲注
+	BigBlind         int    `json:"bigBlind" yaml:"bigBlind"`                 // 大盲注
+	Ante             int    `json:"ante" yaml:"ante"`                         // 前注（0表示不收前注）
+	MinBuyIn         int    `json:"minBuyIn" yaml:"minBuyIn"`                 // 最小买入
+	MaxBuyIn         int    `json:"maxBuyIn" yaml:"maxBuyIn"`                 // 最大买入
+	MaxRebuys        int    `json:"maxRebuys" yaml:"maxRebuys"`               // 每次落座允许的补码次数（0表示不允许补码）
+	MissedBlinds     bool   `json:"missedBlinds" yaml:"missedBlinds"`         // 暂时离开期间错过大盲的玩家，回来时是否需要补交
+	SitOutOrbits     int    `json:"sitOutOrbits" yaml:"sitOutOrbits"`         // 暂时离开超过多少圈后自动离座（0表示不自动离座）
+	DisconnectGrace  int    `json:"disconnectGrace" yaml:"disconnectGrace"`   // 断线后保留座位和行动机会的宽限期（秒）
+	DisconnectAction string `json:"disconnectAction" yaml:"disconnectAction"` // 宽限期结束后的处理方式（fold/sit_out）
+	BettingStructure string `json:"bettingStructure" yaml:"bettingStructure"` // 下注结构
+	ActionTimeout    int    `json:"actionTimeout" yaml:"actionTimeout"`       // 每次行动的时限（秒）
+	TimeBank         int    `json:"timeBank" yaml:"timeBank"`                 // 每个座位本次落座的时间银行（秒）
+	StartCountdown   int    `json:"startCountdown" yaml:"startCountdown"`     // 全员准备后的开局倒计时（秒）
+	ShowdownInterval int    `json:"showdownInterval" yaml:"showdownInterval"` // 逐步摊牌的间隔（秒）
+}
+
+// DefaultTableConfig 返回默认牌桌配置
+func DefaultTableConfig() TableConfig {
+	return TableConfig{
+		Name:             "默认牌桌",
+		Seats:            7,
+		MinPlayers:       2,
+		SmallBlind:       10,
+		BigBlind:         20,
+		Ante:             0,
+		MinBuyIn:         400,
+		MaxBuyIn:         1000,
+		MaxRebuys:        3,
+		MissedBlinds:     true,
+		SitOutOrbits:     3,
+		DisconnectGrace:  30,
+		DisconnectAction: DisconnectSitOut,
+		BettingStructure: BettingNoLimit,
+		ActionTimeout:    20,
+		TimeBank:         60,
+		StartCountdown:   3,
+		ShowdownInterval: 1,
+	}
+}
+
+// Validate 检查配置是否合法
+func (c *TableConfig) Validate() error {
+	if c.Seats < MinSeatCount || c.Seats > MaxSeatCount {
+		return fmt.Errorf("座位数量必须在 %d 到 %d 之间: %d", MinSeatCount, MaxSeatCount, c.Seats)
+	}
+	if c.MinPlayers < 2 || c.MinPlayers > c.Seats {
+		return fmt.Errorf("最少玩家数必须在 2 到座位数 %d 之间: %d", c.Seats, c.MinPlayers)
+	}
+	if c.SmallBlind <= 0 || c.BigBlind <= 0 {
+		return fmt.Errorf("盲注必须大于0: %d/%d", c.SmallBlind, c.BigBlind)
+	}
+	if c.SmallBlind > c.BigBlind {
+		return fmt.Errorf("小盲注不能大于大盲注: %d/%d", c.SmallBlind, c.BigBlind)
+	}
+	if c.Ante < 0 || c.Ante > c.BigBlind {
+		return fmt.Errorf("前注必须在 0 到大盲注之间: %d", c.Ante)
+	}
+	if c.MinBuyIn < c.BigBlind {
+		return fmt.Errorf("最小买入不能少于一个大盲注: %d", c.MinBuyIn)
+	}
+	if c.MaxBuyIn < c.MinBuyIn {
+		return fmt.Errorf("最大买入不能小于最小买入: %d < %d", c.MaxBuyIn, c.MinBuyIn)
+	}
+	if c.MaxRebuys < 0 {
+		return fmt.Errorf("补码次数不能为负数: %d", c.MaxRebuys)
+	}
+	if c.SitOutOrbits < 0 {
+		return fmt.Errorf("自动离座圈数不能为负数: %d", c.SitOutOrbits)
+	}
+	if c.DisconnectGrace < 0 {
+		return fmt.Errorf("断线宽限期不能为负数: %d", c.DisconnectGrace)
+	}
+	switch c.DisconnectAction {
+	case DisconnectFold, DisconnectSitOut:
+	default:
+		return fmt.Errorf("不支持的断线处理方式: %s", c.DisconnectAction)
+	}
+	switch c.BettingStructure {
+	case BettingNoLimit, BettingPotLimit:
+	default:
+		return fmt.Errorf("不支持的下注结构: %s", c.BettingStructure)
+	}
+	if c.ActionTimeout <= 0 {
+		return fmt.Errorf("行动时限必须大于0: %d", c.ActionTimeout)
+	}
+	if c.TimeBank < 0 {
+		return fmt.Errorf("时间银行不能为负数: %d", c.TimeBank)
+	}
+	if c.StartCountdown < 0 {
+		return fmt.Errorf("开局倒计时不能为负数: %d", c.StartCountdown)
+	}
+	if c.ShowdownInterval <= 0 {
+		return fmt.Errorf("摊牌间隔必须大于0: %d", c.ShowdownInterval)
+	}
+	return nil
+}
+
+// LoadTableConfig 从 YAML 或 JSON 文件加载牌桌配置
+// 文件中未出现的字段使用默认值
+func LoadTableConfig(path string) (TableConfig, error) {
+	config := DefaultTableConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	case ".json":
+		err = json.Unmarshal(data, &config)
+	default:
+		return config, fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	if err != nil {
+		return config, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
@@ -0,0 +1,329 @@
+package poker
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PokerStars 手牌历史中使用的阶段名称
+var pokerStarsStreets = []struct {
+	street string
+	title  string
+	name   string
+	cards  int
+}{
+	{GamePhasePreFlop, "HOLE CARDS", "before Flop", 0},
+	{GamePhaseFlop, "FLOP", "on the Flop", 3},
+	{GamePhaseTurn, "TURN", "on the Turn", 4},
+	{GamePhaseRiver, "RIVER", "on the River", 5},
+}
+
+// PokerStars 手牌历史中的牌点名称（单数、复数）
+var pokerStarsRankNames = map[CardRank][2]string{
+	Two: {"Deuce", "Deuces"}, Three: {"Three", "Threes"}, Four: {"Four", "Fours"},
+	Five: {"Five", "Fives"}, Six: {"Six", "Sixes"}, Seven: {"Seven", "Sevens"},
+	Eight: {"Eight", "Eights"}, Nine: {"Nine", "Nines"}, Ten: {"Ten", "Tens"},
+	Jack: {"Jack", "Jacks"}, Queen: {"Queen", "Queens"}, King: {"King", "Kings"},
+	Ace: {"Ace", "Aces"},
+}
+
+// ExportPokerStars 将对局记录导出为 PokerStars 格式的手牌历史文本
+// heroID 对应的玩家会输出 "Dealt to" 行，分析工具以此识别自己的手牌
+func ExportPokerStars(record *GameRound, heroID string) string {
+	var b strings.Builder
+
+	seatName := make(map[int]string)
+	for _, player := range record.Players {
+		seatName[player.Position] = player.Name
+	}
+
+	// 头部
+	limit := "No Limit"
+	if record.Betting == BettingPotLimit {
+		limit = "Pot Limit"
+	}
+	start := time.Unix(record.StartTime, 0)
+	fmt.Fprintf(&b, "PokerStars Hand #%s: Hold'em %s (%d/%d) - %s\n",
+		pokerStarsHandNumber(record.RoundID), limit, record.SmallBlind, record.BigBlind,
+		start.Format("2006/01/02 15:04:05 MST"))
+
+	tableName := record.TableName
+	if tableName == "" {
+		tableName = "holdem"
+	}
+	fmt.Fprintf(&b, "Table '%s' %d-max Seat #%d is the button\n", tableName, record.Seats, record.DealerPos+1)
+
+	players := append([]PlayerRoundInfo(nil), record.Players...)
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Position < players[j].Position
+	})
+	sittingOut := make(map[int]bool)
+	for _, player := range players {
+		fmt.Fprintf(&b, "Seat %d: %s (%d in chips)", player.Position+1, player.Name, player.InitChips)
+		if player.Status == PlayerStatusSittingOut {
+			sittingOut[player.Position] = true
+			fmt.Fprintf(&b, " is sitting out")
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	// 前注和盲注
+	actions := record.Actions
+	forced := 0
+forcedBets:
+	for ; forced < len(actions); forced++ {
+		action := actions[forced]
+		switch action.Type {
+		case ActionAnte:
+			fmt.Fprintf(&b, "%s: posts the ante %d%s\n", action.Name, action.Amount, allInSuffix(action))
+		case ActionSmallBlind:
+			fmt.Fprintf(&b, "%s: posts small blind %d%s\n", action.Name, action.Amount, allInSuffix(action))
+		case ActionBigBlind, ActionMissedBlind:
+			fmt.Fprintf(&b, "%s: posts big blind %d%s\n", action.Name, action.Amount, allInSuffix(action))
+		default:
+			break forcedBets
+		}
+	}
+
+	// 各阶段的行动
+	foldedOn := make(map[int]string)
+	invested := make(map[int]int)
+	for _, action := range actions[:forced] {
+		invested[action.Position] += action.Amount
+	}
+
+	board := record.CommunityCards[:record.BoardReached()]
+	for _, street := range pokerStarsStreets {
+		if len(board) < street.cards {
+			break
+		}
+
+		switch street.cards {
+		case 0:
+			fmt.Fprintf(&b, "*** HOLE CARDS ***\n")
+			for _, player := range players {
+				if player.UserId == heroID && len(player.HoleCards) > 0 {
+					fmt.Fprintf(&b, "Dealt to %s [%s]\n", player.Name, formatCards(player.HoleCards))
+				}
+			}
+		case 3:
+			fmt.Fprintf(&b, "*** FLOP *** [%s]\n", formatCards(board[:3]))
+		default:
+			fmt.Fprintf(&b, "*** %s *** [%s] [%s]\n", street.title,
+				formatCards(board[:street.cards-1]),
+				formatCards(board[street.cards-1:street.cards]))
+		}
+
+		streetBet := 0
+		if street.street == GamePhasePreFlop {
+			streetBet = record.BigBlind
+		}
+		for _, action := range actions[forced:] {
+			if action.Street != street.street {
+				continue
+			}
+			if action.Type != ActionUncalled {
+				invested[action.Position] += action.Amount
+			}
+
+			switch action.Type {
+			case ActionFold:
+				foldedOn[action.Position] = street.name
+				fmt.Fprintf(&b, "%s: folds\n", action.Name)
+			case ActionCheck:
+				fmt.Fprintf(&b, "%s: checks\n", action.Name)
+			case ActionCall:
+				fmt.Fprintf(&b, "%s: calls %d%s\n", action.Name, action.Amount, allInSuffix(action))
+			case ActionBet:
+				fmt.Fprintf(&b, "%s: bets %d%s\n", action.Name, action.RaiseTo, allInSuffix(action))
+				streetBet = action.RaiseTo
+			case ActionRaise:
+				fmt.Fprintf(&b, "%s: raises %d to %d%s\n", action.Name, action.RaiseTo-streetBet, action.RaiseTo, allInSuffix(action))
+				streetBet = action.RaiseTo
+			case ActionUncalled:
+				fmt.Fprintf(&b, "Uncalled bet (%d) returned to %s\n", action.Amount, action.Name)
+			}
+		}
+	}
+
+	// 摊牌
+	showdown := make(map[int]*Hand)
+	contenders := 0
+	for _, player := range players {
+		if _, folded := foldedOn[player.Position]; !folded && !sittingOut[player.Position] {
+			contenders++
+		}
+	}
+	if contenders > 1 {
+		fmt.Fprintf(&b, "*** SHOW DOWN ***\n")
+		for _, player := range players {
+			if _, folded := foldedOn[player.Position]; folded || len(player.HoleCards) == 0 {
+				continue
+			}
+			hand := GetBestHand(&Player{Name: player.Name, HoleCards: player.HoleCards}, record.CommunityCards)
+			showdown[player.Position] = hand
+			fmt.Fprintf(&b, "%s: shows [%s] (%s)\n", player.Name, formatCards(player.HoleCards), describeHand(hand))
+		}
+	}
+
+	// 底池分配
+	won := make(map[int]int)
+	for potIndex, pot := range record.Pots {
+		potName := "pot"
+		if len(record.Pots) > 1 {
+			switch {
+			case potIndex == 0:
+				potName = "main pot"
+			case len(record.Pots) == 2:
+				potName = "side pot"
+			default:
+				potName = fmt.Sprintf("side pot-%d", potIndex)
+			}
+		}
+		for _, winner := range pot.Winners {
+			won[winner.Position] += winner.Amount
+			fmt.Fprintf(&b, "%s collected %d from %s\n", seatName[winner.Position], winner.Amount, potName)
+		}
+	}
+
+	// 汇总
+	fmt.Fprintf(&b, "*** SUMMARY ***\n")
+	if len(record.Pots) > 1 {
+		fmt.Fprintf(&b, "Total pot %d Main pot %d.", record.Pot, record.Pots[0].Amount)
+		for potIndex, pot := range record.Pots[1:] {
+			if len(record.Pots) == 2 {
+				fmt.Fprintf(&b, " Side pot %d.", pot.Amount)
+			} else {
+				fmt.Fprintf(&b, " Side pot-%d %d.", potIndex+1, pot.Amount)
+			}
+		}
+		fmt.Fprintf(&b, " | Rake 0\n")
+	} else {
+		fmt.Fprintf(&b, "Total pot %d | Rake 0\n", record.Pot)
+	}
+	if len(board) > 0 {
+		fmt.Fprintf(&b, "Board [%s]\n", formatCards(board))
+	}
+
+	for _, player := range players {
+		if sittingOut[player.Position] {
+			continue
+		}
+
+		label := ""
+		switch player.Position {
+		case record.DealerPos:
+			label = " (button)"
+		case record.SmallBlindPos:
+			label = " (small blind)"
+		case record.BigBlindPos:
+			label = " (big blind)"
+		}
+
+		fmt.Fprintf(&b, "Seat %d: %s%s ", player.Position+1, player.Name, label)
+		hand, shown := showdown[player.Position]
+		switch {
+		case foldedOn[player.Position] != "":
+			fmt.Fprintf(&b, "folded %s", foldedOn[player.Position])
+			if invested[player.Position] == 0 {
+				fmt.Fprintf(&b, " (didn't bet)")
+			}
+		case shown && won[player.Position] > 0:
+			fmt.Fprintf(&b, "showed [%s] and won (%d) with %s", formatCards(player.HoleCards), won[player.Position], describeHand(hand))
+		case shown:
+			fmt.Fprintf(&b, "showed [%s] and lost with %s", formatCards(player.HoleCards), describeHand(hand))
+		default:
+			fmt.Fprintf(&b, "collected (%d)", won[player.Position])
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	return b.String()
+}
+
+// pokerStarsHandNumber 手牌编号必须是数字，非数字的对局ID转换为稳定的数字编号
+func pokerStarsHandNumber(roundID string) string {
+	if _, err := strconv.ParseUint(roundID, 10, 64); err == nil {
+		return roundID
+	}
+	h := fnv.New64a()
+	h.Write([]byte(roundID))
+	return strconv.FormatUint(h.Sum64()%1e15, 10)
+}
+
+// allInSuffix 全下行动的后缀
+func allInSuffix(action Action) string {
+	if action.AllIn {
+		return " and is all-in"
+	}
+	return ""
+}
+
+// formatCards 将牌格式化为 "Ah Kd" 的形式
+func formatCards(cards []Card) string {
+	parts := make([]string, 0, len(cards))
+	for _, card := range cards {
+		if card.Suit == "" {
+			continue
+		}
+		rank := card.Rank
+		if rank == "10" {
+			rank = "T"
+		}
+		parts = append(parts, rank+card.Suit[:1])
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeHand 用 PokerStars 的英文描述牌型
+func describeHand(hand *Hand) string {
+	rank := func(i int) string {
+		if i >= len(hand.TieBreakers) {
+			return ""
+		}
+		return pokerStarsRankNames[hand.TieBreakers[i]][0]
+	}
+	plural := func(i int) string {
+		if i >= len(hand.TieBreakers) {
+			return ""
+		}
+		return pokerStarsRankNames[hand.TieBreakers[i]][1]
+	}
+	straightLow := func() string {
+		if len(hand.TieBreakers) == 0 {
+			return ""
+		}
+		low := hand.TieBreakers[0] - 4
+		if low < Two {
+			low = Ace
+		}
+		return pokerStarsRankNames[low][0]
+	}
+
+	switch hand.Rank {
+	case RoyalFlushRank:
+		return "a Royal Flush"
+	case StraightFlushRank:
+		return fmt.Sprintf("a straight flush, %s to %s", straightLow(), rank(0))
+	case FourOfAKindRank:
+		return fmt.Sprintf("four of a kind, %s", plural(0))
+	case FullHouseRank:
+		return fmt.Sprintf("a full house, %s full of %s", plural(0), plural(1))
+	case FlushRank:
+		return fmt.Sprintf("a flush, %s high", rank(0))
+	case StraightRank:
+		return fmt.Sprintf("a straight, %s to %s", straightLow(), rank(0))
+	case ThreeOfAKindRank:
+		return fmt.Sprintf("three of a kind, %s", plural(0))
+	case TwoPairRank:
+		return fmt.Sprintf("two pair, %s and %s", plural(0), plural(1))
+	case OnePairRank:
+		return fmt.Sprintf("a pair of %s", plural(0))
+	default:
+		return fmt.Sprintf("high card %s", rank(0))
+	}
+}
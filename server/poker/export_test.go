@@ -0,0 +1,208 @@
+package poker
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportPokerStars(t *testing.T) {
+	tests := []struct {
+		name    string
+		stacks  []int
+		holes   []string
+		actions []testAction
+		hero    string
+		want    []string // 头部第一行之后的全部内容
+	}{
+		{
+			name:   "加注后过牌到摊牌",
+			stacks: []int{500, 500, 500},
+			holes:  []string{"AsAh", "KsKh", "QsQh"},
+			actions: []testAction{
+				{0, "raise", 60}, {1, "fold", 0}, {2, "call", 0},
+				{2, "check", 0}, {0, "check", 0},
+				{2, "check", 0}, {0, "check", 0},
+				{2, "check", 0}, {0, "check", 0},
+			},
+			hero: "u2",
+			want: []string{
+				"Table '默认牌桌' 3-max Seat #1 is the button",
+				"Seat 1: 玩家1 (500 in chips)",
+				"Seat 2: 玩家2 (500 in chips)",
+				"Seat 3: 玩家3 (500 in chips)",
+				"玩家2: posts small blind 10",
+				"玩家3: posts big blind 20",
+				"*** HOLE CARDS ***",
+				"Dealt to 玩家3 [Qs Qh]",
+				"玩家1: raises 40 to 60",
+				"玩家2: folds",
+				"玩家3: calls 40",
+				"*** FLOP *** [2c 7d 9h]",
+				"玩家3: checks",
+				"玩家1: checks",
+				"*** TURN *** [2c 7d 9h] [3s]",
+				"玩家3: checks",
+				"玩家1: checks",
+				"*** RIVER *** [2c 7d 9h 3s] [4c]",
+				"玩家3: checks",
+				"玩家1: checks",
+				"*** SHOW DOWN ***",
+				"玩家1: shows [As Ah] (a pair of Aces)",
+				"玩家3: shows [Qs Qh] (a pair of Queens)",
+				"玩家1 collected 130 from pot",
+				"*** SUMMARY ***",
+				"Total pot 130 | Rake 0",
+				"Board [2c 7d 9h 3s 4c]",
+				"Seat 1: 玩家1 (button) showed [As Ah] and won (130) with a pair of Aces",
+				"Seat 2: 玩家2 (small blind) folded before Flop",
+				"Seat 3: 玩家3 (big blind) showed [Qs Qh] and lost with a pair of Queens",
+			},
+		},
+		{
+			// 不指定玩家时没有 Dealt to 行，无人跟注的加注退还后其他玩家弃牌
+			name:    "翻牌前弃牌结束",
+			stacks:  []int{500, 500, 500},
+			holes:   []string{"AsAh", "KsKh", "QsQh"},
+			actions: []testAction{{0, "raise", 60}, {1, "fold", 0}, {2, "fold", 0}},
+			want: []string{
+				"Table '默认牌桌' 3-max Seat #1 is the button",
+				"Seat 1: 玩家1 (500 in chips)",
+				"Seat 2: 玩家2 (500 in chips)",
+				"Seat 3: 玩家3 (500 in chips)",
+				"玩家2: posts small blind 10",
+				"玩家3: posts big blind 20",
+				"*** HOLE CARDS ***",
+				"玩家1: raises 40 to 60",
+				"玩家2: folds",
+				"玩家3: folds",
+				"Uncalled bet (40) returned to 玩家1",
+				"玩家1 collected 50 from pot",
+				"*** SUMMARY ***",
+				"Total pot 50 | Rake 0",
+				"Seat 1: 玩家1 (button) collected (50)",
+				"Seat 2: 玩家2 (small blind) folded before Flop",
+				"Seat 3: 玩家3 (big blind) folded before Flop",
+			},
+		},
+		{
+			// 其他玩家都弃牌时只输出到最后一个行动所在的阶段
+			name:   "翻牌后弃牌结束",
+			stacks: []int{500, 500, 500},
+			holes:  []string{"AsAh", "KsKh", "QsQh"},
+			actions: []testAction{
+				{0, "call", 0}, {1, "call", 0}, {2, "check", 0},
+				{1, "raise", 40}, {2, "fold", 0}, {0, "fold", 0},
+			},
+			want: []string{
+				"Table '默认牌桌' 3-max Seat #1 is the button",
+				"Seat 1: 玩家1 (500 in chips)",
+				"Seat 2: 玩家2 (500 in chips)",
+				"Seat 3: 玩家3 (500 in chips)",
+				"玩家2: posts small blind 10",
+				"玩家3: posts big blind 20",
+				"*** HOLE CARDS ***",
+				"玩家1: calls 20",
+				"玩家2: calls 10",
+				"玩家3: checks",
+				"*** FLOP *** [2c 7d 9h]",
+				"玩家2: bets 40",
+				"玩家3: folds",
+				"玩家1: folds",
+				"Uncalled bet (40) returned to 玩家2",
+				"玩家2 collected 60 from pot",
+				"*** SUMMARY ***",
+				"Total pot 60 | Rake 0",
+				"Board [2c 7d 9h]",
+				"Seat 1: 玩家1 (button) folded on the Flop",
+				"Seat 2: 玩家2 (small blind) collected (60)",
+				"Seat 3: 玩家3 (big blind) folded on the Flop",
+			},
+		},
+		{
+			name:    "多人全下的边池",
+			stacks:  []int{100, 300, 500, 500},
+			holes:   []string{"AsAh", "KsKh", "QsQh", "JsJh"},
+			actions: []testAction{{3, "allin", 0}, {0, "allin", 0}, {1, "allin", 0}, {2, "call", 0}},
+			want: []string{
+				"Table '默认牌桌' 4-max Seat #1 is the button",
+				"Seat 1: 玩家1 (100 in chips)",
+				"Seat 2: 玩家2 (300 in chips)",
+				"Seat 3: 玩家3 (500 in chips)",
+				"Seat 4: 玩家4 (500 in chips)",
+				"玩家2: posts small blind 10",
+				"玩家3: posts big blind 20",
+				"*** HOLE CARDS ***",
+				"玩家4: raises 480 to 500 and is all-in",
+				"玩家1: calls 100 and is all-in",
+				"玩家2: calls 290 and is all-in",
+				"玩家3: calls 480 and is all-in",
+				"*** FLOP *** [2c 7d 9h]",
+				"*** TURN *** [2c 7d 9h] [3s]",
+				"*** RIVER *** [2c 7d 9h 3s] [4c]",
+				"*** SHOW DOWN ***",
+				"玩家1: shows [As Ah] (a pair of Aces)",
+				"玩家2: shows [Ks Kh] (a pair of Kings)",
+				"玩家3: shows [Qs Qh] (a pair of Queens)",
+				"玩家4: shows [Js Jh] (a pair of Jacks)",
+				"玩家1 collected 400 from main pot",
+				"玩家2 collected 600 from side pot-1",
+				"玩家3 collected 400 from side pot-2",
+				"*** SUMMARY ***",
+				"Total pot 1400 Main pot 400. Side pot-1 600. Side pot-2 400. | Rake 0",
+				"Board [2c 7d 9h 3s 4c]",
+				"Seat 1: 玩家1 (button) showed [As Ah] and won (400) with a pair of Aces",
+				"Seat 2: 玩家2 (small blind) showed [Ks Kh] and won (600) with a pair of Kings",
+				"Seat 3: 玩家3 (big blind) showed [Qs Qh] and won (400) with a pair of Queens",
+				"Seat 4: 玩家4 showed [Js Jh] and lost with a pair of Jacks",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, tt.stacks...)
+			startTestHand(t, g, testDeck(t, tt.holes, "2c 7d 9h 3s 4c"))
+			playActions(t, g, tt.actions)
+			if g.CurrentRound == nil {
+				t.Fatalf("牌局没有结束，阶段: %s", g.GamePhase)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(ExportPokerStars(g.CurrentRound, tt.hero), "\n"), "\n")
+			if !strings.HasPrefix(lines[0], "PokerStars Hand #") || !strings.Contains(lines[0], "Hold'em No Limit (10/20)") {
+				t.Errorf("头部 = %q", lines[0])
+			}
+			got := lines[1:]
+			for i := 0; i < max(len(got), len(tt.want)); i++ {
+				var gotLine, wantLine string
+				if i < len(got) {
+					gotLine = got[i]
+				}
+				if i < len(tt.want) {
+					wantLine = tt.want[i]
+				}
+				if gotLine != wantLine {
+					t.Fatalf("第%d行 = %q, 期望 %q", i+2, gotLine, wantLine)
+				}
+			}
+		})
+	}
+}
+
+func TestPokerStarsHandNumber(t *testing.T) {
+	if got := pokerStarsHandNumber("123456"); got != "123456" {
+		t.Errorf("数字编号 = %s, 期望原样输出", got)
+	}
+
+	// 非数字的对局ID转换为稳定的数字编号
+	first := pokerStarsHandNumber("table-00000001")
+	if _, err := strconv.ParseUint(first, 10, 64); err != nil {
+		t.Errorf("编号 %s 不是数字", first)
+	}
+	if again := pokerStarsHandNumber("table-00000001"); again != first {
+		t.Errorf("同一个对局ID的编号不同: %s, %s", first, again)
+	}
+	if other := pokerStarsHandNumber("table-00000002"); other == first {
+		t.Errorf("不同对局ID的编号相同: %s", other)
+	}
+}
@@ -3,6 +3,9 @@ package poker
 import (
 	"log"
 	"math/rand"
+	"time"
+
+	"github.com/lllllan02/holdem/i18n"
 )
 
 // 游戏状态常量
@@ -21,12 +24,19 @@ const (
 	GamePhaseShowdownReveal = "showdown_reveal" // 逐步摊牌
 )
 
-// 默认配置常量
-const (
-	DefaultSmallBlind = 10
-	DefaultBigBlind   = 20
-	MaxSeats          = 7
-	MinPlayers        = 2 // 最少需要2个玩家才能开始游戏
+// 玩家行动校验错误，消息按玩家的语言生成
+var (
+	ErrGameNotPlaying    = i18n.NewError(i18n.ErrGameNotPlaying)
+	ErrGamePaused        = i18n.NewError(i18n.ErrGamePaused)
+	ErrPlayerNotSeated   = i18n.NewError(i18n.ErrNotInGame)
+	ErrNotYourTurn       = i18n.NewError(i18n.ErrNotYourTurn)
+	ErrCannotAct         = i18n.NewError(i18n.ErrCannotAct)
+	ErrCannotCheck       = i18n.NewError(i18n.ErrCannotCheck)
+	ErrRaiseLocked       = i18n.NewError(i18n.ErrRaiseLocked)
+	ErrInsufficientChips = i18n.NewError(i18n.ErrInsufficientChips)
+	ErrNoChips           = i18n.NewError(i18n.ErrNoChips)
+	ErrInvalidAction     = i18n.NewError(i18n.ErrInvalidAction)
+	ErrExceedsPotLimit   = i18n.NewError(i18n.ErrExceedsPotLimit)
 )
 
 type Game struct {
@@ -35,16 +45,25 @@ type Game struct {
 	GamePhase      string   `json:"gamePhase"`      // 当前游戏阶段
 	CommunityCards []Card   `json:"communityCards"` // 公共牌
 	Pot            int      `json:"pot"`            // 底池
+	Pots           []Pot    `json:"pots"`           // 主池和边池明细
 	CurrentBet     int      `json:"currentBet"`     // 当前下注额
+	LastRaise      int      `json:"lastRaise"`      // 本轮上一次完整加注的增量
+	MinRaiseTo     int      `json:"minRaiseTo"`     // 当前行动玩家最少加注到的金额（0表示不能加注）
+	MaxRaiseTo     int      `json:"maxRaiseTo"`     // 当前行动玩家最多加注到的金额（全下）
 	DealerPos      int      `json:"dealerPos"`      // 庄家位置
 	SmallBlindPos  int      `json:"smallBlindPos"`  // 小盲注位置
 	BigBlindPos    int      `json:"bigBlindPos"`    // 大盲注位置
 	CurrentPlayer  int      `json:"currentPlayer"`  // 当前行动玩家
 	SmallBlind     int      `json:"smallBlind"`     // 小盲注
 	BigBlind       int      `json:"bigBlind"`       // 大盲注
+	Ante           int      `json:"ante"`           // 前注
 	Deck           []Card   `json:"-"`              // 牌堆（不发送给客户端）
+	HandDeck       []Card   `json:"-"`              // 本局洗好的完整牌堆，保存到对局记录用于回放
 	CountdownTimer int      `json:"countdownTimer"` // 倒计时（秒）
+	ActionTimer    int      `json:"actionTimer"`    // 当前行动玩家的剩余行动时间（秒）
+	UsingTimeBank  bool     `json:"usingTimeBank"`  // 当前行动玩家是否正在消耗时间银行
 	Spectators     int      `json:"spectators"`     // 观众数量
+	Paused         bool     `json:"paused"`         // 房主是否暂停了游戏
 
 	// 摊牌相关字段
 	ShowdownOrder   []int `json:"showdownOrder"`   // 摊牌顺序（玩家索引）
@@ -52,7 +71,18 @@ type Game struct {
 	ShowdownTimer   int   `json:"showdownTimer"`   // 摊牌倒计时
 
 	// 对局记录
-	CurrentRound *GameRound `json:"currentRound"` // 当前对局记录，用于结算展示
+	TableID       string     `json:"tableId"`       // 牌桌ID，用于生成对局ID
+	Private       bool       `json:"-"`             // 是否为私人牌桌，对局记录据此只对参与的玩家公开
+	RoundID       string     `json:"roundId"`       // 本局的对局ID
+	Actions       []Action   `json:"actions"`       // 本局按顺序记录的所有行动
+	HandStartTime int64      `json:"handStartTime"` // 本局开始时间
+	CurrentRound  *GameRound `json:"currentRound"`  // 当前对局记录，用于结算展示
+
+	// 牌桌配置
+	Config TableConfig `json:"config"`
+
+	// 是否为回放中的游戏，回放时不保存对局记录
+	replay bool
 }
 
 // Card 扑克牌结构
@@ -62,10 +92,15 @@ type Card struct {
 	Value int    `json:"value"` // 数值: 2-14 (A=14)
 }
 
-func NewGame() *Game {
-	// 初始化7个座位，每个座位都有完整的Player结构
-	players := make([]Player, MaxSeats)
-	for i := 0; i < MaxSeats; i++ {
+// NewGame 根据牌桌配置创建游戏，配置不合法时返回错误
+func NewGame(config TableConfig) (*Game, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 按配置初始化座位，每个座位都有完整的Player结构
+	players := make([]Player, config.Seats)
+	for i := 0; i < config.Seats; i++ {
 		players[i] = NewPlayer()
 	}
 
@@ -75,20 +110,23 @@ func NewGame() *Game {
 		GamePhase:       "",
 		CommunityCards:  make([]Card, 0, 5),
 		Pot:             0,
+		Pots:            make([]Pot, 0),
 		CurrentBet:      0,
 		DealerPos:       -1, // 初始化为-1，表示还未设置庄家
 		SmallBlindPos:   -1, // 初始化为-1，表示还未设置小盲注位置
 		BigBlindPos:     -1, // 初始化为-1，表示还未设置大盲注位置
 		CurrentPlayer:   -1,
-		SmallBlind:      DefaultSmallBlind,
-		BigBlind:        DefaultBigBlind,
+		SmallBlind:      config.SmallBlind,
+		BigBlind:        config.BigBlind,
+		Ante:            config.Ante,
 		Deck:            make([]Card, 0, 52),
 		CountdownTimer:  0,
 		ShowdownOrder:   make([]int, 0),
 		CurrentShowdown: -1,
 		ShowdownTimer:   0,
 		CurrentRound:    nil,
-	}
+		Config:          config,
+	}, nil
 }
 
 // GetSittingPlayersCount 获取已落座的玩家数量
@@ -110,11 +148,17 @@ func (g *Game) CanStartGame() bool {
 		return false
 	}
 
+	// 暂停期间不开始新的一局
+	if g.Paused {
+		log.Printf("[游戏] 无法开始游戏 - 游戏已暂停")
+		return false
+	}
+
 	// 检查玩家数量
 	sittingPlayers := 0
 	readyPlayers := 0
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Chips > 0 {
+		if !player.IsEmpty() && !player.SittingOut && player.Chips > 0 {
 			sittingPlayers++
 			if player.IsReady {
 				readyPlayers++
@@ -123,7 +167,7 @@ func (g *Game) CanStartGame() bool {
 	}
 
 	// 检查是否满足开始条件
-	canStart := sittingPlayers >= MinPlayers && sittingPlayers == readyPlayers
+	canStart := sittingPlayers >= g.Config.MinPlayers && sittingPlayers == readyPlayers
 	log.Printf("[游戏] 检查是否可以开始游戏 - 总玩家数=%d，已准备玩家数=%d，可以开始=%t",
 		sittingPlayers, readyPlayers, canStart)
 	return canStart
@@ -138,15 +182,39 @@ func (g *Game) StartGame() bool {
 	log.Printf("[游戏] 开始新一轮游戏")
 
 	// 重置游戏状态
+	g.resetHand()
+	g.RoundID = NextRoundID(g.TableID)
+
+	// 创建并洗牌
+	g.createDeck()
+	g.shuffleDeck()
+	g.HandDeck = append([]Card(nil), g.Deck...)
+
+	// 根据庄家位置确定庄家、小盲和大盲
+	g.setPositions()
+
+	// 发牌、下盲注并确定第一个行动玩家
+	g.dealHand()
+
+	log.Printf("[游戏] 游戏初始化完成，等待玩家行动")
+	return true
+}
+
+// resetHand 开始新一局前重置牌局和玩家的状态
+func (g *Game) resetHand() {
 	g.GameStatus = GameStatusPlaying
 	g.GamePhase = GamePhasePreFlop
 	g.Pot = 0
+	g.Pots = make([]Pot, 0)
 	g.CurrentBet = 0
+	g.LastRaise = 0
 	g.CommunityCards = make([]Card, 0)
 	g.ShowdownOrder = make([]int, 0)
 	g.CurrentShowdown = -1
 	g.ShowdownTimer = 0
 	g.CurrentRound = nil // 清空当前对局记录
+	g.Actions = make([]Action, 0)
+	g.HandStartTime = time.Now().Unix()
 
 	// 重置所有玩家的游戏状态
 	for i := range g.Players {
@@ -158,35 +226,24 @@ func (g *Game) StartGame() bool {
 			g.Players[i].HandRank = nil               // 清空牌型
 			g.Players[i].WinAmount = 0                // 清空赢得金额
 			g.Players[i].HasActed = false             // 重置行动状态
+			g.Players[i].RaiseLocked = false          // 重置加注限制
 			g.Players[i].Status = PlayerStatusSitting // 重置为坐下状态
 			g.Players[i].IsReady = false              // 重置准备状态
-		}
-	}
-
-	// 创建并洗牌
-	g.createDeck()
-	g.shuffleDeck()
 
-	// 确定庄家位置
-	g.setDealer()
-
-	// 轮换大小盲注位置（如果不是第一局）
-	if g.SmallBlindPos != -1 && g.BigBlindPos != -1 {
-		// 找到下一个有效的小盲注位置（从当前小盲注位置开始）
-		nextSmallBlindPos := g.getNextActivePlayer(g.SmallBlindPos)
-		if nextSmallBlindPos != -1 {
-			// 找到下一个有效的大盲注位置（从新的小盲注位置开始）
-			nextBigBlindPos := g.getNextActivePlayer(nextSmallBlindPos)
-			if nextBigBlindPos != -1 {
-				g.SmallBlindPos = nextSmallBlindPos
-				g.BigBlindPos = nextBigBlindPos
-				log.Printf("[游戏] 轮换盲注位置 - 新小盲：座位%d (%s), 新大盲：座位%d (%s)",
-					g.SmallBlindPos+1, g.Players[g.SmallBlindPos].Name,
-					g.BigBlindPos+1, g.Players[g.BigBlindPos].Name)
+			// 暂时离开或筹码输光的玩家保留座位，但不参与本局
+			if g.Players[i].SittingOut || g.Players[i].Chips <= 0 {
+				g.Players[i].Status = PlayerStatusSittingOut
 			}
+
+			// 两局之间补充的筹码计入这一局的记录
+			g.Players[i].handAdditions = g.Players[i].Additions
+			g.Players[i].Additions = nil
 		}
 	}
+}
 
+// dealHand 在牌堆和位置确定之后发手牌、收盲注并确定第一个行动玩家
+func (g *Game) dealHand() {
 	// 发手牌
 	g.dealHoleCards()
 
@@ -196,8 +253,11 @@ func (g *Game) StartGame() bool {
 	// 设置第一个行动玩家（从大盲注后面第一个玩家开始）
 	g.setFirstActionPlayer()
 
-	log.Printf("[游戏] 游戏初始化完成，等待玩家行动")
-	return true
+	// 所有玩家都因盲注全下，没有人需要行动
+	if g.CurrentPlayer == -1 || g.isRoundComplete() {
+		g.nextPhase()
+	}
+	g.updateRaiseLimits()
 }
 
 // EndGame 结束游戏
@@ -205,35 +265,46 @@ func (g *Game) EndGame() {
 	g.GameStatus = GameStatusWaiting
 	g.GamePhase = ""
 
-	// 轮换大小盲注位置
-	if g.SmallBlindPos != -1 && g.BigBlindPos != -1 {
-		// 找到下一个有效的小盲注位置（从当前小盲注位置开始）
-		nextSmallBlindPos := g.getNextActivePlayer(g.SmallBlindPos)
-		if nextSmallBlindPos != -1 {
-			// 找到下一个有效的大盲注位置（从新的小盲注位置开始）
-			nextBigBlindPos := g.getNextActivePlayer(nextSmallBlindPos)
-			if nextBigBlindPos != -1 {
-				g.SmallBlindPos = nextSmallBlindPos
-				g.BigBlindPos = nextBigBlindPos
-				log.Printf("[游戏] 轮换盲注位置 - 新小盲：座位%d (%s), 新大盲：座位%d (%s)",
-					g.SmallBlindPos+1, g.Players[g.SmallBlindPos].Name,
-					g.BigBlindPos+1, g.Players[g.BigBlindPos].Name)
-			}
-		}
+	g.resetRound()
+}
+
+// SetBlinds 修改盲注和前注，只能在两局之间修改
+func (g *Game) SetBlinds(smallBlind, bigBlind, ante int) error {
+	if g.GameStatus == GameStatusPlaying {
+		return i18n.NewError(i18n.ErrGameInProgress)
 	}
 
-	g.resetRound()
+	config := g.Config
+	config.SmallBlind = smallBlind
+	config.BigBlind = bigBlind
+	config.Ante = ante
+	if err := config.Validate(); err != nil {
+		log.Printf("[游戏] 盲注设置不合法: %v", err)
+		return i18n.NewError(i18n.ErrInvalidBlinds, smallBlind, bigBlind, ante)
+	}
+
+	g.Config = config
+	g.SmallBlind = smallBlind
+	g.BigBlind = bigBlind
+	g.Ante = ante
+	log.Printf("[游戏] 盲注修改为 %d/%d，前注 %d", smallBlind, bigBlind, ante)
+	return nil
 }
 
 // resetRound 重置游戏轮次
 func (g *Game) resetRound() {
 	g.Pot = 0
+	g.Pots = make([]Pot, 0)
 	g.CurrentBet = 0
+	g.LastRaise = 0
+	g.MinRaiseTo = 0
+	g.MaxRaiseTo = 0
 	g.CommunityCards = make([]Card, 0, 5)
 	g.DealerPos = -1 // 重置为-1，下次开始游戏时重新设置
+	g.SmallBlindPos = -1
+	g.BigBlindPos = -1
 	g.CurrentPlayer = -1
 	g.Deck = make([]Card, 0, 52)
-	// 不重置 SmallBlindPos 和 BigBlindPos，这样它们可以在游戏之间保持
 }
 
 // createDeck 创建标准52张牌
@@ -286,110 +357,212 @@ func (g *Game) dealHoleCards() {
 }
 
 // postBlinds 下盲注
+// 小盲座位可能因玩家离开而为空（死小盲），此时本局不收小盲
 func (g *Game) postBlinds() {
-	playerCount := g.GetSittingPlayersCount()
+	log.Printf("[游戏] 玩家数量: %d", g.GetSittingPlayersCount())
+	log.Printf("[游戏] 庄家位置: 座位%d (%s)", g.DealerPos+1, g.Players[g.DealerPos].Name)
+	log.Printf("[游戏] 小盲位置: 座位%d (%s)", g.SmallBlindPos+1, g.Players[g.SmallBlindPos].Name)
+	log.Printf("[游戏] 大盲位置: 座位%d (%s)", g.BigBlindPos+1, g.Players[g.BigBlindPos].Name)
 
-	// 获取所有有人的座位
-	occupiedSeats := make([]int, 0)
-	for i, player := range g.Players {
-		if !player.IsEmpty() && player.Status == PlayerStatusSitting {
-			occupiedSeats = append(occupiedSeats, i)
+	// 前注
+	if g.Ante > 0 {
+		for i := range g.Players {
+			if g.isActiveSeat(i) {
+				amount := g.Players[i].PostAnte(g.Ante)
+				g.Pot += amount
+				g.recordAction(i, ActionAnte, amount, 0, false)
+			}
 		}
+		log.Printf("[游戏] 所有玩家下前注 %d", g.Ante)
 	}
 
-	// 如果是第一局游戏（大小盲注位置都是-1）
-	if g.SmallBlindPos == -1 || g.BigBlindPos == -1 {
-		if len(occupiedSeats) >= 2 {
-			g.SmallBlindPos = occupiedSeats[0] // 第一个有人的座位作为小盲
-			g.BigBlindPos = occupiedSeats[1]   // 第二个有人的座位作为大盲
-		} else if len(occupiedSeats) == 1 {
-			// 只有一个玩家的情况（理论上不应该发生）
-			g.SmallBlindPos = occupiedSeats[0]
-			g.BigBlindPos = occupiedSeats[0]
-		} else {
-			return // 没有玩家
+	// 小盲注
+	if g.isActiveSeat(g.SmallBlindPos) {
+		player := &g.Players[g.SmallBlindPos]
+		amount := min(g.SmallBlind, player.Chips)
+		player.PostBlind(amount)
+		g.Pot += amount
+		g.recordAction(g.SmallBlindPos, ActionSmallBlind, amount, 0, false)
+		log.Printf("[游戏] %s 下小盲注 %d", player.Name, amount)
+	} else {
+		log.Printf("[游戏] 小盲座位%d无人，本局为死小盲", g.SmallBlindPos+1)
+	}
+
+	// 大盲注
+	if g.isActiveSeat(g.BigBlindPos) {
+		player := &g.Players[g.BigBlindPos]
+		amount := min(g.BigBlind, player.Chips)
+		player.PostBlind(amount)
+		g.Pot += amount
+		g.recordAction(g.BigBlindPos, ActionBigBlind, amount, 0, false)
+		log.Printf("[游戏] %s 下大盲注 %d", player.Name, amount)
+	}
+
+	// 暂时离开期间错过大盲的玩家回来后补交一个大盲，本局正好在盲注位置上的不用补交
+	for i := range g.Players {
+		player := &g.Players[i]
+		if player.MissedBlinds == 0 || !g.isActiveSeat(i) {
+			continue
 		}
+		if i != g.SmallBlindPos && i != g.BigBlindPos {
+			amount := min(player.MissedBlinds, player.Chips)
+			player.PostBlind(amount)
+			g.Pot += amount
+			g.recordAction(i, ActionMissedBlind, amount, 0, false)
+			log.Printf("[游戏] %s 补交错过的盲注 %d", player.Name, amount)
+		}
+		player.MissedBlinds = 0
 	}
 
-	log.Printf("[游戏] 玩家数量: %d", playerCount)
-	log.Printf("[游戏] 庄家位置: 座位%d (%s)", g.DealerPos+1, g.Players[g.DealerPos].Name)
-	log.Printf("[游戏] 小盲位置: 座位%d (%s)", g.SmallBlindPos+1, g.Players[g.SmallBlindPos].Name)
-	log.Printf("[游戏] 大盲位置: 座位%d (%s)", g.BigBlindPos+1, g.Players[g.BigBlindPos].Name)
+	// 大盲不足额全下时，跟注额仍然是一个完整的大盲
+	g.CurrentBet = g.BigBlind
+	g.LastRaise = g.BigBlind
 
-	// 小盲注
-	if g.SmallBlindPos != -1 {
-		g.Players[g.SmallBlindPos].PostBlind(g.SmallBlind)
-		g.Pot += g.SmallBlind
-		log.Printf("[游戏] %s 下小盲注 %d", g.Players[g.SmallBlindPos].Name, g.SmallBlind)
+	g.updatePots()
+}
+
+// setPositions 确定本局的庄家、小盲和大盲位置
+// 第一局从第一个有人的座位开始；之后按死庄规则：大盲总是移动到下一位玩家，
+// 小盲落在上一局大盲的座位，庄家落在上一局小盲的座位（座位可能已经空了）。
+// 单挑时庄家下小盲，另一位玩家下大盲。
+func (g *Game) setPositions() {
+	activeCount := 0
+	for i := range g.Players {
+		if g.isActiveSeat(i) {
+			activeCount++
+		}
+	}
+	if activeCount < g.Config.MinPlayers {
+		return
 	}
+	defer g.markAwaySeats(g.BigBlindPos)
 
-	// 大盲注
-	if g.BigBlindPos != -1 {
-		g.Players[g.BigBlindPos].PostBlind(g.BigBlind)
-		g.Pot += g.BigBlind
-		g.CurrentBet = g.BigBlind
-		log.Printf("[游戏] %s 下大盲注 %d", g.Players[g.BigBlindPos].Name, g.BigBlind)
+	switch {
+	case g.DealerPos == -1 || g.BigBlindPos == -1:
+		// 第一局游戏，第一个有玩家的座位作为庄家
+		g.DealerPos = g.findFirstActivePlayer()
+		if activeCount == 2 {
+			g.SmallBlindPos = g.DealerPos
+		} else {
+			g.SmallBlindPos = g.getNextActivePlayer(g.DealerPos)
+		}
+		g.BigBlindPos = g.getNextActivePlayer(g.SmallBlindPos)
+		log.Printf("[游戏] 第一局游戏，庄家位置设置为座位%d", g.DealerPos+1)
+	case activeCount == 2:
+		// 单挑：大盲前移，庄家即小盲
+		g.BigBlindPos = g.getNextActivePlayer(g.BigBlindPos)
+		g.DealerPos = g.getNextActivePlayer(g.BigBlindPos)
+		g.SmallBlindPos = g.DealerPos
+	default:
+		bigBlindPos := g.getNextActivePlayer(g.BigBlindPos)
+		smallBlindPos := g.BigBlindPos
+		dealerPos := g.SmallBlindPos
+
+		if dealerPos == bigBlindPos || smallBlindPos == bigBlindPos {
+			// 座位变化太大，无法套用死庄规则，按活跃玩家依次轮转
+			dealerPos = g.getNextActivePlayer(g.DealerPos)
+			if dealerPos == -1 {
+				dealerPos = g.findFirstActivePlayer()
+			}
+			smallBlindPos = g.getNextActivePlayer(dealerPos)
+			bigBlindPos = g.getNextActivePlayer(smallBlindPos)
+		}
+
+		g.DealerPos, g.SmallBlindPos, g.BigBlindPos = dealerPos, smallBlindPos, bigBlindPos
 	}
+
+	log.Printf("[游戏] 本局位置 - 庄家：座位%d, 小盲：座位%d, 大盲：座位%d",
+		g.DealerPos+1, g.SmallBlindPos+1, g.BigBlindPos+1)
 }
 
-// setFirstActionPlayer 设置翻牌前第一个行动玩家
-func (g *Game) setFirstActionPlayer() {
-	playerCount := g.GetSittingPlayersCount()
+// markAwaySeats 大盲从 prevBigBlind 移动到本局大盲位置时经过的暂时离开的座位，
+// 离开圈数加一，并按配置记下错过的大盲
+func (g *Game) markAwaySeats(prevBigBlind int) {
+	if prevBigBlind < 0 {
+		return
+	}
 
-	// 获取所有有人的座位
-	occupiedSeats := make([]int, 0)
-	for i, player := range g.Players {
-		if !player.IsEmpty() && player.Status == PlayerStatusSitting {
-			occupiedSeats = append(occupiedSeats, i)
+	for i := 1; i < len(g.Players); i++ {
+		pos := (prevBigBlind + i) % len(g.Players)
+		if pos == g.BigBlindPos {
+			break
 		}
+
+		player := &g.Players[pos]
+		if player.IsEmpty() || player.Status != PlayerStatusSittingOut {
+			continue
+		}
+		player.OrbitsAway++
+		if g.Config.MissedBlinds {
+			player.MissedBlinds = g.BigBlind
+		}
+		log.Printf("[游戏] 暂时离开的玩家 %s 错过大盲，已离开 %d 圈", player.Name, player.OrbitsAway)
 	}
+}
 
-	if len(occupiedSeats) < 2 {
-		return // 玩家不足
+// SetSittingOut 设置玩家暂时离开或回到座位
+// 游戏进行中离开的玩家打完本局，从下一局开始不再参与；回来的玩家从下一局开始参与
+func (g *Game) SetSittingOut(userId string, away bool) error {
+	pos := g.findPlayerPos(userId)
+	if pos == -1 {
+		return ErrPlayerNotSeated
 	}
 
-	if playerCount == 2 {
-		// 2人游戏：小盲注玩家先行动
-		g.CurrentPlayer = occupiedSeats[0] // 小盲位置
-		log.Printf("[游戏] 翻牌前，2人游戏，小盲注玩家先行动：座位%d (%s)", g.CurrentPlayer+1, g.Players[g.CurrentPlayer].Name)
-	} else {
-		// 3人以上：大盲注后的第一个玩家先行动
-		bigBlindPos := occupiedSeats[1] // 大盲位置
-		// 找到大盲后的下一个玩家
-		nextPlayerIndex := -1
-		for i, seat := range occupiedSeats {
-			if seat == bigBlindPos && i+1 < len(occupiedSeats) {
-				nextPlayerIndex = i + 1
-				break
-			}
+	player := &g.Players[pos]
+	player.SittingOut = away
+	player.IsReady = false
+	if away {
+		// 两局之间立即生效，进行中的一局不受影响
+		if player.Status == PlayerStatusSitting && g.GameStatus != GameStatusPlaying {
+			player.Status = PlayerStatusSittingOut
 		}
-		// 如果大盲是最后一个玩家，则从第一个玩家开始
-		if nextPlayerIndex == -1 {
-			nextPlayerIndex = 0
+	} else {
+		player.OrbitsAway = 0
+		if player.Status == PlayerStatusSittingOut && g.GameStatus != GameStatusPlaying {
+			player.Status = PlayerStatusSitting
 		}
-
-		g.CurrentPlayer = occupiedSeats[nextPlayerIndex]
-		log.Printf("[游戏] 翻牌前，%d人游戏，大盲注后第一个玩家先行动：座位%d (%s)", playerCount, g.CurrentPlayer+1, g.Players[g.CurrentPlayer].Name)
 	}
+
+	log.Printf("[游戏] 玩家 %s %s", player.Name, map[bool]string{true: "暂时离开", false: "回到座位"}[away])
+	return nil
 }
 
-// setFirstActionPlayerPostFlop 设置翻牌后第一个行动玩家（从小盲注开始）
-func (g *Game) setFirstActionPlayerPostFlop() {
-	// 获取所有有人的座位
-	occupiedSeats := make([]int, 0)
-	for i, player := range g.Players {
-		if !player.IsEmpty() && player.Status == PlayerStatusSitting {
-			occupiedSeats = append(occupiedSeats, i)
+// AwayTooLong 返回暂时离开的圈数达到配置上限、需要自动离座的座位
+func (g *Game) AwayTooLong() []int {
+	seats := make([]int, 0)
+	if g.Config.SitOutOrbits <= 0 {
+		return seats
+	}
+	for i := range g.Players {
+		if !g.Players[i].IsEmpty() && g.Players[i].SittingOut && g.Players[i].OrbitsAway >= g.Config.SitOutOrbits {
+			seats = append(seats, i)
 		}
 	}
+	return seats
+}
 
-	if len(occupiedSeats) < 2 {
-		return // 玩家不足
+// isActiveSeat 检查座位上是否有参与本局的玩家
+func (g *Game) isActiveSeat(pos int) bool {
+	return pos >= 0 && pos < len(g.Players) &&
+		!g.Players[pos].IsEmpty() && g.Players[pos].Status == PlayerStatusSitting
+}
+
+// setFirstActionPlayer 设置翻牌前第一个行动玩家：大盲之后第一位可以行动的玩家
+// 单挑时大盲之后就是庄家（小盲），因此庄家翻牌前先行动
+func (g *Game) setFirstActionPlayer() {
+	g.CurrentPlayer = g.getNextActivePlayer(g.BigBlindPos)
+	if g.CurrentPlayer != -1 {
+		log.Printf("[游戏] 翻牌前，大盲注后第一个玩家先行动：座位%d (%s)", g.CurrentPlayer+1, g.Players[g.CurrentPlayer].Name)
 	}
+}
 
-	// 翻牌后总是从小盲注玩家开始行动
-	g.CurrentPlayer = occupiedSeats[0] // 小盲位置
-	log.Printf("[游戏] 翻牌后，小盲注玩家先行动：座位%d (%s)", g.CurrentPlayer+1, g.Players[g.CurrentPlayer].Name)
+// setFirstActionPlayerPostFlop 设置翻牌后第一个行动玩家：庄家之后第一位可以行动的玩家
+// 单挑时庄家之后是大盲，因此庄家翻牌后最后行动
+func (g *Game) setFirstActionPlayerPostFlop() {
+	g.CurrentPlayer = g.getNextActivePlayer(g.DealerPos)
+	if g.CurrentPlayer != -1 {
+		log.Printf("[游戏] 翻牌后，庄家之后第一个玩家先行动：座位%d (%s)", g.CurrentPlayer+1, g.Players[g.CurrentPlayer].Name)
+	}
 }
 
 // findFirstActivePlayer 找到第一个活跃玩家位置（优先从座位1开始）
@@ -420,99 +593,134 @@ func (g *Game) getNextActivePlayer(currentPos int) int {
 	return -1
 }
 
-// PlayerAction 处理玩家行动
-func (g *Game) PlayerAction(userId string, action string, amount int) bool {
-	log.Printf("[游戏] 开始处理玩家行动 - 玩家ID: %s, 行动: %s, 金额: %d", userId, action, amount)
-
+// ValidateAction 检查玩家行动是否合法，不合法时返回原因
+func (g *Game) ValidateAction(userId string, action string, amount int) error {
 	// 检查游戏状态
 	if g.GameStatus != GameStatusPlaying {
-		log.Printf("[游戏] 游戏状态错误: %s", g.GameStatus)
-		return false
+		return ErrGameNotPlaying
 	}
-
-	// 找到玩家位置
-	playerPos := -1
-	for i, player := range g.Players {
-		if player.UserId == userId && !player.IsEmpty() {
-			playerPos = i
-			break
-		}
+	if g.Paused {
+		return ErrGamePaused
 	}
 
+	// 找到玩家位置
+	playerPos := g.findPlayerPos(userId)
 	if playerPos == -1 {
-		log.Printf("[游戏] 未找到玩家: %s", userId)
-		return false
+		return ErrPlayerNotSeated
 	}
 
 	// 检查是否轮到该玩家行动
 	if g.CurrentPlayer != playerPos {
-		log.Printf("[游戏] 不是该玩家的行动轮次 - 当前玩家: %d, 行动玩家: %d", g.CurrentPlayer+1, playerPos+1)
-		return false
+		return ErrNotYourTurn
 	}
 
 	player := &g.Players[playerPos]
 
 	// 检查玩家是否可以行动
 	if !player.CanAct() {
-		log.Printf("[游戏] 玩家无法行动 - 状态: %s, HasActed: %v", player.Status, player.HasActed)
+		return ErrCannotAct
+	}
+
+	switch action {
+	case "fold", "call":
+		// 弃牌不需要验证，跟注筹码不足时自动全下
+	case "check":
+		// 只有在没有下注时才能过牌
+		if g.CurrentBet != player.CurrentBet {
+			return ErrCannotCheck
+		}
+	case "raise":
+		if player.RaiseLocked {
+			return ErrRaiseLocked
+		}
+		if player.CurrentBet+player.Chips <= g.CurrentBet {
+			return ErrInsufficientChips
+		}
+		// 加注额达到或超过筹码时视为全下，不受最小加注限制
+		minRaiseTo := g.CurrentBet + g.minRaiseIncrement()
+		if amount < minRaiseTo && amount-player.CurrentBet < player.Chips {
+			return i18n.NewError(i18n.ErrRaiseTooSmall, minRaiseTo)
+		}
+		if maxRaiseTo := g.maxRaiseTo(player); min(amount, player.CurrentBet+player.Chips) > maxRaiseTo {
+			return i18n.NewError(i18n.ErrRaiseTooLarge, maxRaiseTo)
+		}
+	case "allin":
+		if player.Chips <= 0 {
+			return ErrNoChips
+		}
+		if player.RaiseLocked && player.CurrentBet+player.Chips > g.CurrentBet {
+			return ErrRaiseLocked
+		}
+		if player.CurrentBet+player.Chips > g.maxRaiseTo(player) {
+			return ErrExceedsPotLimit
+		}
+	default:
+		return ErrInvalidAction
+	}
+
+	return nil
+}
+
+// PlayerAction 处理玩家行动
+func (g *Game) PlayerAction(userId string, action string, amount int) bool {
+	return g.playerAction(userId, action, amount, false)
+}
+
+// playerAction 执行玩家行动并记录到行动日志，auto 表示超时自动行动
+func (g *Game) playerAction(userId string, action string, amount int, auto bool) bool {
+	log.Printf("[游戏] 开始处理玩家行动 - 玩家ID: %s, 行动: %s, 金额: %d", userId, action, amount)
+
+	if err := g.ValidateAction(userId, action, amount); err != nil {
+		log.Printf("[游戏] 玩家行动不合法 - 玩家ID: %s, 行动: %s, 原因: %v", userId, action, err)
 		return false
 	}
 
+	playerPos := g.findPlayerPos(userId)
+	player := &g.Players[playerPos]
+	chipsBefore := player.Chips
+	betBefore := player.CurrentBet
+	currentBetBefore := g.CurrentBet
+
 	// 处理不同的行动
 	switch action {
 	case "fold":
 		log.Printf("[游戏] 玩家 %s (座位%d) 执行弃牌", player.Name, playerPos+1)
 		player.Fold()
 	case "call":
+		// 筹码不足时允许全下跟注（跟注不足额）
 		callAmount := g.CurrentBet - player.CurrentBet
 		if callAmount > 0 {
-			if callAmount > player.Chips {
-				log.Printf("[游戏] 玩家筹码不足以跟注 - 需要: %d, 拥有: %d", callAmount, player.Chips)
-				return false
+			if callAmount >= player.Chips {
+				log.Printf("[游戏] 玩家 %s 筹码不足以完整跟注，全下 %d", player.Name, player.Chips)
+				callAmount = player.Chips
 			}
 			player.Bet(callAmount)
 			g.Pot += callAmount
 		}
 	case "check":
-		// 只有在没有下注时才能过牌
-		if g.CurrentBet == player.CurrentBet {
-			player.HasActed = true
-		} else {
-			log.Printf("[游戏] 无法过牌 - 当前注: %d, 玩家注: %d", g.CurrentBet, player.CurrentBet)
-			return false
-		}
+		player.HasActed = true
 	case "raise":
-		// 加注必须至少比当前下注多一个大盲注
-		minRaise := g.CurrentBet + g.BigBlind
-		if amount < minRaise {
-			log.Printf("[游戏] 加注金额不足 - 最小加注: %d, 实际加注: %d", minRaise, amount)
-			return false
-		}
-
-		// 检查玩家是否有足够的筹码
-		raiseAmount := amount - player.CurrentBet
-		if raiseAmount > player.Chips {
-			log.Printf("[游戏] 玩家筹码不足以加注 - 需要: %d, 拥有: %d", raiseAmount, player.Chips)
-			return false
-		}
-
-		player.Bet(raiseAmount)
-		g.Pot += raiseAmount
-		g.CurrentBet = amount
-
-		// 重置其他玩家的行动状态（除了已弃牌的）
-		for i := range g.Players {
-			if i != playerPos && !g.Players[i].IsEmpty() && g.Players[i].Status != PlayerStatusFolded {
-				g.Players[i].HasActed = false
-			}
+		// 加注额超过筹码时视为全下
+		if amount-player.CurrentBet >= player.Chips {
+			g.allIn(playerPos)
+			break
 		}
-	default:
-		log.Printf("[游戏] 无效的行动类型: %s", action)
-		return false
+		g.raiseTo(playerPos, amount)
+	case "allin":
+		g.allIn(playerPos)
 	}
 
 	// 标记玩家已经行动
 	player.HasActed = true
+	g.updatePots()
+
+	// 记录行动（在进入下一阶段之前，保证阶段和底池是行动时的状态）
+	actionType := classifyAction(action, betBefore, currentBetBefore, player)
+	raiseTo := 0
+	if actionType == ActionBet || actionType == ActionRaise {
+		raiseTo = player.CurrentBet
+	}
+	g.recordAction(playerPos, actionType, chipsBefore-player.Chips, raiseTo, auto)
 	log.Printf("[游戏] 玩家行动成功，移动到下一个玩家")
 
 	// 移动到下一个玩家
@@ -524,9 +732,136 @@ func (g *Game) PlayerAction(userId string, action string, amount int) bool {
 		g.nextPhase()
 	}
 
+	g.updateRaiseLimits()
 	return true
 }
 
+// AutoAction 行动超时时替当前玩家自动行动：能过牌则过牌，否则弃牌
+func (g *Game) AutoAction() (string, bool) {
+	if g.GameStatus != GameStatusPlaying || g.CurrentPlayer < 0 || g.CurrentPlayer >= len(g.Players) {
+		return "", false
+	}
+
+	player := &g.Players[g.CurrentPlayer]
+	action := "fold"
+	if g.CurrentBet == player.CurrentBet {
+		action = "check"
+	}
+
+	log.Printf("[游戏] 玩家 %s (座位%d) 行动超时，自动%s", player.Name, g.CurrentPlayer+1,
+		map[string]string{"check": "过牌", "fold": "弃牌"}[action])
+	return action, g.playerAction(player.UserId, action, 0, true)
+}
+
+// findPlayerPos 根据用户ID查找玩家座位索引，未找到返回-1
+func (g *Game) findPlayerPos(userId string) int {
+	for i, player := range g.Players {
+		if player.UserId == userId && !player.IsEmpty() {
+			return i
+		}
+	}
+	return -1
+}
+
+// minRaiseIncrement 本轮最小加注增量：上一次完整加注的幅度，且不小于大盲注
+func (g *Game) minRaiseIncrement() int {
+	if g.LastRaise < g.BigBlind {
+		return g.BigBlind
+	}
+	return g.LastRaise
+}
+
+// maxRaiseTo 玩家最多可以加注到的金额
+// 无限注时为全部筹码；底池限注时为跟注后底池大小的加注
+func (g *Game) maxRaiseTo(player *Player) int {
+	stack := player.CurrentBet + player.Chips
+	if g.Config.BettingStructure != BettingPotLimit {
+		return stack
+	}
+
+	callAmount := max(g.CurrentBet-player.CurrentBet, 0)
+	potLimit := g.CurrentBet + g.Pot + callAmount
+	return min(stack, potLimit)
+}
+
+// updateRaiseLimits 根据当前行动玩家更新可加注的范围
+func (g *Game) updateRaiseLimits() {
+	g.MinRaiseTo = 0
+	g.MaxRaiseTo = 0
+
+	if g.GameStatus != GameStatusPlaying || g.CurrentPlayer < 0 || g.CurrentPlayer >= len(g.Players) {
+		return
+	}
+
+	player := &g.Players[g.CurrentPlayer]
+	maxRaiseTo := g.maxRaiseTo(player)
+	if player.RaiseLocked || maxRaiseTo <= g.CurrentBet {
+		// 只能跟注或弃牌
+		return
+	}
+
+	g.MaxRaiseTo = maxRaiseTo
+	g.MinRaiseTo = min(g.CurrentBet+g.minRaiseIncrement(), maxRaiseTo)
+}
+
+// allIn 玩家全下所有筹码，根据全下后的总注额判断是跟注还是加注
+func (g *Game) allIn(playerPos int) {
+	player := &g.Players[playerPos]
+	total := player.CurrentBet + player.Chips
+	log.Printf("[游戏] 玩家 %s (座位%d) 全下 %d", player.Name, playerPos+1, player.Chips)
+
+	if total <= g.CurrentBet {
+		// 不足额跟注
+		g.Pot += player.Chips
+		player.Bet(player.Chips)
+		return
+	}
+
+	g.raiseTo(playerPos, total)
+}
+
+// raiseTo 将玩家的本轮下注提高到 total，并处理重新开放行动的规则
+// 完整加注会让其他玩家重新获得行动权；不足额的全下加注只要求已行动的玩家补齐跟注，
+// 但不允许他们再加注，除非多次不足额加注累计达到一次完整加注
+func (g *Game) raiseTo(playerPos int, total int) {
+	player := &g.Players[playerPos]
+	raiseAmount := total - player.CurrentBet
+	increment := total - g.CurrentBet
+	fullRaise := increment >= g.minRaiseIncrement()
+
+	player.Bet(raiseAmount)
+	g.Pot += raiseAmount
+	g.CurrentBet = total
+	if fullRaise {
+		g.LastRaise = increment
+	}
+
+	for i := range g.Players {
+		other := &g.Players[i]
+		if i == playerPos || other.IsEmpty() || other.Status != PlayerStatusSitting {
+			continue
+		}
+
+		if fullRaise {
+			// 完整加注：所有玩家重新获得完整的行动权
+			other.HasActed = false
+			other.RaiseLocked = false
+		} else if other.CurrentBet < g.CurrentBet {
+			// 不完整加注：已行动的玩家只能跟注或弃牌，
+			// 但他行动之后的多次不完整加注累计达到一次完整加注时重新获得加注权。
+			// 已行动的玩家的本轮下注就是他上次行动时面对的注额
+			if other.HasActed || other.RaiseLocked {
+				other.RaiseLocked = g.CurrentBet-other.CurrentBet < g.minRaiseIncrement()
+			}
+			other.HasActed = false
+		}
+	}
+
+	if !fullRaise {
+		log.Printf("[游戏] 玩家 %s 不足额加注到 %d，不重新开放加注", player.Name, total)
+	}
+}
+
 // moveToNextPlayer 移动到下一个可以行动的玩家
 func (g *Game) moveToNextPlayer() {
 	startPos := g.CurrentPlayer
@@ -535,7 +870,7 @@ func (g *Game) moveToNextPlayer() {
 	// 计算活跃玩家数量
 	activePlayers := 0
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Status != PlayerStatusFolded {
+		if player.InHand() {
 			activePlayers++
 		}
 	}
@@ -552,8 +887,8 @@ func (g *Game) moveToNextPlayer() {
 		nextPos := (startPos + i) % len(g.Players)
 		player := &g.Players[nextPos]
 
-		// 跳过空座位和已弃牌的玩家
-		if player.IsEmpty() || player.Status == PlayerStatusFolded {
+		// 跳过空座位、已弃牌和暂时离开的玩家
+		if !player.InHand() {
 			continue
 		}
 
@@ -579,7 +914,7 @@ func (g *Game) isRoundComplete() bool {
 	actedPlayers := 0
 
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Status != PlayerStatusFolded {
+		if player.InHand() {
 			activePlayers++
 			if player.HasActed || player.Status == PlayerStatusAllIn {
 				actedPlayers++
@@ -610,13 +945,15 @@ func (g *Game) nextPhase() {
 
 	// 重置所有玩家的行动状态和当前下注
 	for i := range g.Players {
-		if !g.Players[i].IsEmpty() && g.Players[i].Status != PlayerStatusFolded {
+		if g.Players[i].InHand() {
 			g.Players[i].HasActed = false
+			g.Players[i].RaiseLocked = false
 			g.Players[i].CurrentBet = 0
 		}
 	}
 
 	g.CurrentBet = 0
+	g.LastRaise = 0
 
 	switch g.GamePhase {
 	case GamePhasePreFlop:
@@ -629,8 +966,10 @@ func (g *Game) nextPhase() {
 		g.GamePhase = GamePhaseRiver
 		g.dealRiver()
 	case GamePhaseRiver:
+		// 摊牌后不再有行动玩家，逐步摊牌期间不能接受行动
 		g.GamePhase = GamePhaseShowdown
 		g.showdown()
+		return
 	default:
 		// 游戏结束，重新开始
 		g.EndGame()
@@ -679,15 +1018,29 @@ func (g *Game) showdown() {
 	var activePlayers []*Player
 	for i := range g.Players {
 		player := &g.Players[i]
-		if !player.IsEmpty() && player.Status != PlayerStatusFolded {
+		if player.InHand() {
 			activePlayers = append(activePlayers, player)
 		}
 	}
 
 	// 如果只有一个玩家，直接获胜
 	if len(activePlayers) == 1 {
+		// 先退还无人跟注的下注，剩余底池全部归获胜者
+		g.returnUncalledBet()
+		g.updatePots()
+
 		winner := activePlayers[0]
 		winAmount := g.Pot
+		winnerPos := -1
+		for i := range g.Players {
+			if &g.Players[i] == winner {
+				winnerPos = i
+				break
+			}
+		}
+		for i := range g.Pots {
+			g.Pots[i].Winners = []PotWinner{{Position: winnerPos, Amount: g.Pots[i].Amount}}
+		}
 
 		// 创建获胜者信息
 		winnerHand := PlayerHand{
@@ -697,15 +1050,14 @@ func (g *Game) showdown() {
 		winners := []PlayerHand{winnerHand}
 		winAmounts := []int{winAmount}
 
-		// 创建并保存对局记录
-		g.CurrentRound = CreateGameRecord(g, winners, winAmounts)
-		if err := SaveGameRecord(g.CurrentRound); err != nil {
-			log.Printf("[警告] 保存对局记录失败: %v", err)
-		}
-
 		// 更新玩家状态
 		winner.Chips += winAmount
 		winner.WinAmount = winAmount
+
+		// 创建并保存对局记录
+		g.CurrentRound = CreateGameRecord(g, winners, winAmounts)
+		g.saveRecord()
+
 		winner.HoleCards = make([]Card, 0) // 清空手牌，这样就不会显示
 		winner.HandRank = nil
 
@@ -713,7 +1065,6 @@ func (g *Game) showdown() {
 		g.GamePhase = GamePhaseShowdown
 		g.GameStatus = GameStatusWaiting
 		g.CurrentPlayer = -1
-		g.DealerPos = -1
 		g.CountdownTimer = -1
 		g.ShowdownTimer = -1
 		g.CurrentShowdown = -1
@@ -723,7 +1074,7 @@ func (g *Game) showdown() {
 		for i := range g.Players {
 			if !g.Players[i].IsEmpty() {
 				g.Players[i].IsReady = false
-				g.Players[i].Status = PlayerStatusSitting
+				g.Players[i].Status = g.Players[i].seatStatus()
 				g.Players[i].HasActed = false
 			}
 		}
@@ -743,29 +1094,14 @@ func (g *Game) startShowdownReveal() {
 	// 切换到逐步摊牌阶段
 	g.GamePhase = GamePhaseShowdownReveal
 
-	// 确定摊牌顺序：从小盲位开始，按座位顺序
+	// 确定摊牌顺序：从庄家之后的第一位玩家开始，按座位顺序
 	g.ShowdownOrder = make([]int, 0)
-
-	// 找到小盲位
-	smallBlindPos := -1
-	for i, player := range g.Players {
-		if !player.IsEmpty() && player.Status != PlayerStatusFolded {
-			// 简化逻辑：从第一个未弃牌的玩家开始
-			if smallBlindPos == -1 {
-				smallBlindPos = i
-			}
-		}
-	}
-
-	// 从小盲位开始，按座位顺序添加所有未弃牌的玩家
-	if smallBlindPos != -1 {
-		for i := 0; i < MaxSeats; i++ {
-			pos := (smallBlindPos + i) % MaxSeats
-			player := &g.Players[pos]
-			if !player.IsEmpty() && player.Status != PlayerStatusFolded {
-				g.ShowdownOrder = append(g.ShowdownOrder, pos)
-				log.Printf("[摊牌] 添加玩家 %s (座位%d) 到摊牌顺序", player.Name, pos+1)
-			}
+	for i := 1; i <= len(g.Players); i++ {
+		pos := (g.DealerPos + i) % len(g.Players)
+		player := &g.Players[pos]
+		if player.InHand() {
+			g.ShowdownOrder = append(g.ShowdownOrder, pos)
+			log.Printf("[摊牌] 添加玩家 %s (座位%d) 到摊牌顺序", player.Name, pos+1)
 		}
 	}
 
@@ -828,34 +1164,25 @@ func (g *Game) finishShowdown() {
 		return
 	}
 
-	// 使用新的手牌比较算法找出获胜者
-	winners := FindWinningHands(activePlayers, g.CommunityCards)
-
-	// 检查是否有获胜者，防止除零错误
-	if len(winners) == 0 {
-		log.Printf("[摊牌] 警告：没有找到获胜者，跳过底池分配")
-		return
-	}
-
-	// 分配底池
-	winAmount := g.Pot / len(winners)
-	remainder := g.Pot % len(winners)
+	// 退还无人跟注的下注，然后按主池和边池分别结算
+	g.returnUncalledBet()
+	g.updatePots()
+	potWinAmounts, bestHands := g.awardPots()
 
-	// 计算每个获胜者的赢得金额
-	winAmounts := make([]int, len(winners))
-	for i := range winners {
-		winAmounts[i] = winAmount
-		if i < remainder {
-			winAmounts[i]++ // 余数分给前几个获胜者
+	// 汇总每个获胜者在所有底池中赢得的金额（按座位顺序）
+	winners := make([]PlayerHand, 0)
+	winAmounts := make([]int, 0)
+	for _, playerIndex := range g.ShowdownOrder {
+		if amount, ok := potWinAmounts[playerIndex]; ok {
+			winners = append(winners, PlayerHand{Hand: bestHands[playerIndex], Player: &g.Players[playerIndex]})
+			winAmounts = append(winAmounts, amount)
 		}
 	}
 
-	// 创建对局记录
-	g.CurrentRound = CreateGameRecord(g, winners, winAmounts)
-
-	// 保存对局记录
-	if err := SaveGameRecord(g.CurrentRound); err != nil {
-		log.Printf("[警告] 保存对局记录失败: %v", err)
+	// 检查是否有获胜者
+	if len(winners) == 0 {
+		log.Printf("[摊牌] 警告：没有找到获胜者，跳过底池分配")
+		return
 	}
 
 	// 分配筹码给获胜者
@@ -865,13 +1192,18 @@ func (g *Game) finishShowdown() {
 		log.Printf("[游戏] 玩家 %s 获胜，赢得 %d 筹码", winner.Player.Name, winAmounts[i])
 	}
 
+	// 创建对局记录（筹码分配之后，保证最终筹码正确）
+	g.CurrentRound = CreateGameRecord(g, winners, winAmounts)
+
+	// 保存对局记录
+	g.saveRecord()
+
 	// 切换到摊牌阶段并设置游戏状态为等待
 	g.GamePhase = GamePhaseShowdown
 	g.GameStatus = GameStatusWaiting
 
 	// 重置游戏相关状态
 	g.CurrentPlayer = -1
-	g.DealerPos = -1 // 重置庄家位置，下一局会重新选择
 	g.CountdownTimer = -1
 	g.ShowdownTimer = -1
 	g.CurrentShowdown = -1
@@ -881,7 +1213,7 @@ func (g *Game) finishShowdown() {
 	for i := range g.Players {
 		if !g.Players[i].IsEmpty() {
 			g.Players[i].IsReady = false
-			g.Players[i].Status = PlayerStatusSitting
+			g.Players[i].Status = g.Players[i].seatStatus()
 			g.Players[i].HasActed = false
 			// 不要清空手牌、牌型和赢得金额，这些需要在结算面板中显示
 			// 这些信息会在玩家准备或开始新一局时清空
@@ -903,7 +1235,7 @@ func (g *Game) CheckAllPlayersReady() bool {
 
 	// 统计所有有筹码的玩家
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Chips > 0 {
+		if !player.IsEmpty() && !player.SittingOut && player.Chips > 0 {
 			sittingPlayers++
 			if player.IsReady {
 				readyPlayers++
@@ -914,7 +1246,7 @@ func (g *Game) CheckAllPlayersReady() bool {
 	log.Printf("[游戏] 检查准备状态：阶段=%s, 总玩家数=%d，已准备玩家数=%d", g.GamePhase, sittingPlayers, readyPlayers)
 
 	// 至少需要2个玩家，且所有玩家都已准备
-	return sittingPlayers >= MinPlayers && sittingPlayers == readyPlayers
+	return sittingPlayers >= g.Config.MinPlayers && sittingPlayers == readyPlayers
 }
 
 // SetPlayerReady 设置玩家准备状态
@@ -933,6 +1265,12 @@ func (g *Game) SetPlayerReady(userId string, ready bool) bool {
 				return false
 			}
 
+			// 暂时离开的玩家需要先回到座位
+			if ready && g.Players[i].SittingOut {
+				log.Printf("[游戏] 玩家 %s 暂时离开中，无法准备游戏", g.Players[i].Name)
+				return false
+			}
+
 			g.Players[i].IsReady = ready
 
 			// 如果是准备状态，且不在摊牌阶段，清空玩家的相关信息
@@ -968,7 +1306,7 @@ func (g *Game) GetReadyPlayersCount() (int, int) {
 
 	// 统一统计逻辑：只统计有筹码的玩家
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Chips > 0 {
+		if !player.IsEmpty() && !player.SittingOut && player.Chips > 0 {
 			sittingPlayers++
 			if player.IsReady {
 				readyPlayers++
@@ -987,7 +1325,7 @@ func (g *Game) shouldGoToShowdown() bool {
 	playersWithChips := 0
 
 	for _, player := range g.Players {
-		if !player.IsEmpty() && player.Status != PlayerStatusFolded {
+		if player.InHand() {
 			activePlayers++
 			if player.Status == PlayerStatusAllIn {
 				allInPlayers++
@@ -1033,17 +1371,3 @@ func (g *Game) dealRemainingCards() {
 		g.CommunityCards = append(g.CommunityCards, card)
 	}
 }
-
-// setDealer 设置庄家位置
-func (g *Game) setDealer() {
-	if g.DealerPos == -1 {
-		// 第一局游戏，选择第一个有玩家的位置作为庄家
-		g.DealerPos = g.findFirstActivePlayer()
-		log.Printf("[游戏] 第一局游戏，庄家位置设置为座位%d", g.DealerPos+1)
-	} else {
-		// 之后的每一局，庄家位置向后移动一位
-		oldDealerPos := g.DealerPos
-		g.DealerPos = g.getNextActivePlayer(g.DealerPos)
-		log.Printf("[游戏] 庄家位置从座位%d轮转到座位%d", oldDealerPos+1, g.DealerPos+1)
-	}
-}
@@ -0,0 +1,565 @@
+package poker
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testAction 测试中某个座位的一次行动
+type testAction struct {
+	seat   int
+	action string
+	amount int
+}
+
+// newTestGame 按筹码让玩家从第一个座位开始依次落座，不保存对局记录
+func newTestGame(t *testing.T, smallBlind, bigBlind int, stacks ...int) *Game {
+	t.Helper()
+	config := DefaultTableConfig()
+	config.Seats = len(stacks)
+	config.SmallBlind = smallBlind
+	config.BigBlind = bigBlind
+	config.MinBuyIn = bigBlind
+	config.MaxBuyIn = bigBlind
+	for _, chips := range stacks {
+		config.MaxBuyIn = max(config.MaxBuyIn, chips)
+	}
+
+	g, err := NewGame(config)
+	if err != nil {
+		t.Fatalf("创建游戏失败: %v", err)
+	}
+	g.replay = true
+	for i, chips := range stacks {
+		g.Players[i].SitDown(fmt.Sprintf("u%d", i), fmt.Sprintf("玩家%d", i+1), chips, 0)
+	}
+	return g
+}
+
+// startTestHand 用给定的牌堆开始一局，位置按引擎的规则确定
+func startTestHand(t *testing.T, g *Game, deck []Card) {
+	t.Helper()
+	g.resetHand()
+	g.Deck = append([]Card(nil), deck...)
+	g.HandDeck = append([]Card(nil), deck...)
+	g.setPositions()
+	if g.DealerPos < 0 {
+		t.Fatalf("玩家不足，无法开始")
+	}
+	g.dealHand()
+}
+
+// playActions 依次执行行动，每一步都检查轮到的座位，牌局进入逐步摊牌后摊完所有牌
+func playActions(t *testing.T, g *Game, actions []testAction) {
+	t.Helper()
+	for i, a := range actions {
+		if g.CurrentPlayer != a.seat {
+			t.Fatalf("第%d个行动: 轮到座位%d，期望座位%d", i+1, g.CurrentPlayer, a.seat)
+		}
+		if !g.PlayerAction(g.Players[a.seat].UserId, a.action, a.amount) {
+			t.Fatalf("第%d个行动: 座位%d %s %d 失败", i+1, a.seat, a.action, a.amount)
+		}
+	}
+	for g.GamePhase == GamePhaseShowdownReveal {
+		g.AdvanceShowdown()
+	}
+}
+
+// testDeck 按发牌顺序排好牌堆：holes 为参与本局的玩家按座位顺序的两张底牌，
+// board 为五张公共牌，烧牌和剩余的牌从没有用到的牌中依次选取
+func testDeck(t *testing.T, holes []string, board string) []Card {
+	t.Helper()
+	used := make(map[Card]bool)
+	take := func(cards []Card) []Card {
+		for _, card := range cards {
+			if used[card] {
+				t.Fatalf("重复的牌: %+v", card)
+			}
+			used[card] = true
+		}
+		return cards
+	}
+
+	var hands [][]Card
+	for _, hole := range holes {
+		hands = append(hands, take(parseCards(t, hole)))
+	}
+	community := take(parseCards(t, board))
+
+	var rest []Card
+	for _, suit := range "shdc" {
+		for _, rank := range "23456789TJQKA" {
+			if card := parseCard(t, string(rank)+string(suit)); !used[card] {
+				rest = append(rest, card)
+			}
+		}
+	}
+	burn := func() Card {
+		card := rest[0]
+		rest = rest[1:]
+		return card
+	}
+
+	deck := make([]Card, 0, 52)
+	for round := 0; round < 2; round++ {
+		for _, hand := range hands {
+			deck = append(deck, hand[round])
+		}
+	}
+	deck = append(deck, burn())
+	deck = append(deck, community[:3]...)
+	deck = append(deck, burn(), community[3])
+	deck = append(deck, burn(), community[4])
+	return append(deck, rest...)
+}
+
+// testChips 返回前 n 个座位的筹码
+func testChips(g *Game, n int) []int {
+	result := make([]int, 0, n)
+	for _, player := range g.Players[:n] {
+		result = append(result, player.Chips)
+	}
+	return result
+}
+
+func TestShortAllInReopening(t *testing.T) {
+	holes := []string{"AsAh", "KsKh", "QsQh", "JsJh"}
+	tests := []struct {
+		name       string
+		stacks     []int
+		actions    []testAction
+		next       int  // 接下来行动的座位
+		canRaise   bool // 是否还能加注
+		minRaiseTo int
+	}{
+		{
+			// 跟注 20 之后全下到 30 不足一次完整加注，还没行动的玩家不受限制
+			name:       "不完整加注后未行动的玩家",
+			stacks:     []int{30, 500, 500, 500},
+			actions:    []testAction{{3, "call", 0}, {0, "allin", 0}},
+			next:       1,
+			canRaise:   true,
+			minRaiseTo: 50,
+		},
+		{
+			name:   "不完整加注后已行动的玩家",
+			stacks: []int{30, 500, 500, 500},
+			actions: []testAction{
+				{3, "call", 0}, {0, "allin", 0}, {1, "call", 0}, {2, "call", 0},
+			},
+			next: 3,
+		},
+		{
+			// 两次不完整加注累计 10，仍不足一次完整加注
+			name:   "累计不足完整加注的短码全下",
+			stacks: []int{25, 30, 500, 500},
+			actions: []testAction{
+				{3, "call", 0}, {0, "allin", 0}, {1, "allin", 0}, {2, "call", 0},
+			},
+			next: 3,
+		},
+		{
+			// 两次不完整加注累计 20，达到一次完整加注，重新开放加注
+			name:   "累计达到完整加注的短码全下",
+			stacks: []int{30, 40, 500, 500},
+			actions: []testAction{
+				{3, "call", 0}, {0, "allin", 0}, {1, "allin", 0}, {2, "call", 0},
+			},
+			next:       3,
+			canRaise:   true,
+			minRaiseTo: 60,
+		},
+		{
+			// 加注到 60 之后完整加注为 40，两次不完整加注累计 30 仍不够
+			name:   "加注后累计不足上次加注增量",
+			stacks: []int{75, 90, 500, 500},
+			actions: []testAction{
+				{3, "raise", 60}, {0, "allin", 0}, {1, "allin", 0}, {2, "fold", 0},
+			},
+			next: 3,
+		},
+		{
+			name:   "加注后累计达到上次加注增量",
+			stacks: []int{90, 120, 500, 500},
+			actions: []testAction{
+				{3, "raise", 60}, {0, "allin", 0}, {1, "allin", 0}, {2, "fold", 0},
+			},
+			next:       3,
+			canRaise:   true,
+			minRaiseTo: 160,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, tt.stacks...)
+			startTestHand(t, g, testDeck(t, holes, "2c 7d 9h 3s 4c"))
+			playActions(t, g, tt.actions)
+
+			if g.CurrentPlayer != tt.next {
+				t.Fatalf("轮到座位%d，期望座位%d", g.CurrentPlayer, tt.next)
+			}
+			if g.MinRaiseTo != tt.minRaiseTo {
+				t.Errorf("MinRaiseTo = %d, 期望 %d", g.MinRaiseTo, tt.minRaiseTo)
+			}
+			userID := g.Players[tt.next].UserId
+			err := g.ValidateAction(userID, "raise", g.CurrentBet+100)
+			if tt.canRaise && err != nil {
+				t.Errorf("应该可以加注: %v", err)
+			}
+			if !tt.canRaise && err != ErrRaiseLocked {
+				t.Errorf("加注 = %v, 期望 %v", err, ErrRaiseLocked)
+			}
+			if err := g.ValidateAction(userID, "call", 0); err != nil {
+				t.Errorf("应该可以跟注: %v", err)
+			}
+		})
+	}
+}
+
+func TestMinRaiseTracking(t *testing.T) {
+	holes := []string{"AsAh", "KsKh", "QsQh", "JsJh"}
+	tests := []struct {
+		name       string
+		stacks     []int
+		actions    []testAction
+		next       int
+		minRaiseTo int
+		maxRaiseTo int
+	}{
+		{
+			name:       "翻牌前第一个行动",
+			stacks:     []int{500, 500, 500, 500},
+			next:       3,
+			minRaiseTo: 40,
+			maxRaiseTo: 500,
+		},
+		{
+			name:       "加注后按加注增量计算",
+			stacks:     []int{500, 500, 500, 500},
+			actions:    []testAction{{3, "raise", 60}},
+			next:       0,
+			minRaiseTo: 100,
+			maxRaiseTo: 500,
+		},
+		{
+			name:       "再加注后按更大的增量计算",
+			stacks:     []int{500, 500, 500, 500},
+			actions:    []testAction{{3, "raise", 60}, {0, "raise", 200}},
+			next:       1,
+			minRaiseTo: 340,
+			maxRaiseTo: 500,
+		},
+		{
+			// 全下到 30 只比大盲多 10，不改变最小加注增量
+			name:       "不完整加注不改变增量",
+			stacks:     []int{500, 500, 500, 30},
+			actions:    []testAction{{3, "allin", 0}},
+			next:       0,
+			minRaiseTo: 50,
+			maxRaiseTo: 500,
+		},
+		{
+			name:   "翻牌后重新按大盲计算",
+			stacks: []int{500, 500, 500, 500},
+			actions: []testAction{
+				{3, "raise", 60}, {0, "call", 0}, {1, "call", 0}, {2, "call", 0},
+			},
+			next:       1,
+			minRaiseTo: 20,
+			maxRaiseTo: 440,
+		},
+		{
+			name:       "筹码不足最小加注时只能全下",
+			stacks:     []int{500, 500, 500, 30},
+			next:       3,
+			minRaiseTo: 30,
+			maxRaiseTo: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, tt.stacks...)
+			startTestHand(t, g, testDeck(t, holes, "2c 7d 9h 3s 4c"))
+			playActions(t, g, tt.actions)
+
+			if g.CurrentPlayer != tt.next {
+				t.Fatalf("轮到座位%d，期望座位%d", g.CurrentPlayer, tt.next)
+			}
+			if g.MinRaiseTo != tt.minRaiseTo || g.MaxRaiseTo != tt.maxRaiseTo {
+				t.Errorf("加注范围 = %d-%d, 期望 %d-%d", g.MinRaiseTo, g.MaxRaiseTo, tt.minRaiseTo, tt.maxRaiseTo)
+			}
+
+			userID := g.Players[tt.next].UserId
+			if tt.minRaiseTo < tt.maxRaiseTo {
+				if err := g.ValidateAction(userID, "raise", tt.minRaiseTo-1); err == nil {
+					t.Errorf("加注到 %d 应该被拒绝", tt.minRaiseTo-1)
+				}
+			}
+			if err := g.ValidateAction(userID, "raise", tt.minRaiseTo); err != nil {
+				t.Errorf("加注到 %d 应该可以: %v", tt.minRaiseTo, err)
+			}
+		})
+	}
+}
+
+// 单挑时庄家下小盲，翻牌前先行动、翻牌后最后行动，庄家每局轮换
+func TestHeadsUpBlindOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		stacks  []int
+		dealers []int // 每一局的庄家
+	}{
+		{
+			name:    "两个玩家",
+			stacks:  []int{500, 500},
+			dealers: []int{0, 1, 0},
+		},
+		{
+			// 筹码输光的座位不发牌也不下盲注，剩下两个玩家按单挑的顺序
+			name:    "筹码输光的座位不参与",
+			stacks:  []int{500, 0, 500},
+			dealers: []int{0, 2, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, tt.stacks...)
+			for hand, dealer := range tt.dealers {
+				bigBlind := tt.dealers[(hand+1)%2]
+				startTestHand(t, g, testDeck(t, []string{"AsAh", "KsKh"}, "2c 7d 9h 3s 4c"))
+
+				if g.DealerPos != dealer || g.SmallBlindPos != dealer || g.BigBlindPos != bigBlind {
+					t.Fatalf("第%d局 庄家/小盲/大盲 = %d/%d/%d, 期望 %d/%d/%d", hand+1,
+						g.DealerPos, g.SmallBlindPos, g.BigBlindPos, dealer, dealer, bigBlind)
+				}
+				if g.Players[dealer].CurrentBet != 10 || g.Players[bigBlind].CurrentBet != 20 {
+					t.Fatalf("第%d局 盲注 = %d/%d", hand+1, g.Players[dealer].CurrentBet, g.Players[bigBlind].CurrentBet)
+				}
+				for i, player := range g.Players {
+					if i != dealer && i != bigBlind && len(player.HoleCards) > 0 {
+						t.Fatalf("第%d局 座位%d 不应该发牌", hand+1, i)
+					}
+				}
+
+				// 翻牌前庄家先行动，翻牌后大盲先行动
+				playActions(t, g, []testAction{
+					{dealer, "call", 0}, {bigBlind, "check", 0},
+					{bigBlind, "raise", 20}, {dealer, "fold", 0},
+				})
+				if g.GameStatus == GameStatusPlaying {
+					t.Fatalf("第%d局没有结束", hand+1)
+				}
+			}
+		})
+	}
+}
+
+func TestAllInShowdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		stacks    []int
+		holes     []string
+		board     string
+		actions   []testAction
+		wantChips []int
+	}{
+		{
+			name:   "多人全下按边池结算",
+			stacks: []int{100, 300, 500, 500},
+			holes:  []string{"AsAh", "KsKh", "QsQh", "JsJh"},
+			board:  "2c 7d 9h 3s 4c",
+			actions: []testAction{
+				{3, "allin", 0},
+				{0, "allin", 0},
+				{1, "allin", 0},
+				{2, "call", 0},
+			},
+			wantChips: []int{400, 600, 400, 0},
+		},
+		{
+			// 短码全下 100，后面加注到 400 无人跟注，多出的 300 在结算前退还
+			name:   "退还无人跟注的加注",
+			stacks: []int{100, 500, 500},
+			holes:  []string{"AsAh", "KsKh", "2c7d"},
+			board:  "3c 8d 9h Td 4s",
+			actions: []testAction{
+				{0, "allin", 0},
+				{1, "raise", 400},
+				{2, "fold", 0},
+			},
+			wantChips: []int{220, 400, 480},
+		},
+		{
+			name:   "筹码不足时跟注全下",
+			stacks: []int{500, 500, 60},
+			holes:  []string{"AsAh", "KsKh", "QsQh"},
+			board:  "2c 7d 9h 3s 4c",
+			actions: []testAction{
+				{0, "raise", 200},
+				{1, "fold", 0},
+				{2, "call", 0},
+			},
+			wantChips: []int{570, 490, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, tt.stacks...)
+			startTestHand(t, g, testDeck(t, tt.holes, tt.board))
+			playActions(t, g, tt.actions)
+
+			if g.GameStatus == GameStatusPlaying {
+				t.Fatalf("牌局没有结束，阶段: %s", g.GamePhase)
+			}
+			if got := testChips(g, len(tt.stacks)); !reflect.DeepEqual(got, tt.wantChips) {
+				t.Errorf("筹码 = %v, 期望 %v", got, tt.wantChips)
+			}
+		})
+	}
+}
+
+func TestChipAdditionsRecorded(t *testing.T) {
+	g := newTestGame(t, 10, 20, 0, 500, 500)
+	g.Players[0].AddChips(ChipsRebuy, 400)
+	g.Players[1].AddChips(ChipsTopUp, 100)
+
+	startTestHand(t, g, testDeck(t, []string{"AsAh", "KsKh", "QsQh"}, "2c 7d 9h 3s 4c"))
+	playActions(t, g, []testAction{{0, "fold", 0}, {1, "fold", 0}})
+	if g.CurrentRound == nil {
+		t.Fatalf("牌局没有结束，阶段: %s", g.GamePhase)
+	}
+
+	tests := []struct {
+		seat      int
+		initChips int
+		additions []ChipAddition
+	}{
+		{seat: 0, initChips: 400, additions: []ChipAddition{{Type: ChipsRebuy, Amount: 400}}},
+		{seat: 1, initChips: 600, additions: []ChipAddition{{Type: ChipsTopUp, Amount: 100}}},
+		{seat: 2, initChips: 500},
+	}
+	for _, tt := range tests {
+		info := g.CurrentRound.Players[tt.seat]
+		if info.InitChips != tt.initChips || !reflect.DeepEqual(info.Additions, tt.additions) {
+			t.Errorf("座位%d 初始筹码 %d，补充 %+v，期望 %d、%+v", tt.seat, info.InitChips, info.Additions, tt.initChips, tt.additions)
+		}
+		if len(g.Players[tt.seat].Additions) != 0 {
+			t.Errorf("座位%d 开局后仍有待记录的补充筹码", tt.seat)
+		}
+	}
+	if g.Players[0].Rebuys != 1 || g.Players[1].Rebuys != 0 {
+		t.Errorf("补码次数 = %d、%d，期望 1、0", g.Players[0].Rebuys, g.Players[1].Rebuys)
+	}
+}
+
+// foldHand 开始一局，轮到的玩家依次弃牌直到本局结束
+func foldHand(t *testing.T, g *Game) {
+	t.Helper()
+	startTestHand(t, g, testDeck(t, []string{"AsAh", "KsKh", "QsQh", "JsJh"}, "2c 7d 9h 3s 4c"))
+	for g.GameStatus == GameStatusPlaying {
+		if !g.PlayerAction(g.Players[g.CurrentPlayer].UserId, "fold", 0) {
+			t.Fatalf("座位%d 弃牌失败", g.CurrentPlayer)
+		}
+	}
+}
+
+func TestSitOutMissedBlinds(t *testing.T) {
+	// 座位3从第一局开始暂时离开，其余三个玩家轮流下大盲，第2、5局大盲经过座位3
+	tests := []struct {
+		name         string
+		missedBlinds bool
+		orbits       int // 自动离座的圈数
+		hands        int // 离开期间打的局数
+		back         bool
+		wantOrbits   int
+		wantPosted   int   // 回来后第一局补交的盲注
+		wantRemoved  []int // 需要自动离座的座位
+	}{
+		{name: "回来后补交错过的大盲", missedBlinds: true, orbits: 3, hands: 2, back: true, wantPosted: 20, wantRemoved: []int{}},
+		{name: "不要求补交盲注", orbits: 3, hands: 2, back: true, wantRemoved: []int{}},
+		{name: "离开不足圈数", missedBlinds: true, orbits: 2, hands: 4, wantOrbits: 1, wantRemoved: []int{}},
+		{name: "离开达到圈数后自动离座", missedBlinds: true, orbits: 2, hands: 5, wantOrbits: 2, wantRemoved: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, 1000, 1000, 1000, 1000)
+			g.Config.MissedBlinds = tt.missedBlinds
+			g.Config.SitOutOrbits = tt.orbits
+			if err := g.SetSittingOut("u3", true); err != nil {
+				t.Fatalf("暂时离开失败: %v", err)
+			}
+
+			for hand := 0; hand < tt.hands; hand++ {
+				foldHand(t, g)
+				if len(g.Players[3].HoleCards) > 0 || g.Players[3].Chips != 1000 {
+					t.Fatalf("第%d局 暂时离开的座位参与了本局", hand+1)
+				}
+			}
+
+			if tt.back {
+				if err := g.SetSittingOut("u3", false); err != nil {
+					t.Fatalf("回到座位失败: %v", err)
+				}
+				foldHand(t, g)
+				posted := 0
+				for _, action := range g.CurrentRound.Actions {
+					if action.Type == ActionMissedBlind && action.Position == 3 {
+						posted += action.Amount
+					}
+				}
+				if posted != tt.wantPosted {
+					t.Errorf("补交盲注 = %d, 期望 %d", posted, tt.wantPosted)
+				}
+			}
+
+			if g.Players[3].OrbitsAway != tt.wantOrbits {
+				t.Errorf("离开圈数 = %d, 期望 %d", g.Players[3].OrbitsAway, tt.wantOrbits)
+			}
+			if got := g.AwayTooLong(); !reflect.DeepEqual(got, tt.wantRemoved) {
+				t.Errorf("自动离座 = %v, 期望 %v", got, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestReadyIgnoresSittingOut(t *testing.T) {
+	tests := []struct {
+		name  string
+		away  []string // 暂时离开的玩家
+		ready []string // 已准备的玩家
+		want  bool
+	}{
+		{name: "所有玩家都已准备", ready: []string{"u0", "u1", "u2"}, want: true},
+		{name: "有玩家没有准备", ready: []string{"u0", "u1"}},
+		{name: "暂时离开的玩家不用准备", away: []string{"u2"}, ready: []string{"u0", "u1"}, want: true},
+		{name: "准备的玩家不足", away: []string{"u1", "u2"}, ready: []string{"u0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, 500, 500, 500)
+			for _, userID := range tt.away {
+				if err := g.SetSittingOut(userID, true); err != nil {
+					t.Fatalf("暂时离开失败: %v", err)
+				}
+				if g.SetPlayerReady(userID, true) {
+					t.Fatalf("暂时离开的玩家 %s 不应该能准备", userID)
+				}
+			}
+			for _, userID := range tt.ready {
+				if !g.SetPlayerReady(userID, true) {
+					t.Fatalf("玩家 %s 准备失败", userID)
+				}
+			}
+			if got := g.CheckAllPlayersReady(); got != tt.want {
+				t.Errorf("CheckAllPlayersReady = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package poker
 
 import (
 	"sort"
+
+	"github.com/lllllan02/holdem/i18n"
 )
 
 // 牌型常量（从小到大）
@@ -227,30 +229,7 @@ func CompareHands(hand1, hand2 HandRank) int {
 	return 0
 }
 
-// GetHandName 获取牌型名称
+// GetHandName 获取默认语言的牌型名称
 func GetHandName(rank int) string {
-	switch rank {
-	case RoyalFlush:
-		return "皇家同花顺"
-	case StraightFlush:
-		return "同花顺"
-	case FourOfAKind:
-		return "四条"
-	case FullHouse:
-		return "葫芦"
-	case Flush:
-		return "同花"
-	case Straight:
-		return "顺子"
-	case ThreeOfAKind:
-		return "三条"
-	case TwoPair:
-		return "两对"
-	case OnePair:
-		return "一对"
-	case HighCard:
-		return "高牌"
-	default:
-		return "未知"
-	}
+	return i18n.HandName(i18n.Default, rank)
 }
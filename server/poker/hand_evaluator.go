@@ -3,6 +3,8 @@ package poker
 import (
 	"log"
 	"sort"
+
+	"github.com/lllllan02/holdem/i18n"
 )
 
 // 比较结果常量
@@ -568,32 +570,9 @@ func IsHighCard(cs [5]InternalCard) *Hand {
 	}
 }
 
-// GetHandRankName 获取牌型名称（兼容现有系统）
+// GetHandRankName 获取默认语言的牌型名称（兼容现有系统）
 func GetHandRankName(rank HandRankType) string {
-	switch rank {
-	case RoyalFlushRank:
-		return "皇家同花顺"
-	case StraightFlushRank:
-		return "同花顺"
-	case FourOfAKindRank:
-		return "四条"
-	case FullHouseRank:
-		return "葫芦"
-	case FlushRank:
-		return "同花"
-	case StraightRank:
-		return "顺子"
-	case ThreeOfAKindRank:
-		return "三条"
-	case TwoPairRank:
-		return "两对"
-	case OnePairRank:
-		return "一对"
-	case HighCardRank:
-		return "高牌"
-	default:
-		return "未知"
-	}
+	return i18n.HandName(i18n.Default, int(rank))
 }
 
 // ConvertToOldHandRank 将新的Hand转换为旧的HandRank格式（兼容性）
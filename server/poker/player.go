@@ -4,31 +4,56 @@ import (
 	"log"
 )
 
+// 补充筹码的方式
+const (
+	ChipsRebuy = "rebuy"  // 补码：筹码少于最小买入时买回
+	ChipsTopUp = "top_up" // 加码：补足到不超过最大买入
+)
+
+// ChipAddition 两局之间补充的一笔筹码
+type ChipAddition struct {
+	Type   string `json:"type"`   // 补充方式
+	Amount int    `json:"amount"` // 补充的筹码
+}
+
 // 玩家状态常量
 const (
 	PlayerStatusEmpty   = "empty"   // 空座位
 	PlayerStatusSitting = "sitting" // 已落座
 	PlayerStatusFolded  = "folded"  // 已弃牌
 	PlayerStatusAllIn   = "allin"   // 全下
-)
 
-// 默认配置常量
-const (
-	DefaultChips = 1000
+	PlayerStatusSittingOut = "sitting_out" // 暂时离开，保留座位但不参与发牌和盲注
 )
 
 type Player struct {
-	UserId     string    `json:"userId"`
-	Name       string    `json:"name"`
-	Status     string    `json:"status"`
-	Chips      int       `json:"chips"`
-	HoleCards  []Card    `json:"holeCards"`  // 底牌（只发给玩家自己）
-	CurrentBet int       `json:"currentBet"` // 当前轮下注额
-	TotalBet   int       `json:"totalBet"`   // 本局总下注额
-	HasActed   bool      `json:"hasActed"`   // 本轮是否已行动
-	HandRank   *HandRank `json:"handRank"`   // 牌型（摊牌时显示）
-	WinAmount  int       `json:"winAmount"`  // 本局赢得的金额
-	IsReady    bool      `json:"isReady"`    // 是否已准备
+	UserId      string     `json:"userId"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Chips       int        `json:"chips"`
+	HoleCards   []Card     `json:"holeCards"`       // 底牌（只发给玩家自己）
+	CurrentBet  int        `json:"currentBet"`      // 当前轮下注额
+	TotalBet    int        `json:"totalBet"`        // 本局总下注额
+	HasActed    bool       `json:"hasActed"`        // 本轮是否已行动
+	HandRank    *HandRank  `json:"handRank"`        // 牌型（摊牌时显示）
+	WinAmount   int        `json:"winAmount"`       // 本局赢得的金额
+	IsReady     bool       `json:"isReady"`         // 是否已准备
+	RaiseLocked bool       `json:"raiseLocked"`     // 面对不完整的全下加注，只能跟注或弃牌
+	TimeBank    int        `json:"timeBank"`        // 剩余时间银行（秒），整个落座期间共用
+	Stats       *SeatStats `json:"stats,omitempty"` // 玩家的历史统计数据，由牌桌填充
+	Rebuys      int        `json:"rebuys"`          // 本次落座的补码次数
+
+	// 暂时离开
+	SittingOut   bool `json:"sittingOut"`   // 是否暂时离开，从下一局开始不参与发牌和盲注
+	MissedBlinds int  `json:"missedBlinds"` // 离开期间错过大盲，回来后第一局需要补交的盲注
+	OrbitsAway   int  `json:"orbitsAway"`   // 离开期间大盲经过座位的次数，即离开的圈数
+
+	// 断线后在宽限期内保留座位、手牌和行动机会，由牌桌维护
+	Disconnected bool `json:"disconnected"`
+
+	// 上一局结束后补充的筹码，下一局开始时移入 handAdditions 并写入对局记录
+	Additions     []ChipAddition `json:"additions,omitempty"`
+	handAdditions []ChipAddition
 }
 
 // NewPlayer 创建一个新的空座位玩家
@@ -49,12 +74,13 @@ func NewPlayer() Player {
 }
 
 // NewSittingPlayer 创建一个已落座的玩家
-func NewSittingPlayer(userId, name string) Player {
+func NewSittingPlayer(userId, name string, chips, timeBank int) Player {
 	return Player{
 		UserId:    userId,
 		Name:      name,
 		Status:    PlayerStatusSitting,
-		Chips:     DefaultChips,
+		Chips:     chips,
+		TimeBank:  timeBank,
 		HandRank:  nil,
 		WinAmount: 0,
 		IsReady:   false,
@@ -79,14 +105,25 @@ func (p *Player) Reset() {
 	p.HandRank = nil
 	p.WinAmount = 0
 	p.IsReady = false
+	p.RaiseLocked = false
+	p.TimeBank = 0
+	p.Stats = nil
+	p.Rebuys = 0
+	p.Additions = nil
+	p.handAdditions = nil
+	p.SittingOut = false
+	p.MissedBlinds = 0
+	p.OrbitsAway = 0
+	p.Disconnected = false
 }
 
-// SitDown 玩家落座
-func (p *Player) SitDown(userId, name string) {
+// SitDown 玩家带着 chips 筹码落座，timeBank 为本次落座的时间银行
+func (p *Player) SitDown(userId, name string, chips, timeBank int) {
 	p.UserId = userId
 	p.Name = name
 	p.Status = PlayerStatusSitting
-	p.Chips = DefaultChips
+	p.Chips = chips
+	p.TimeBank = timeBank
 	p.HoleCards = make([]Card, 0, 2)
 	p.CurrentBet = 0
 	p.TotalBet = 0
@@ -94,6 +131,23 @@ func (p *Player) SitDown(userId, name string) {
 	p.HandRank = nil
 	p.WinAmount = 0
 	p.IsReady = false
+	p.RaiseLocked = false
+	p.Rebuys = 0
+	p.Additions = nil
+	p.handAdditions = nil
+	p.SittingOut = false
+	p.MissedBlinds = 0
+	p.OrbitsAway = 0
+	p.Disconnected = false
+}
+
+// AddChips 在两局之间补充筹码，补码计入本次落座的补码次数
+func (p *Player) AddChips(kind string, amount int) {
+	p.Chips += amount
+	p.Additions = append(p.Additions, ChipAddition{Type: kind, Amount: amount})
+	if kind == ChipsRebuy {
+		p.Rebuys++
+	}
 }
 
 // ResetForNewRound 为新一轮游戏重置玩家状态
@@ -105,11 +159,12 @@ func (p *Player) ResetForNewRound() {
 	p.HasActed = false
 	p.HandRank = nil
 	p.WinAmount = 0
+	p.RaiseLocked = false
 }
 
 // Bet 玩家下注
 func (p *Player) Bet(amount int) bool {
-	if amount > p.Chips {
+	if amount >= p.Chips {
 		// 全下
 		amount = p.Chips
 		p.Status = PlayerStatusAllIn
@@ -125,7 +180,7 @@ func (p *Player) Bet(amount int) bool {
 
 // PostBlind 玩家下盲注（不设置HasActed标志）
 func (p *Player) PostBlind(amount int) bool {
-	if amount > p.Chips {
+	if amount >= p.Chips {
 		// 全下
 		amount = p.Chips
 		p.Status = PlayerStatusAllIn
@@ -139,6 +194,33 @@ func (p *Player) PostBlind(amount int) bool {
 	return true
 }
 
+// PostAnte 玩家下前注（计入总下注，不计入本轮下注）
+func (p *Player) PostAnte(amount int) int {
+	if amount >= p.Chips {
+		// 全下
+		amount = p.Chips
+		p.Status = PlayerStatusAllIn
+	}
+
+	p.Chips -= amount
+	p.TotalBet += amount
+
+	return amount
+}
+
+// seatStatus 两局之间和新一局开始时玩家的状态
+func (p *Player) seatStatus() string {
+	if p.SittingOut {
+		return PlayerStatusSittingOut
+	}
+	return PlayerStatusSitting
+}
+
+// InHand 检查玩家是否参与了本局且没有弃牌
+func (p *Player) InHand() bool {
+	return !p.IsEmpty() && p.Status != PlayerStatusFolded && p.Status != PlayerStatusSittingOut
+}
+
 // Fold 玩家弃牌
 func (p *Player) Fold() {
 	p.Status = PlayerStatusFolded
@@ -0,0 +1,190 @@
+package poker
+
+import (
+	"log"
+	"sort"
+)
+
+// Pot 底池（主池或边池）
+type Pot struct {
+	Amount   int         `json:"amount"`            // 底池金额
+	Eligible []int       `json:"eligible"`          // 有资格争夺该底池的玩家座位索引
+	Winners  []PotWinner `json:"winners,omitempty"` // 该底池的获胜者（结算后填充）
+}
+
+// PotWinner 记录某个底池的获胜者及分得的金额
+type PotWinner struct {
+	Position int `json:"position"` // 座位位置
+	Amount   int `json:"amount"`   // 分得金额
+}
+
+// calculatePots 根据每个玩家的总下注额构建主池和边池
+// 每一层的金额由所有玩家（包括已弃牌玩家）在该层的投入组成，
+// 只有未弃牌且投入达到该层的玩家才有资格争夺
+func (g *Game) calculatePots() []Pot {
+	// 收集未弃牌玩家的下注层级
+	levelSet := make(map[int]bool)
+	for _, player := range g.Players {
+		if !player.IsEmpty() && player.Status != PlayerStatusFolded && player.TotalBet > 0 {
+			levelSet[player.TotalBet] = true
+		}
+	}
+
+	levels := make([]int, 0, len(levelSet))
+	for level := range levelSet {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+
+	pots := make([]Pot, 0, len(levels))
+	prevLevel := 0
+	for _, level := range levels {
+		pot := Pot{Eligible: make([]int, 0)}
+		for i, player := range g.Players {
+			if player.IsEmpty() {
+				continue
+			}
+			pot.Amount += min(player.TotalBet, level) - min(player.TotalBet, prevLevel)
+			if player.Status != PlayerStatusFolded && player.TotalBet >= level {
+				pot.Eligible = append(pot.Eligible, i)
+			}
+		}
+
+		// 与上一个底池参与者完全相同时合并（例如多层下注都没有人全下）
+		if n := len(pots); n > 0 && len(pots[n-1].Eligible) == len(pot.Eligible) {
+			pots[n-1].Amount += pot.Amount
+		} else {
+			pots = append(pots, pot)
+		}
+		prevLevel = level
+	}
+
+	// 已弃牌玩家超过最高层级的投入属于死钱，并入最后一个底池
+	if len(pots) > 0 {
+		for _, player := range g.Players {
+			if !player.IsEmpty() && player.TotalBet > prevLevel {
+				pots[len(pots)-1].Amount += player.TotalBet - prevLevel
+			}
+		}
+	}
+
+	return pots
+}
+
+// updatePots 重新计算并更新底池明细
+func (g *Game) updatePots() {
+	g.Pots = g.calculatePots()
+}
+
+// returnUncalledBet 将无人跟注的超额下注退还给下注者
+func (g *Game) returnUncalledBet() {
+	highest, second := -1, 0
+	for i, player := range g.Players {
+		if player.IsEmpty() {
+			continue
+		}
+		if highest == -1 || player.TotalBet > g.Players[highest].TotalBet {
+			if highest != -1 {
+				second = g.Players[highest].TotalBet
+			}
+			highest = i
+		} else if player.TotalBet > second {
+			second = player.TotalBet
+		}
+	}
+
+	if highest == -1 {
+		return
+	}
+
+	player := &g.Players[highest]
+	uncalled := player.TotalBet - second
+	if uncalled <= 0 {
+		return
+	}
+
+	player.Chips += uncalled
+	player.TotalBet -= uncalled
+	if player.CurrentBet >= uncalled {
+		player.CurrentBet -= uncalled
+	} else {
+		player.CurrentBet = 0
+	}
+	if player.Status == PlayerStatusAllIn && player.Chips > 0 {
+		player.Status = PlayerStatusSitting
+	}
+	g.Pot -= uncalled
+	g.recordAction(highest, ActionUncalled, uncalled, 0, false)
+	log.Printf("[底池] 退还玩家 %s (座位%d) 无人跟注的下注 %d", player.Name, highest+1, uncalled)
+}
+
+// awardPots 依次结算每个底池，返回每个座位赢得的金额和最佳牌型
+func (g *Game) awardPots() (map[int]int, map[int]*Hand) {
+	winAmounts := make(map[int]int)
+	bestHands := make(map[int]*Hand)
+
+	for potIndex := range g.Pots {
+		pot := &g.Pots[potIndex]
+		if pot.Amount == 0 || len(pot.Eligible) == 0 {
+			continue
+		}
+
+		// 按庄家之后的座位顺序排列，余数筹码优先分给靠前的玩家
+		eligible := g.orderFromDealer(pot.Eligible)
+		players := make([]*Player, 0, len(eligible))
+		for _, pos := range eligible {
+			players = append(players, &g.Players[pos])
+		}
+
+		winners := FindWinningHands(players, g.CommunityCards)
+		if len(winners) == 0 {
+			log.Printf("[底池] 警告：底池%d没有找到获胜者", potIndex+1)
+			continue
+		}
+
+		winnerSet := make(map[*Player]bool)
+		for _, winner := range winners {
+			winnerSet[winner.Player] = true
+		}
+
+		share := pot.Amount / len(winners)
+		remainder := pot.Amount % len(winners)
+		pot.Winners = make([]PotWinner, 0, len(winners))
+		for _, pos := range eligible {
+			player := &g.Players[pos]
+			if !winnerSet[player] {
+				continue
+			}
+
+			amount := share
+			if remainder > 0 {
+				amount++
+				remainder--
+			}
+			pot.Winners = append(pot.Winners, PotWinner{Position: pos, Amount: amount})
+			winAmounts[pos] += amount
+
+			for _, winner := range winners {
+				if winner.Player == player {
+					bestHands[pos] = winner.Hand
+				}
+			}
+			log.Printf("[底池] 底池%d (%d): 玩家 %s (座位%d) 分得 %d", potIndex+1, pot.Amount, player.Name, pos+1, amount)
+		}
+	}
+
+	return winAmounts, bestHands
+}
+
+// orderFromDealer 将座位按庄家之后的顺时针顺序排列
+func (g *Game) orderFromDealer(positions []int) []int {
+	ordered := make([]int, len(positions))
+	copy(ordered, positions)
+
+	seats := len(g.Players)
+	start := g.DealerPos + 1
+	sort.Slice(ordered, func(i, j int) bool {
+		return (ordered[i]-start+seats*2)%seats < (ordered[j]-start+seats*2)%seats
+	})
+	return ordered
+}
@@ -0,0 +1,243 @@
+package poker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSeat 测试中一个座位的下注情况，holes 为空表示不需要比牌
+type testSeat struct {
+	status   string
+	chips    int
+	totalBet int
+	holes    string
+}
+
+// newPotGame 按座位的下注情况构造一局，底池为所有座位下注之和
+func newPotGame(t *testing.T, dealer int, board string, seats []testSeat) *Game {
+	t.Helper()
+	g := &Game{DealerPos: dealer, CommunityCards: parseCards(t, board)}
+	for i, seat := range seats {
+		player := Player{
+			UserId:    string(rune('a' + i)),
+			Name:      string(rune('A' + i)),
+			Status:    seat.status,
+			Chips:     seat.chips,
+			TotalBet:  seat.totalBet,
+			HoleCards: parseCards(t, seat.holes),
+		}
+		g.Players = append(g.Players, player)
+		g.Pot += seat.totalBet
+	}
+	g.updatePots()
+	return g
+}
+
+// parseCard 解析 "Ah"、"Td" 形式的牌
+func parseCard(t *testing.T, s string) Card {
+	t.Helper()
+	suits := map[byte]string{'s': "spades", 'h': "hearts", 'd': "diamonds", 'c': "clubs"}
+	ranks := "23456789TJQKA"
+	if len(s) != 2 {
+		t.Fatalf("无效的牌: %s", s)
+	}
+	value := strings.IndexByte(ranks, s[0])
+	suit, ok := suits[s[1]]
+	if value < 0 || !ok {
+		t.Fatalf("无效的牌: %s", s)
+	}
+	rank := string(s[0])
+	if rank == "T" {
+		rank = "10"
+	}
+	return Card{Suit: suit, Rank: rank, Value: value + 2}
+}
+
+// parseCards 解析连续或以空格分隔的多张牌，例如 "AsAh" 或 "2c 7d 9h"
+func parseCards(t *testing.T, s string) []Card {
+	t.Helper()
+	s = strings.ReplaceAll(s, " ", "")
+	cards := make([]Card, 0, len(s)/2)
+	for i := 0; i+2 <= len(s); i += 2 {
+		cards = append(cards, parseCard(t, s[i:i+2]))
+	}
+	return cards
+}
+
+func TestCalculatePots(t *testing.T) {
+	tests := []struct {
+		name  string
+		seats []testSeat
+		want  []Pot
+	}{
+		{
+			name: "没有全下时只有一个底池",
+			seats: []testSeat{
+				{status: PlayerStatusSitting, totalBet: 20},
+				{status: PlayerStatusSitting, totalBet: 20},
+				{status: PlayerStatusSitting, totalBet: 20},
+			},
+			want: []Pot{{Amount: 60, Eligible: []int{0, 1, 2}}},
+		},
+		{
+			name: "多人全下的边池",
+			seats: []testSeat{
+				{status: PlayerStatusAllIn, totalBet: 100},
+				{status: PlayerStatusAllIn, totalBet: 300},
+				{status: PlayerStatusAllIn, totalBet: 500},
+				{status: PlayerStatusSitting, totalBet: 500},
+			},
+			want: []Pot{
+				{Amount: 400, Eligible: []int{0, 1, 2, 3}},
+				{Amount: 600, Eligible: []int{1, 2, 3}},
+				{Amount: 400, Eligible: []int{2, 3}},
+			},
+		},
+		{
+			// 弃牌玩家的投入按层级计入底池，但没有资格争夺
+			name: "弃牌玩家的投入留在底池",
+			seats: []testSeat{
+				{status: PlayerStatusFolded, totalBet: 50},
+				{status: PlayerStatusAllIn, totalBet: 100},
+				{status: PlayerStatusSitting, totalBet: 200},
+				{status: PlayerStatusSitting, totalBet: 200},
+			},
+			want: []Pot{
+				{Amount: 350, Eligible: []int{1, 2, 3}},
+				{Amount: 200, Eligible: []int{2, 3}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPotGame(t, 0, "", tt.seats)
+			if !reflect.DeepEqual(g.Pots, tt.want) {
+				t.Errorf("底池 = %+v, 期望 %+v", g.Pots, tt.want)
+			}
+		})
+	}
+}
+
+func TestAwardPots(t *testing.T) {
+	tests := []struct {
+		name   string
+		dealer int
+		board  string
+		seats  []testSeat
+		want   map[int]int // 每个座位赢得的筹码
+	}{
+		{
+			// 短码牌最大，只能赢走自己投入所在层级的主池
+			name:  "短码只赢主池",
+			board: "2c 7d 9h 3s 4c",
+			seats: []testSeat{
+				{status: PlayerStatusAllIn, totalBet: 100, holes: "AsAh"},
+				{status: PlayerStatusAllIn, totalBet: 300, holes: "QsQh"},
+				{status: PlayerStatusAllIn, totalBet: 500, holes: "JsJh"},
+				{status: PlayerStatusAllIn, totalBet: 500, holes: "KsKh"},
+			},
+			want: map[int]int{0: 400, 3: 1000},
+		},
+		{
+			name:  "每个边池由各自的最大牌赢得",
+			board: "2c 7d 9h 3s 4c",
+			seats: []testSeat{
+				{status: PlayerStatusAllIn, totalBet: 100, holes: "AsAh"},
+				{status: PlayerStatusAllIn, totalBet: 300, holes: "KsKh"},
+				{status: PlayerStatusAllIn, totalBet: 500, holes: "QsQh"},
+				{status: PlayerStatusAllIn, totalBet: 500, holes: "JsJh"},
+			},
+			want: map[int]int{0: 400, 1: 600, 2: 400},
+		},
+		{
+			// 25 平分时多出的 1 个筹码给庄家之后最先的赢家
+			name:   "平分底池的余数筹码",
+			dealer: 0,
+			board:  "As Ks Qs Js Ts",
+			seats: []testSeat{
+				{status: PlayerStatusSitting, totalBet: 10, holes: "2c3d"},
+				{status: PlayerStatusFolded, totalBet: 5},
+				{status: PlayerStatusSitting, totalBet: 10, holes: "6c7d"},
+			},
+			want: map[int]int{0: 12, 2: 13},
+		},
+		{
+			name:   "余数筹码从庄家之后开始分",
+			dealer: 2,
+			board:  "As Ks Qs Js Ts",
+			seats: []testSeat{
+				{status: PlayerStatusSitting, totalBet: 10, holes: "2c3d"},
+				{status: PlayerStatusFolded, totalBet: 5},
+				{status: PlayerStatusSitting, totalBet: 10, holes: "6c7d"},
+			},
+			want: map[int]int{0: 13, 2: 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPotGame(t, tt.dealer, tt.board, tt.seats)
+			won, _ := g.awardPots()
+			if !reflect.DeepEqual(won, tt.want) {
+				t.Errorf("赢得 = %v, 期望 %v", won, tt.want)
+			}
+		})
+	}
+}
+
+func TestReturnUncalledBet(t *testing.T) {
+	tests := []struct {
+		name      string
+		seats     []testSeat
+		wantChips []int
+		wantBets  []int
+		wantPot   int
+	}{
+		{
+			name: "最高下注无人跟注",
+			seats: []testSeat{
+				{status: PlayerStatusSitting, chips: 100, totalBet: 400},
+				{status: PlayerStatusAllIn, totalBet: 100},
+				{status: PlayerStatusFolded, chips: 480, totalBet: 20},
+			},
+			wantChips: []int{400, 0, 480},
+			wantBets:  []int{100, 100, 20},
+			wantPot:   220,
+		},
+		{
+			name: "最高下注已被跟注",
+			seats: []testSeat{
+				{status: PlayerStatusSitting, chips: 100, totalBet: 100},
+				{status: PlayerStatusSitting, chips: 100, totalBet: 100},
+			},
+			wantChips: []int{100, 100},
+			wantBets:  []int{100, 100},
+			wantPot:   200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPotGame(t, 0, "", tt.seats)
+			g.returnUncalledBet()
+
+			gotChips := make([]int, 0, len(g.Players))
+			gotBets := make([]int, 0, len(g.Players))
+			for _, player := range g.Players {
+				gotChips = append(gotChips, player.Chips)
+				gotBets = append(gotBets, player.TotalBet)
+			}
+			if !reflect.DeepEqual(gotChips, tt.wantChips) {
+				t.Errorf("筹码 = %v, 期望 %v", gotChips, tt.wantChips)
+			}
+			if !reflect.DeepEqual(gotBets, tt.wantBets) {
+				t.Errorf("总下注 = %v, 期望 %v", gotBets, tt.wantBets)
+			}
+			if g.Pot != tt.wantPot {
+				t.Errorf("底池 = %d, 期望 %d", g.Pot, tt.wantPot)
+			}
+		})
+	}
+}
@@ -1,12 +1,10 @@
 package poker
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -18,15 +16,46 @@ const (
 // GameRound 记录一局游戏的信息
 type GameRound struct {
 	RoundID        string              `json:"roundId"`        // 对局ID
+	TableName      string              `json:"tableName"`      // 牌桌名称
+	Private        bool                `json:"private"`        // 是否为私人牌桌的对局，只对参与的玩家公开
 	StartTime      int64               `json:"startTime"`      // 开始时间
 	EndTime        int64               `json:"endTime"`        // 结束时间
 	DealerPos      int                 `json:"dealerPos"`      // 庄家位置
 	SmallBlindPos  int                 `json:"smallBlindPos"`  // 小盲位置
 	BigBlindPos    int                 `json:"bigBlindPos"`    // 大盲位置
+	Seats          int                 `json:"seats"`          // 座位数量
+	SmallBlind     int                 `json:"smallBlind"`     // 小盲注
+	BigBlind       int                 `json:"bigBlind"`       // 大盲注
+	Ante           int                 `json:"ante"`           // 前注
+	Betting        string              `json:"betting"`        // 下注结构
+	Deck           []Card              `json:"-"`              // 洗好的完整牌堆，用于回放，只保存在存储中
 	Pot            int                 `json:"pot"`            // 总底池
+	Pots           []Pot               `json:"pots"`           // 主池和边池明细
 	CommunityCards []Card              `json:"communityCards"` // 公共牌
 	Players        []PlayerRoundInfo   `json:"players"`        // 玩家信息
 	Winners        []PlayerWinningInfo `json:"winners"`        // 获胜者信息
+	Actions        []Action            `json:"actions"`        // 按顺序记录的所有行动
+}
+
+// StoredRound 存储中保存的对局记录
+// 牌堆可以推算出弃牌玩家的手牌和没有发出的公共牌，不能随对局记录发送给客户端，只在存储中保存
+type StoredRound struct {
+	*GameRound
+	Deck []Card `json:"deck"`
+}
+
+// NewStoredRound 创建保存到存储中的对局记录
+func NewStoredRound(record *GameRound) *StoredRound {
+	return &StoredRound{GameRound: record, Deck: record.Deck}
+}
+
+// Round 返回从存储中读出的对局记录，包含牌堆
+func (s *StoredRound) Round() *GameRound {
+	if s.GameRound == nil {
+		return nil
+	}
+	s.GameRound.Deck = s.Deck
+	return s.GameRound
 }
 
 // PlayerRoundInfo 记录一局游戏中玩家的信息
@@ -41,6 +70,8 @@ type PlayerRoundInfo struct {
 	Status      string `json:"status"`      // 最终状态
 	HoleCards   []Card `json:"holeCards"`   // 手牌
 	HandRank    string `json:"handRank"`    // 牌型
+
+	Additions []ChipAddition `json:"additions,omitempty"` // 这一局开始前补充的筹码，已计入初始筹码
 }
 
 // PlayerWinningInfo 记录获胜者信息
@@ -57,16 +88,26 @@ type PlayerWinningInfo struct {
 func CreateGameRecord(g *Game, winners []PlayerHand, winAmounts []int) *GameRound {
 	now := time.Now()
 	gameRound := &GameRound{
-		RoundID:        now.Format("150405"), // 使用时分秒作为对局ID
-		StartTime:      now.Unix(),
+		RoundID:        g.RoundID,
+		TableName:      g.Config.Name,
+		Private:        g.Private,
+		StartTime:      g.HandStartTime,
 		EndTime:        now.Unix(),
 		DealerPos:      g.DealerPos,
-		SmallBlindPos:  -1, // 这里可以不记录，因为已经结束了
-		BigBlindPos:    -1, // 这里可以不记录，因为已经结束了
+		SmallBlindPos:  g.SmallBlindPos,
+		BigBlindPos:    g.BigBlindPos,
+		Seats:          len(g.Players),
+		SmallBlind:     g.SmallBlind,
+		BigBlind:       g.BigBlind,
+		Ante:           g.Ante,
+		Betting:        g.Config.BettingStructure,
+		Deck:           g.HandDeck,
 		Pot:            g.Pot,
+		Pots:           g.Pots,
 		CommunityCards: g.CommunityCards,
 		Players:        make([]PlayerRoundInfo, 0),
 		Winners:        make([]PlayerWinningInfo, 0),
+		Actions:        append([]Action(nil), g.Actions...),
 	}
 
 	// 创建一个映射来存储每个玩家赢得的金额
@@ -93,6 +134,7 @@ func CreateGameRecord(g *Game, winners []PlayerHand, winAmounts []int) *GameRoun
 				Status:      player.Status,
 				HoleCards:   player.HoleCards,
 				HandRank:    "",
+				Additions:   player.handAdditions,
 			}
 			if player.HandRank != nil {
 				playerInfo.HandRank = GetHandRankName(HandRankType(player.HandRank.Rank))
@@ -126,40 +168,33 @@ func CreateGameRecord(g *Game, winners []PlayerHand, winAmounts []int) *GameRoun
 	return gameRound
 }
 
-// SaveGameRecord 保存对局记录到文件
-func SaveGameRecord(record *GameRound) error {
-	if record == nil {
-		return nil
+// saveRecord 保存当前对局记录，回放时不保存
+func (g *Game) saveRecord() {
+	if g.replay {
+		return
 	}
-
-	// 创建记录目录
-	now := time.Now()
-	dateDir := filepath.Join(recordDir, now.Format("2006-01-02"))
-	if err := os.MkdirAll(dateDir, 0755); err != nil {
-		return fmt.Errorf("创建记录目录失败: %v", err)
+	if err := SaveGameRecord(g.CurrentRound); err != nil {
+		log.Printf("[警告] 保存对局记录失败: %v", err)
 	}
+}
 
-	filename := filepath.Join(dateDir, fmt.Sprintf("%s.json", now.Format("150405")))
-
-	// 将记录转换为JSON
-	data, err := json.MarshalIndent(record, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化对局记录失败: %v", err)
+// SaveGameRecord 保存对局记录
+func SaveGameRecord(record *GameRound) error {
+	if record == nil {
+		return nil
 	}
-
-	// 写入文件
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("写入对局记录失败: %v", err)
+	if err := recordStore.SaveRound(record); err != nil {
+		return err
 	}
 
-	log.Printf("[游戏] 对局记录已保存到文件: %s", filename)
+	log.Printf("[游戏] 对局记录已保存: %s", record.RoundID)
 	return nil
 }
 
-// GetRecentGameRecords 获取最近的游戏记录
+// GetGameRecords 获取最近几天的历史记录，按时间倒序排序
 // days 参数指定要获取最近几天的记录，默认为 7 天
 // limit 参数指定最多返回多少条记录，默认为 50 条
-func GetRecentGameRecords(days int, limit int) ([]*GameRound, error) {
+func GetGameRecords(days int, limit int) ([]*GameRound, error) {
 	if days <= 0 {
 		days = 7
 	}
@@ -167,87 +202,69 @@ func GetRecentGameRecords(days int, limit int) ([]*GameRound, error) {
 		limit = 50
 	}
 
-	log.Printf("[记录] 开始获取最近 %d 天的记录，最多 %d 条", days, limit)
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+	return recordStore.ListRounds(from, now.Add(time.Second), limit)
+}
 
-	// 获取当前工作目录
-	currentDir, err := os.Getwd()
-	if err != nil {
-		log.Printf("[记录] 获取工作目录失败: %v", err)
-		return nil, fmt.Errorf("获取工作目录失败: %v", err)
+// GetGameRecordsByDate 获取日期范围内（包含首尾两天）的所有记录，按开始时间正序排序
+func GetGameRecordsByDate(from, to time.Time) ([]*GameRound, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("结束日期不能早于开始日期")
 	}
-	log.Printf("[记录] 当前工作目录: %s", currentDir)
 
-	var allRecords []*GameRound
-	now := time.Now()
+	records, err := recordStore.ListRounds(from, to.AddDate(0, 0, 1), 0)
+	if err != nil {
+		return nil, err
+	}
 
-	// 遍历最近几天的记录
-	for i := 0; i < days; i++ {
-		date := now.AddDate(0, 0, -i)
-		dateDir := filepath.Join(currentDir, recordDir, date.Format("2006-01-02"))
-		log.Printf("[记录] 检查日期目录: %s", dateDir)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartTime < records[j].StartTime
+	})
+	return records, nil
+}
 
-		// 如果目录不存在，继续下一天
-		if _, err := os.Stat(dateDir); os.IsNotExist(err) {
-			log.Printf("[记录] 目录不存在: %s", dateDir)
-			continue
+// BoardReached 返回牌局实际进行到的公共牌数量
+// 其他玩家都弃牌时引擎仍会发完公共牌，这时只算最后一个行动所在阶段的公共牌
+func (r *GameRound) BoardReached() int {
+	contenders := 0
+	for _, player := range r.Players {
+		if player.Status != PlayerStatusFolded && player.Status != PlayerStatusSittingOut {
+			contenders++
 		}
+	}
+	if contenders > 1 {
+		return len(r.CommunityCards)
+	}
 
-		// 读取目录下的所有文件
-		files, err := os.ReadDir(dateDir)
-		if err != nil {
-			log.Printf("[警告] 读取记录目录失败: %v", err)
-			continue
+	street := GamePhasePreFlop
+	for _, action := range r.Actions {
+		if action.Type != ActionUncalled {
+			street = action.Street
 		}
-		log.Printf("[记录] 找到 %d 个文件", len(files))
-
-		// 处理每个记录文件
-		for _, file := range files {
-			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
-				continue
-			}
-			filePath := filepath.Join(dateDir, file.Name())
-			log.Printf("[记录] 处理文件: %s", filePath)
-
-			// 读取文件内容
-			data, err := os.ReadFile(filePath)
-			if err != nil {
-				log.Printf("[警告] 读取记录文件失败: %v", err)
-				continue
-			}
-
-			// 解析JSON
-			var record GameRound
-			if err := json.Unmarshal(data, &record); err != nil {
-				log.Printf("[警告] 解析记录文件失败: %v", err)
-				continue
-			}
-
-			allRecords = append(allRecords, &record)
-			log.Printf("[记录] 成功读取记录: %s", record.RoundID)
+	}
+	cards := map[string]int{GamePhaseFlop: 3, GamePhaseTurn: 4, GamePhaseRiver: 5}[street]
+	return min(cards, len(r.CommunityCards))
+}
 
-			// 如果已经达到限制数量，提前返回
-			if len(allRecords) >= limit {
-				log.Printf("[记录] 已达到限制数量 %d，提前返回", limit)
-				return allRecords[:limit], nil
-			}
+// VisibleTo 判断对局记录能否对用户公开
+// 私人牌桌的对局只对参与的玩家可见，对局ID中的牌桌ID也不会泄露给其他人
+func (r *GameRound) VisibleTo(userID string) bool {
+	if !r.Private {
+		return true
+	}
+	for _, player := range r.Players {
+		if userID != "" && player.UserId == userID {
+			return true
 		}
 	}
-
-	log.Printf("[记录] 总共读取到 %d 条记录", len(allRecords))
-	return allRecords, nil
+	return false
 }
 
-// GetGameRecords 获取历史记录，按时间倒序排序
-func GetGameRecords(days int, limit int) ([]*GameRound, error) {
-	records, err := GetRecentGameRecords(days, limit)
-	if err != nil {
-		return nil, err
+// FindGameRecord 根据对局ID查找对局记录
+func FindGameRecord(roundID string) (*GameRound, error) {
+	if roundID == "" || strings.ContainsAny(roundID, `/\.`) {
+		return nil, fmt.Errorf("无效的对局ID: %s", roundID)
 	}
-
-	// 按时间倒序排序
-	sort.Slice(records, func(i, j int) bool {
-		return records[i].StartTime > records[j].StartTime
-	})
-
-	return records, nil
+	return recordStore.GetRound(roundID)
 }
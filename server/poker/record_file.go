@@ -0,0 +1,388 @@
+package poker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 对局索引文件名，每行一条 "对局ID<TAB>记录文件相对路径"，只追加不改写
+const indexFileName = "index.log"
+
+// 聊天消息目录，每个牌桌一个文件：<dir>/chat/<牌桌ID>.jsonl，每行一条消息，只追加不改写
+const chatDirName = "chat"
+
+// defaultRecordTableID 没有牌桌ID的游戏使用的牌桌ID
+const defaultRecordTableID = "table"
+
+// FileRecordStore 以 JSON 文件保存对局记录
+// 每局一个文件，按开局日期分目录：<dir>/<日期>/<对局ID>.json，
+// 索引文件记录对局ID到文件的映射，同时用来恢复每个牌桌的最大手牌编号
+type FileRecordStore struct {
+	dir string
+
+	mu     sync.Mutex
+	loaded bool
+	paths  map[string]string // 对局ID -> 相对 dir 的文件路径
+	hands  map[string]int    // 牌桌ID -> 已分配的最大手牌编号
+}
+
+// NewFileRecordStore 创建以 dir 为根目录的文件存储，索引在第一次使用时加载
+func NewFileRecordStore(dir string) *FileRecordStore {
+	return &FileRecordStore{dir: dir}
+}
+
+// FormatRoundID 由牌桌ID和手牌编号生成对局ID，编号补零使同一牌桌的ID按字典序即按时间排序
+func FormatRoundID(tableID string, handNumber int) string {
+	return fmt.Sprintf("%s-%08d", tableID, handNumber)
+}
+
+// ParseRoundID 解析对局ID中的牌桌ID和手牌编号，旧格式的ID返回 false
+func ParseRoundID(roundID string) (string, int, bool) {
+	i := strings.LastIndex(roundID, "-")
+	if i <= 0 {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(roundID[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return roundID[:i], number, true
+}
+
+// NextRoundID 为牌桌分配下一局的对局ID
+// 编号在重启后从索引中已有的最大编号继续递增，因此不会与已保存的记录重复
+func (s *FileRecordStore) NextRoundID(tableID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+
+	s.hands[tableID]++
+	return FormatRoundID(tableID, s.hands[tableID]), nil
+}
+
+// SaveRound 写入临时文件后重命名，再追加到索引
+func (s *FileRecordStore) SaveRound(record *GameRound) error {
+	rel := filepath.ToSlash(filepath.Join(time.Unix(record.StartTime, 0).Format("2006-01-02"), record.RoundID+".json"))
+
+	// 将记录转换为JSON
+	data, err := json.MarshalIndent(NewStoredRound(record), "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化对局记录失败: %v", err)
+	}
+
+	// 写入临时文件后重命名，避免留下不完整的记录
+	if err := WriteFileAtomic(filepath.Join(s.dir, rel), data, 0644); err != nil {
+		return fmt.Errorf("写入对局记录失败: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+
+	file, err := os.OpenFile(filepath.Join(s.dir, indexFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开对局索引失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s\t%s\n", record.RoundID, rel); err != nil {
+		return fmt.Errorf("写入对局索引失败: %v", err)
+	}
+	s.put(record.RoundID, rel)
+	return nil
+}
+
+// GetRound 通过索引找到记录文件并读取
+func (s *FileRecordStore) GetRound(roundID string) (*GameRound, error) {
+	s.mu.Lock()
+	s.load()
+	rel, ok := s.paths[roundID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("对局记录不存在: %s", roundID)
+	}
+
+	return readRecordFile(filepath.Join(s.dir, rel))
+}
+
+// ListRounds 读取时间范围内每个日期目录下的所有记录
+func (s *FileRecordStore) ListRounds(from, to time.Time, limit int) ([]*GameRound, error) {
+	return s.listRounds(from, to, limit, func(*GameRound) bool { return true })
+}
+
+// ListUserRounds 读取时间范围内的所有记录，筛选出用户参与的
+func (s *FileRecordStore) ListUserRounds(userID string, from, to time.Time, limit int) ([]*GameRound, error) {
+	return s.listRounds(from, to, limit, func(record *GameRound) bool {
+		for _, player := range record.Players {
+			if player.UserId == userID {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// listRounds 按日期目录读取记录，只保留开始时间在 [from, to) 内且满足 match 的
+func (s *FileRecordStore) listRounds(from, to time.Time, limit int, match func(*GameRound) bool) ([]*GameRound, error) {
+	records := make([]*GameRound, 0)
+
+	dateDirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, fmt.Errorf("读取记录目录失败: %v", err)
+	}
+
+	// 只读取时间范围内的日期目录，目录名按字典序即时间顺序
+	first := from.Format("2006-01-02")
+	last := to.Format("2006-01-02")
+	for _, entry := range dateDirs {
+		if !entry.IsDir() || entry.Name() < first || entry.Name() > last {
+			continue
+		}
+
+		dateDir := filepath.Join(s.dir, entry.Name())
+		files, err := os.ReadDir(dateDir)
+		if err != nil {
+			log.Printf("[警告] 读取记录目录失败: %v", err)
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+
+			record, err := readRecordFile(filepath.Join(dateDir, file.Name()))
+			if err != nil {
+				log.Printf("[警告] %v", err)
+				continue
+			}
+			if record.StartTime < from.Unix() || record.StartTime >= to.Unix() || !match(record) {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartTime > records[j].StartTime
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// SaveChat 把消息追加到牌桌的聊天文件
+func (s *FileRecordStore) SaveChat(message *ChatMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("序列化聊天消息失败: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, chatDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建聊天目录失败: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, message.TableID+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开聊天文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入聊天消息失败: %v", err)
+	}
+	return nil
+}
+
+// ListChat 从对局所在牌桌的聊天文件中筛选出该对局的消息
+func (s *FileRecordStore) ListChat(roundID string) ([]*ChatMessage, error) {
+	messages := make([]*ChatMessage, 0)
+	tableID, _, ok := ParseRoundID(roundID)
+	if !ok {
+		return messages, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(filepath.Join(s.dir, chatDirName, tableID+".jsonl"))
+	if os.IsNotExist(err) {
+		return messages, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开聊天文件失败: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var message ChatMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			log.Printf("[警告] 解析聊天消息失败: %v", err)
+			continue
+		}
+		if message.RoundID == roundID {
+			messages = append(messages, &message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取聊天文件失败: %v", err)
+	}
+	return messages, nil
+}
+
+// readRecordFile 读取并解析一个记录文件
+func readRecordFile(path string) (*GameRound, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取记录文件失败: %v", err)
+	}
+
+	stored := StoredRound{GameRound: &GameRound{}}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("解析记录文件失败: %s, %v", path, err)
+	}
+	return stored.Round(), nil
+}
+
+// put 更新内存中的索引，调用者需持有锁
+func (s *FileRecordStore) put(roundID string, path string) {
+	s.paths[roundID] = path
+	if tableID, number, ok := ParseRoundID(roundID); ok && number > s.hands[tableID] {
+		s.hands[tableID] = number
+	}
+}
+
+// load 第一次使用时读取索引文件，索引文件不存在时扫描记录目录重建，调用者需持有锁
+func (s *FileRecordStore) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.paths = make(map[string]string)
+	s.hands = make(map[string]int)
+
+	file, err := os.Open(filepath.Join(s.dir, indexFileName))
+	if os.IsNotExist(err) {
+		s.rebuild()
+		return
+	}
+	if err != nil {
+		log.Printf("[警告] 读取对局索引失败: %v", err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		roundID, path, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		s.put(roundID, path)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("[警告] 读取对局索引失败: %v", err)
+	}
+	log.Printf("[记录] 已加载对局索引，共 %d 条记录", len(s.paths))
+}
+
+// rebuild 扫描所有记录文件重建索引，旧格式的对局ID在不同日期会重复，保留最新的一条
+func (s *FileRecordStore) rebuild() {
+	dateDirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[警告] 读取记录目录失败: %v", err)
+		}
+		return
+	}
+
+	var lines strings.Builder
+	for _, dateDir := range dateDirs {
+		if !dateDir.IsDir() {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(s.dir, dateDir.Name()))
+		if err != nil {
+			log.Printf("[警告] 读取记录目录失败: %v", err)
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+
+			path := dateDir.Name() + "/" + file.Name()
+			data, err := os.ReadFile(filepath.Join(s.dir, path))
+			if err != nil {
+				log.Printf("[警告] 读取记录文件失败: %v", err)
+				continue
+			}
+
+			var record struct {
+				RoundID string `json:"roundId"`
+			}
+			if err := json.Unmarshal(data, &record); err != nil || record.RoundID == "" {
+				log.Printf("[警告] 解析记录文件失败: %s", path)
+				continue
+			}
+
+			s.put(record.RoundID, path)
+			fmt.Fprintf(&lines, "%s\t%s\n", record.RoundID, path)
+		}
+	}
+
+	if err := WriteFileAtomic(filepath.Join(s.dir, indexFileName), []byte(lines.String()), 0644); err != nil {
+		log.Printf("[警告] 保存对局索引失败: %v", err)
+		return
+	}
+	log.Printf("[记录] 已重建对局索引，共 %d 条记录", len(s.paths))
+}
+
+// WriteFileAtomic 先写入同目录下的临时文件再重命名，避免进程中断时留下不完整的文件
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,173 @@
+package poker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRoundID(t *testing.T) {
+	tests := []struct {
+		roundID string
+		table   string
+		number  int
+		ok      bool
+	}{
+		{roundID: FormatRoundID("table", 1), table: "table", number: 1, ok: true},
+		{roundID: "a1b2-c3-00000012", table: "a1b2-c3", number: 12, ok: true},
+		{roundID: "1700000000123"},
+		{roundID: "table-x"},
+		{roundID: "-00000001"},
+	}
+
+	if got := FormatRoundID("table", 1); got != "table-00000001" {
+		t.Errorf("FormatRoundID = %s, 期望 table-00000001", got)
+	}
+	for _, tt := range tests {
+		t.Run(tt.roundID, func(t *testing.T) {
+			table, number, ok := ParseRoundID(tt.roundID)
+			if table != tt.table || number != tt.number || ok != tt.ok {
+				t.Errorf("ParseRoundID = %q, %d, %v, 期望 %q, %d, %v", table, number, ok, tt.table, tt.number, tt.ok)
+			}
+		})
+	}
+}
+
+// saveStoreRound 在存储中保存一局，开始时间为 day 当天
+func saveStoreRound(t *testing.T, store RecordStore, roundID string, day time.Time, userIDs ...string) {
+	t.Helper()
+	record := &GameRound{RoundID: roundID, StartTime: day.Unix(), Deck: make([]Card, 52)}
+	for i, userID := range userIDs {
+		record.Players = append(record.Players, PlayerRoundInfo{UserId: userID, Position: i})
+	}
+	if err := store.SaveRound(record); err != nil {
+		t.Fatalf("保存对局记录失败: %v", err)
+	}
+}
+
+func TestFileRecordStoreRoundIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		reopen func(dir string) // 重新打开存储之前对目录的处理
+	}{
+		{name: "从索引恢复编号", reopen: func(string) {}},
+		{name: "索引丢失时扫描记录重建", reopen: func(dir string) { os.Remove(filepath.Join(dir, indexFileName)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := NewFileRecordStore(dir)
+			day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+			// 每个牌桌的编号独立递增
+			var ids []string
+			for _, tableID := range []string{"a", "a", "b", "a"} {
+				id, err := store.NextRoundID(tableID)
+				if err != nil {
+					t.Fatalf("分配对局ID失败: %v", err)
+				}
+				saveStoreRound(t, store, id, day, "u1")
+				ids = append(ids, id)
+			}
+			want := []string{"a-00000001", "a-00000002", "b-00000001", "a-00000003"}
+			if !reflect.DeepEqual(ids, want) {
+				t.Fatalf("对局ID = %v, 期望 %v", ids, want)
+			}
+
+			index, err := os.ReadFile(filepath.Join(dir, indexFileName))
+			if err != nil {
+				t.Fatalf("读取索引失败: %v", err)
+			}
+			if lines := strings.Split(strings.TrimSpace(string(index)), "\n"); len(lines) != 4 ||
+				lines[0] != "a-00000001\t2024-05-01/a-00000001.json" {
+				t.Fatalf("索引内容 = %q", index)
+			}
+
+			// 重启后编号从已保存的最大编号继续，记录仍然可以按ID读取
+			tt.reopen(dir)
+			store = NewFileRecordStore(dir)
+			if id, _ := store.NextRoundID("a"); id != "a-00000004" {
+				t.Errorf("重启后的对局ID = %s, 期望 a-00000004", id)
+			}
+			if id, _ := store.NextRoundID("c"); id != "c-00000001" {
+				t.Errorf("新牌桌的对局ID = %s, 期望 c-00000001", id)
+			}
+			record, err := store.GetRound("b-00000001")
+			if err != nil {
+				t.Fatalf("读取对局记录失败: %v", err)
+			}
+			if len(record.Deck) != 52 {
+				t.Errorf("读出的牌堆有 %d 张，期望 52", len(record.Deck))
+			}
+		})
+	}
+}
+
+func TestFileRecordStoreListUserRounds(t *testing.T) {
+	store := NewFileRecordStore(t.TempDir())
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	saveStoreRound(t, store, "t-00000001", day, "u1", "u2")
+	saveStoreRound(t, store, "t-00000002", day.Add(time.Hour), "u2")
+	saveStoreRound(t, store, "t-00000003", day.AddDate(0, 0, 1), "u1")
+
+	tests := []struct {
+		name     string
+		userID   string
+		from, to time.Time
+		want     []string
+	}{
+		{name: "全部日期按时间倒序", userID: "u1", from: day.AddDate(0, 0, -1), to: day.AddDate(0, 0, 2), want: []string{"t-00000003", "t-00000001"}},
+		{name: "只包含时间范围内的", userID: "u1", from: day, to: day.AddDate(0, 0, 1), want: []string{"t-00000001"}},
+		{name: "其他玩家", userID: "u2", from: day, to: day.AddDate(0, 0, 2), want: []string{"t-00000002", "t-00000001"}},
+		{name: "没有参与的玩家", userID: "u3", from: day, to: day.AddDate(0, 0, 2), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.ListUserRounds(tt.userID, tt.from, tt.to, 0)
+			if err != nil {
+				t.Fatalf("读取记录失败: %v", err)
+			}
+			got := make([]string, 0, len(records))
+			for _, record := range records {
+				got = append(got, record.RoundID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("记录 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "data.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("文件内容 = %q (%v), 期望 %q", data, err, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("读取文件信息失败: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("文件权限 = %v, 期望 0600", info.Mode().Perm())
+	}
+
+	// 不留下临时文件
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("目录中有 %d 个文件，期望只有 1 个", len(entries))
+	}
+}
@@ -0,0 +1,252 @@
+package poker
+
+import (
+	"fmt"
+	"log"
+)
+
+// ReplayPlayer 回放中某一步的玩家状态
+type ReplayPlayer struct {
+	Position   int    `json:"position"`           // 座位位置
+	UserId     string `json:"userId"`             // 用户ID
+	Name       string `json:"name"`               // 玩家名称
+	Chips      int    `json:"chips"`              // 剩余筹码
+	CurrentBet int    `json:"currentBet"`         // 本轮下注
+	TotalBet   int    `json:"totalBet"`           // 本局总下注
+	Status     string `json:"status"`             // 玩家状态
+	HoleCards  []Card `json:"holeCards"`          // 手牌，按可见规则不可见时为空
+	HandRank   string `json:"handRank,omitempty"` // 摊牌后的牌型
+}
+
+// ReplayStep 回放中的一步，即某次行动或摊牌之后的牌桌状态
+type ReplayStep struct {
+	Index          int            `json:"index"`            // 步骤序号
+	Action         *Action        `json:"action,omitempty"` // 产生该状态的行动，初始状态和摊牌步骤为空
+	Phase          string         `json:"phase"`            // 游戏阶段
+	Pot            int            `json:"pot"`              // 底池
+	Pots           []Pot          `json:"pots"`             // 主池和边池明细
+	CurrentBet     int            `json:"currentBet"`       // 当前下注额
+	CurrentPlayer  int            `json:"currentPlayer"`    // 下一个行动的玩家
+	CommunityCards []Card         `json:"communityCards"`   // 公共牌
+	Players        []ReplayPlayer `json:"players"`          // 已落座的玩家
+}
+
+// Replay 一局游戏的完整回放
+type Replay struct {
+	RoundID       string       `json:"roundId"`       // 对局ID
+	DealerPos     int          `json:"dealerPos"`     // 庄家位置
+	SmallBlindPos int          `json:"smallBlindPos"` // 小盲位置
+	BigBlindPos   int          `json:"bigBlindPos"`   // 大盲位置
+	Steps         []ReplayStep `json:"steps"`         // 按顺序排列的每一步
+}
+
+// replayer 回放过程中的状态
+type replayer struct {
+	game     *Game
+	viewerID string
+	revealed map[int]bool // 已经摊牌的座位
+	replay   *Replay
+}
+
+// ReplayRound 使用游戏引擎按记录的牌堆和行动重新进行一局，返回每一步的牌桌状态
+// viewerID 对应的玩家始终能看到自己的手牌，其他玩家的手牌只有摊牌后才可见。
+// 回放结果与记录不一致时返回错误，因此回放同时也是对记录的一致性检查
+func ReplayRound(record *GameRound, viewerID string) (*Replay, error) {
+	if len(record.Deck) != 52 {
+		return nil, fmt.Errorf("对局记录没有保存牌堆，无法回放")
+	}
+
+	g, err := newReplayGame(record)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replayer{
+		game:     g,
+		viewerID: viewerID,
+		revealed: make(map[int]bool),
+		replay: &Replay{
+			RoundID:       record.RoundID,
+			DealerPos:     record.DealerPos,
+			SmallBlindPos: record.SmallBlindPos,
+			BigBlindPos:   record.BigBlindPos,
+			Steps:         make([]ReplayStep, 0, len(record.Actions)+1),
+		},
+	}
+
+	// 按记录的牌堆和位置发牌、下盲注
+	g.resetHand()
+	g.RoundID = record.RoundID
+	g.Deck = append([]Card(nil), record.Deck...)
+	g.HandDeck = append([]Card(nil), record.Deck...)
+	g.DealerPos = record.DealerPos
+	g.SmallBlindPos = record.SmallBlindPos
+	g.BigBlindPos = record.BigBlindPos
+	g.dealHand()
+	r.snapshot(nil)
+
+	// 依次执行玩家行动，前注、盲注和退还由引擎自己产生
+	for i, action := range record.Actions {
+		switch action.Type {
+		case ActionAnte, ActionSmallBlind, ActionBigBlind, ActionMissedBlind, ActionUncalled:
+			continue
+		}
+
+		if g.GameStatus != GameStatusPlaying || g.CurrentPlayer != action.Position {
+			return nil, fmt.Errorf("第%d个行动不一致：记录为座位%d行动，引擎当前行动座位为%d",
+				i+1, action.Position+1, g.CurrentPlayer+1)
+		}
+
+		name, amount := replayActionRequest(action)
+		before := len(g.Actions)
+		if !g.playerAction(action.UserId, name, amount, action.Auto) {
+			return nil, fmt.Errorf("第%d个行动无法执行：座位%d %s %d", i+1, action.Position+1, action.Type, amount)
+		}
+		r.snapshot(&g.Actions[before])
+	}
+
+	// 逐个摊牌
+	order := append([]int(nil), g.ShowdownOrder...)
+	for shown := 0; g.GamePhase == GamePhaseShowdownReveal; shown++ {
+		g.AdvanceShowdown()
+		if shown < len(order) {
+			r.revealed[order[shown]] = true
+		}
+		r.snapshot(nil)
+	}
+
+	if g.GameStatus == GameStatusPlaying {
+		return nil, fmt.Errorf("记录的行动不完整，牌局没有结束")
+	}
+	if err := r.verify(record); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[回放] 对局 %s 回放完成，共 %d 步", record.RoundID, len(r.replay.Steps))
+	return r.replay, nil
+}
+
+// newReplayGame 按对局记录的牌桌设置创建游戏并让玩家按初始筹码落座
+func newReplayGame(record *GameRound) (*Game, error) {
+	config := DefaultTableConfig()
+	config.Name = "回放"
+	config.Seats = record.Seats
+	config.SmallBlind = record.SmallBlind
+	config.BigBlind = record.BigBlind
+	config.Ante = record.Ante
+	if record.Betting != "" {
+		config.BettingStructure = record.Betting
+	}
+
+	config.MinBuyIn = record.BigBlind
+	config.MaxBuyIn = record.BigBlind
+	for _, player := range record.Players {
+		config.MaxBuyIn = max(config.MaxBuyIn, player.InitChips)
+	}
+
+	g, err := NewGame(config)
+	if err != nil {
+		return nil, fmt.Errorf("对局记录的牌桌设置无效: %v", err)
+	}
+	g.replay = true
+
+	for _, player := range record.Players {
+		if player.Position < 0 || player.Position >= len(g.Players) {
+			return nil, fmt.Errorf("对局记录的座位无效: %d", player.Position)
+		}
+		g.Players[player.Position].SitDown(player.UserId, player.Name, player.InitChips, 0)
+		g.Players[player.Position].SittingOut = player.Status == PlayerStatusSittingOut
+	}
+
+	// 补交的盲注由引擎按玩家欠下的盲注产生
+	for _, action := range record.Actions {
+		if action.Type == ActionMissedBlind && action.Position >= 0 && action.Position < len(g.Players) {
+			g.Players[action.Position].MissedBlinds = action.Amount
+		}
+	}
+	return g, nil
+}
+
+// replayActionRequest 把记录的行动转换为引擎的行动请求
+func replayActionRequest(action Action) (string, int) {
+	switch action.Type {
+	case ActionBet, ActionRaise:
+		if action.AllIn {
+			return "allin", 0
+		}
+		return "raise", action.RaiseTo
+	default:
+		return action.Type, 0
+	}
+}
+
+// snapshot 记录当前牌桌状态为回放的一步
+func (r *replayer) snapshot(action *Action) {
+	g := r.game
+	step := ReplayStep{
+		Index:          len(r.replay.Steps),
+		Phase:          g.GamePhase,
+		Pot:            g.Pot,
+		Pots:           append([]Pot(nil), g.Pots...),
+		CurrentBet:     g.CurrentBet,
+		CurrentPlayer:  g.CurrentPlayer,
+		CommunityCards: append([]Card(nil), g.CommunityCards...),
+		Players:        make([]ReplayPlayer, 0),
+	}
+	if action != nil {
+		copied := *action
+		step.Action = &copied
+	}
+
+	for i, player := range g.Players {
+		if player.IsEmpty() {
+			continue
+		}
+
+		info := ReplayPlayer{
+			Position:   i,
+			UserId:     player.UserId,
+			Name:       player.Name,
+			Chips:      player.Chips,
+			CurrentBet: player.CurrentBet,
+			TotalBet:   player.TotalBet,
+			Status:     player.Status,
+			HoleCards:  make([]Card, 0),
+		}
+		if player.UserId == r.viewerID || r.revealed[i] {
+			info.HoleCards = append(info.HoleCards, player.HoleCards...)
+		}
+		if r.revealed[i] && player.HandRank != nil {
+			info.HandRank = GetHandRankName(HandRankType(player.HandRank.Rank))
+		}
+		step.Players = append(step.Players, info)
+	}
+
+	r.replay.Steps = append(r.replay.Steps, step)
+}
+
+// verify 检查回放产生的行动和最终筹码是否与记录一致
+func (r *replayer) verify(record *GameRound) error {
+	g := r.game
+	if len(g.Actions) != len(record.Actions) {
+		return fmt.Errorf("回放产生了%d个行动，记录中有%d个", len(g.Actions), len(record.Actions))
+	}
+
+	for i, expected := range record.Actions {
+		actual := g.Actions[i]
+		if actual.Street != expected.Street || actual.Position != expected.Position ||
+			actual.Type != expected.Type || actual.Amount != expected.Amount ||
+			actual.RaiseTo != expected.RaiseTo || actual.Pot != expected.Pot {
+			return fmt.Errorf("第%d个行动不一致：记录为 %s 座位%d %s %d（底池%d），回放为 %s 座位%d %s %d（底池%d）",
+				i+1, expected.Street, expected.Position+1, expected.Type, expected.Amount, expected.Pot,
+				actual.Street, actual.Position+1, actual.Type, actual.Amount, actual.Pot)
+		}
+	}
+
+	for _, player := range record.Players {
+		if chips := g.Players[player.Position].Chips; chips != player.FinalChips {
+			return fmt.Errorf("玩家 %s 最终筹码不一致：记录为%d，回放为%d", player.Name, player.FinalChips, chips)
+		}
+	}
+	return nil
+}
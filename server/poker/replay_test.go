@@ -0,0 +1,146 @@
+package poker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// playRecordedHand 三个玩家打完一局到摊牌，返回对局记录
+// 座位0加注，座位1弃牌，座位2跟注后一路过牌到摊牌，座位0的 AA 赢下底池
+func playRecordedHand(t *testing.T) *GameRound {
+	t.Helper()
+	g := newTestGame(t, 10, 20, 500, 500, 500)
+	g.RoundID = "table-00000001"
+	startTestHand(t, g, testDeck(t, []string{"AsAh", "KsKh", "QsQh"}, "2c 7d 9h 3s 4c"))
+	playActions(t, g, []testAction{
+		{0, "raise", 60}, {1, "fold", 0}, {2, "call", 0},
+		{2, "check", 0}, {0, "check", 0},
+		{2, "check", 0}, {0, "check", 0},
+		{2, "check", 0}, {0, "check", 0},
+	})
+	if g.CurrentRound == nil {
+		t.Fatalf("牌局没有结束，阶段: %s", g.GamePhase)
+	}
+	return g.CurrentRound
+}
+
+func TestReplayRound(t *testing.T) {
+	record := playRecordedHand(t)
+	replay, err := ReplayRound(record, "u2")
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+
+	// 初始状态、9个行动、两名玩家依次亮牌和结算
+	if len(replay.Steps) != 13 {
+		t.Fatalf("回放步数 = %d, 期望 13", len(replay.Steps))
+	}
+	first, last := replay.Steps[0], replay.Steps[len(replay.Steps)-1]
+	if first.Pot != 30 || first.CurrentPlayer != 0 {
+		t.Errorf("初始状态 底池 %d，行动座位 %d，期望 30、0", first.Pot, first.CurrentPlayer)
+	}
+
+	chips := make(map[int]int)
+	for _, player := range last.Players {
+		chips[player.Position] = player.Chips
+	}
+	if chips[0] != 570 || chips[1] != 490 || chips[2] != 440 {
+		t.Errorf("最终筹码 = %v, 期望 map[0:570 1:490 2:440]", chips)
+	}
+
+	// 观看者始终能看到自己的手牌，其他玩家摊牌后才可见，弃牌的玩家一直不可见
+	for _, player := range first.Players {
+		if visible := len(player.HoleCards) > 0; visible != (player.UserId == "u2") {
+			t.Errorf("初始状态 座位%d 手牌可见 = %v", player.Position, visible)
+		}
+	}
+	for _, player := range last.Players {
+		if visible := len(player.HoleCards) > 0; visible != (player.Position != 1) {
+			t.Errorf("摊牌后 座位%d 手牌可见 = %v", player.Position, visible)
+		}
+	}
+}
+
+func TestReplayRoundMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(record *GameRound)
+		want   string
+	}{
+		{
+			name:   "没有保存牌堆",
+			tamper: func(record *GameRound) { record.Deck = nil },
+			want:   "没有保存牌堆",
+		},
+		{
+			name: "行动的座位不一致",
+			tamper: func(record *GameRound) {
+				for i := range record.Actions {
+					if record.Actions[i].Type == ActionFold {
+						record.Actions[i].Position = 2
+					}
+				}
+			},
+			want: "行动不一致",
+		},
+		{
+			name: "加注金额不一致",
+			tamper: func(record *GameRound) {
+				for i := range record.Actions {
+					if record.Actions[i].Type == ActionRaise {
+						record.Actions[i].Amount = 50
+					}
+				}
+			},
+			want: "行动不一致",
+		},
+		{
+			name:   "最终筹码不一致",
+			tamper: func(record *GameRound) { record.Players[0].FinalChips += 10 },
+			want:   "最终筹码不一致",
+		},
+		{
+			name:   "行动不完整",
+			tamper: func(record *GameRound) { record.Actions = record.Actions[:len(record.Actions)-1] },
+			want:   "没有结束",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := playRecordedHand(t)
+			tt.tamper(record)
+			_, err := ReplayRound(record, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordDeckStoredOnly(t *testing.T) {
+	record := playRecordedHand(t)
+
+	// 发给客户端的记录不包含牌堆
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	if strings.Contains(string(data), `"deck"`) {
+		t.Errorf("对局记录的 JSON 包含牌堆")
+	}
+
+	// 存储中的记录保存牌堆，读出后可以回放
+	data, err = json.Marshal(NewStoredRound(record))
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	var stored StoredRound
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if _, err := ReplayRound(stored.Round(), ""); err != nil {
+		t.Errorf("读出的记录回放失败: %v", err)
+	}
+}
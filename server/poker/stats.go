@@ -0,0 +1,189 @@
+package poker
+
+import "math"
+
+// PlayerStats 玩家的统计数据，由对局记录逐局累计得到
+// 比率都是百分比，AF 是翻后下注加注次数与跟注次数之比，BBPer100 是每百手赢得的大盲数
+type PlayerStats struct {
+	UserID string `json:"userId"` // 用户ID
+
+	// 计数
+	Hands        int     `json:"hands"`        // 参与的手数
+	VPIPHands    int     `json:"vpipHands"`    // 翻前主动入池的手数
+	PFRHands     int     `json:"pfrHands"`     // 翻前加注的手数
+	ThreeBetOpps int     `json:"threeBetOpps"` // 面对一次加注、有机会再加注的手数
+	ThreeBets    int     `json:"threeBets"`    // 翻前再加注的手数
+	PostflopAggr int     `json:"postflopAggr"` // 翻后下注和加注的次数
+	PostflopCall int     `json:"postflopCall"` // 翻后跟注的次数
+	SawFlop      int     `json:"sawFlop"`      // 看到翻牌的手数
+	Showdowns    int     `json:"showdowns"`    // 摊牌的手数
+	ShowdownsWon int     `json:"showdownsWon"` // 摊牌获胜的手数
+	HandsWon     int     `json:"handsWon"`     // 赢得底池的手数
+	ChipsWon     int     `json:"chipsWon"`     // 筹码净输赢
+	BigBlindsWon float64 `json:"bigBlindsWon"` // 以大盲计的净输赢
+
+	// 比率
+	VPIP     float64 `json:"vpip"`     // 主动入池率
+	PFR      float64 `json:"pfr"`      // 翻前加注率
+	AF       float64 `json:"af"`       // 激进度
+	ThreeBet float64 `json:"threeBet"` // 3-bet 率
+	WTSD     float64 `json:"wtsd"`     // 看到翻牌后摊牌的比例
+	WSD      float64 `json:"wsd"`      // 摊牌胜率
+	WinRate  float64 `json:"winRate"`  // 赢得底池的比例
+	BBPer100 float64 `json:"bbPer100"` // 每百手赢得的大盲数
+}
+
+// SeatStats 牌桌上随座位一起广播的关键统计数据
+type SeatStats struct {
+	Hands    int     `json:"hands"`    // 手数
+	VPIP     float64 `json:"vpip"`     // 主动入池率
+	PFR      float64 `json:"pfr"`      // 翻前加注率
+	AF       float64 `json:"af"`       // 激进度
+	ThreeBet float64 `json:"threeBet"` // 3-bet 率
+	BBPer100 float64 `json:"bbPer100"` // 每百手赢得的大盲数
+}
+
+// ComputePlayerStats 根据对局记录计算玩家的统计数据
+func ComputePlayerStats(userID string, records []*GameRound) *PlayerStats {
+	stats := &PlayerStats{UserID: userID}
+	for _, record := range records {
+		stats.Add(record)
+	}
+	return stats
+}
+
+// Add 把一局记录计入统计，玩家没有参与、暂时离开或记录中没有行动（旧版本的记录）时忽略
+func (s *PlayerStats) Add(record *GameRound) {
+	if len(record.Actions) == 0 || record.BigBlind <= 0 {
+		return
+	}
+
+	var player *PlayerRoundInfo
+	contenders := 0
+	for i := range record.Players {
+		if record.Players[i].UserId == s.UserID {
+			player = &record.Players[i]
+		}
+		if record.Players[i].Status != PlayerStatusFolded && record.Players[i].Status != PlayerStatusSittingOut {
+			contenders++
+		}
+	}
+	if player == nil || player.Status == PlayerStatusSittingOut {
+		return
+	}
+	pos := player.Position
+
+	s.Hands++
+	s.ChipsWon += player.ChipsChange
+	s.BigBlindsWon += float64(player.ChipsChange) / float64(record.BigBlind)
+
+	// 翻前：大盲注算作第一次下注，第一次主动加注是开局加注，再加注即 3-bet
+	var vpip, pfr, threeBetOpp, threeBet, foldedPreflop bool
+	raises := 0
+	for _, action := range record.Actions {
+		if action.Street != GamePhasePreFlop {
+			if action.Position == pos {
+				switch action.Type {
+				case ActionBet, ActionRaise:
+					s.PostflopAggr++
+				case ActionCall:
+					s.PostflopCall++
+				}
+			}
+			continue
+		}
+
+		if action.Position == pos {
+			switch action.Type {
+			case ActionCall:
+				vpip = true
+			case ActionBet, ActionRaise:
+				vpip, pfr = true, true
+			case ActionFold:
+				foldedPreflop = true
+			}
+			if raises == 1 && !threeBetOpp && action.Type != ActionUncalled {
+				threeBetOpp = true
+				threeBet = action.Type == ActionRaise
+			}
+		}
+		if action.Type == ActionBet || action.Type == ActionRaise {
+			raises++
+		}
+	}
+
+	if vpip {
+		s.VPIPHands++
+	}
+	if pfr {
+		s.PFRHands++
+	}
+	if threeBetOpp {
+		s.ThreeBetOpps++
+	}
+	if threeBet {
+		s.ThreeBets++
+	}
+	// 其他玩家都弃牌时引擎仍会发完公共牌，按实际进行到的阶段判断
+	if !foldedPreflop && record.BoardReached() >= 3 {
+		s.SawFlop++
+	}
+
+	won := false
+	for _, winner := range record.Winners {
+		if winner.UserId == s.UserID && winner.WinAmount > 0 {
+			won = true
+		}
+	}
+	if won {
+		s.HandsWon++
+	}
+	if player.Status != PlayerStatusFolded && contenders > 1 {
+		s.Showdowns++
+		if won {
+			s.ShowdownsWon++
+		}
+	}
+
+	s.updateRates()
+}
+
+// Summary 返回随座位广播的关键数据
+func (s *PlayerStats) Summary() *SeatStats {
+	return &SeatStats{
+		Hands:    s.Hands,
+		VPIP:     s.VPIP,
+		PFR:      s.PFR,
+		AF:       s.AF,
+		ThreeBet: s.ThreeBet,
+		BBPer100: s.BBPer100,
+	}
+}
+
+// updateRates 根据计数重新计算比率
+func (s *PlayerStats) updateRates() {
+	s.VPIP = percent(s.VPIPHands, s.Hands)
+	s.PFR = percent(s.PFRHands, s.Hands)
+	s.ThreeBet = percent(s.ThreeBets, s.ThreeBetOpps)
+	s.WTSD = percent(s.Showdowns, s.SawFlop)
+	s.WSD = percent(s.ShowdownsWon, s.Showdowns)
+	s.WinRate = percent(s.HandsWon, s.Hands)
+
+	// 没有跟注时激进度按跟注一次计算，避免除以零
+	s.AF = round1(float64(s.PostflopAggr) / float64(max(s.PostflopCall, 1)))
+	if s.Hands > 0 {
+		s.BBPer100 = round1(s.BigBlindsWon / float64(s.Hands) * 100)
+	}
+}
+
+// percent 计算百分比，保留一位小数
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round1(float64(n) / float64(total) * 100)
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,130 @@
+package poker
+
+import "testing"
+
+// statCounts 统计数据中的计数部分
+type statCounts struct {
+	VPIP, PFR, ThreeBetOpps, ThreeBets int
+	PostflopAggr, PostflopCall         int
+	SawFlop, Showdowns, HandsWon       int
+}
+
+func countsOf(s *PlayerStats) statCounts {
+	return statCounts{
+		VPIP:         s.VPIPHands,
+		PFR:          s.PFRHands,
+		ThreeBetOpps: s.ThreeBetOpps,
+		ThreeBets:    s.ThreeBets,
+		PostflopAggr: s.PostflopAggr,
+		PostflopCall: s.PostflopCall,
+		SawFlop:      s.SawFlop,
+		Showdowns:    s.Showdowns,
+		HandsWon:     s.HandsWon,
+	}
+}
+
+func TestPlayerStatsCounts(t *testing.T) {
+	// 三个玩家时座位0是庄家并且翻前第一个行动，座位1、2是小盲和大盲
+	tests := []struct {
+		name    string
+		actions []testAction
+		want    map[string]statCounts
+	}{
+		{
+			name: "翻前加注后摊牌",
+			actions: []testAction{
+				{0, "raise", 60}, {1, "fold", 0}, {2, "call", 0},
+				{2, "check", 0}, {0, "check", 0},
+				{2, "check", 0}, {0, "check", 0},
+				{2, "check", 0}, {0, "check", 0},
+			},
+			want: map[string]statCounts{
+				"u0": {VPIP: 1, PFR: 1, SawFlop: 1, Showdowns: 1, HandsWon: 1},
+				"u1": {ThreeBetOpps: 1},
+				"u2": {VPIP: 1, ThreeBetOpps: 1, SawFlop: 1, Showdowns: 1},
+			},
+		},
+		{
+			// 所有人弃牌时引擎仍会发完公共牌，但没有人看到翻牌
+			name: "翻前再加注后其他人弃牌",
+			actions: []testAction{
+				{0, "raise", 60}, {1, "raise", 180}, {2, "fold", 0}, {0, "fold", 0},
+			},
+			want: map[string]statCounts{
+				"u0": {VPIP: 1, PFR: 1},
+				"u1": {VPIP: 1, PFR: 1, ThreeBetOpps: 1, ThreeBets: 1, HandsWon: 1},
+				// 面对的已经是再加注，不算 3-bet 机会
+				"u2": {},
+			},
+		},
+		{
+			name: "翻后下注在转牌结束",
+			actions: []testAction{
+				{0, "call", 0}, {1, "call", 0}, {2, "check", 0},
+				{1, "raise", 40}, {2, "fold", 0}, {0, "call", 0},
+				{1, "check", 0}, {0, "raise", 100}, {1, "fold", 0},
+			},
+			want: map[string]statCounts{
+				"u0": {VPIP: 1, PostflopAggr: 1, PostflopCall: 1, SawFlop: 1, HandsWon: 1},
+				"u1": {VPIP: 1, PostflopAggr: 1, SawFlop: 1},
+				"u2": {SawFlop: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, 10, 20, 500, 500, 500)
+			startTestHand(t, g, testDeck(t, []string{"AsAh", "KsKh", "QsQh"}, "2c 7d 9h 3s 4c"))
+			playActions(t, g, tt.actions)
+			if g.CurrentRound == nil {
+				t.Fatalf("牌局没有结束，阶段: %s", g.GamePhase)
+			}
+
+			for userID, want := range tt.want {
+				stats := ComputePlayerStats(userID, []*GameRound{g.CurrentRound})
+				if stats.Hands != 1 {
+					t.Errorf("%s 手数 = %d, 期望 1", userID, stats.Hands)
+				}
+				if got := countsOf(stats); got != want {
+					t.Errorf("%s 计数 = %+v, 期望 %+v", userID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlayerStatsRates(t *testing.T) {
+	stats := &PlayerStats{
+		Hands:        4,
+		VPIPHands:    3,
+		PFRHands:     1,
+		ThreeBetOpps: 3,
+		ThreeBets:    1,
+		PostflopAggr: 3,
+		SawFlop:      2,
+		Showdowns:    1,
+		ShowdownsWon: 1,
+		BigBlindsWon: -2.5,
+	}
+	stats.updateRates()
+
+	want := []struct {
+		name      string
+		got, want float64
+	}{
+		{"VPIP", stats.VPIP, 75},
+		{"PFR", stats.PFR, 25},
+		{"3-bet", stats.ThreeBet, 33.3},
+		{"WTSD", stats.WTSD, 50},
+		{"WSD", stats.WSD, 100},
+		// 没有跟注时按跟注一次计算
+		{"AF", stats.AF, 3},
+		{"BB/100", stats.BBPer100, -62.5},
+	}
+	for _, tt := range want {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, 期望 %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package poker
+
+import (
+	"log"
+	"time"
+)
+
+// RecordStore 对局记录的存储
+// 默认使用按日期分目录的 JSON 文件，也可以通过 SetRecordStore 换成数据库
+type RecordStore interface {
+	// NextRoundID 为牌桌分配下一局的对局ID
+	NextRoundID(tableID string) (string, error)
+	// SaveRound 保存一局的记录，包括行动记录和每个玩家的筹码变化
+	SaveRound(record *GameRound) error
+	// GetRound 根据对局ID获取记录
+	GetRound(roundID string) (*GameRound, error)
+	// ListRounds 获取开始时间在 [from, to) 内的记录，按开始时间倒序，limit 不大于 0 时不限制数量
+	ListRounds(from, to time.Time, limit int) ([]*GameRound, error)
+	// ListUserRounds 获取用户参与的、开始时间在 [from, to) 内的记录，排序和数量限制同 ListRounds
+	ListUserRounds(userID string, from, to time.Time, limit int) ([]*GameRound, error)
+	// SaveChat 追加一条牌桌聊天消息
+	SaveChat(message *ChatMessage) error
+	// ListChat 获取属于该对局的聊天消息，按发送顺序
+	ListChat(roundID string) ([]*ChatMessage, error)
+}
+
+// recordStore 当前使用的对局记录存储
+var recordStore RecordStore = NewDefaultRecordStore()
+
+// NewDefaultRecordStore 创建默认的对局记录存储，即数据目录下的 game_records
+func NewDefaultRecordStore() *FileRecordStore {
+	return NewFileRecordStore(recordDir)
+}
+
+// SetRecordStore 设置对局记录的存储，需要在创建牌桌之前调用
+func SetRecordStore(store RecordStore) {
+	recordStore = store
+}
+
+// NextRoundID 为牌桌分配下一局的对局ID
+func NextRoundID(tableID string) string {
+	if tableID == "" {
+		tableID = defaultRecordTableID
+	}
+
+	roundID, err := recordStore.NextRoundID(tableID)
+	if err != nil {
+		// 分配失败时用时间戳作为编号，保证这一局仍然可以保存
+		log.Printf("[警告] 分配对局ID失败: %v", err)
+		return FormatRoundID(tableID, int(time.Now().UnixMilli()))
+	}
+	return roundID
+}
+
+// GetUserGameRecords 获取用户在 [from, to) 内参与的所有记录，按开始时间倒序排序
+func GetUserGameRecords(userID string, from, to time.Time) ([]*GameRound, error) {
+	return recordStore.ListUserRounds(userID, from, to, 0)
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/i18n"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 会话令牌相关常量
+const (
+	tokenTTL         = 30 * 24 * time.Hour // 令牌有效期
+	claimCodeTTL     = 7 * 24 * time.Hour  // 认领码有效期
+	tokenSecretEnv   = "HOLDEM_TOKEN_SECRET"
+	passwordIter     = 100000 // PBKDF2 迭代次数
+	passwordSaltSize = 16
+	passwordKeySize  = 32
+	minPasswordLen   = 6
+	maxUsernameLen   = 32
+	contextUserKey   = "user"
+)
+
+// 游客创建频率限制
+const (
+	guestRateLimit  = 10        // 时间窗口内每个 IP 最多创建的游客数
+	guestRateWindow = time.Hour // 限制创建频率的时间窗口
+)
+
+var (
+	tokenSecretOnce sync.Once
+	tokenSecret     []byte
+
+	guestMu    sync.Mutex
+	guestTimes = make(map[string][]time.Time) // 每个 IP 最近创建游客的时间
+)
+
+// AuthRequest 登录和注册请求
+type AuthRequest struct {
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	ClaimCode string `json:"claimCode"` // 注册时认领旧版本用户数据的认领码，由管理员签发
+}
+
+// AuthResponse 登录、注册和游客接口的返回值
+type AuthResponse struct {
+	Token string `json:"token"`
+	User  *User  `json:"user"`
+}
+
+// getTokenSecret 获取签名密钥
+// 优先使用环境变量，否则读取数据目录下的密钥文件，不存在时随机生成
+func getTokenSecret() []byte {
+	tokenSecretOnce.Do(func() {
+		if secret := os.Getenv(tokenSecretEnv); secret != "" {
+			tokenSecret = []byte(secret)
+			return
+		}
+
+		if data, err := os.ReadFile(secretFile); err == nil && len(data) > 0 {
+			tokenSecret = data
+			return
+		}
+
+		tokenSecret = make([]byte, 32)
+		if _, err := rand.Read(tokenSecret); err != nil {
+			log.Fatalf("生成令牌密钥失败: %v", err)
+		}
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			log.Printf("[警告] 创建数据目录失败: %v", err)
+		}
+		if err := os.WriteFile(secretFile, tokenSecret, 0600); err != nil {
+			log.Printf("[警告] 保存令牌密钥失败，重启后已签发的令牌将失效: %v", err)
+		}
+	})
+	return tokenSecret
+}
+
+// IssueToken 为用户签发会话令牌，格式为 base64(用户ID|过期时间).base64(签名)
+func IssueToken(userID string) string {
+	expires := time.Now().Add(tokenTTL).Unix()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s|%d", userID, expires)))
+	return payload + "." + signToken(payload)
+}
+
+// ParseToken 校验令牌签名和有效期，返回用户ID
+func ParseToken(token string) (string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("令牌格式错误")
+	}
+	if !hmac.Equal([]byte(signature), []byte(signToken(payload))) {
+		return "", fmt.Errorf("令牌签名无效")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("令牌格式错误")
+	}
+	userID, expiresStr, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return "", fmt.Errorf("令牌格式错误")
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("令牌格式错误")
+	}
+	if time.Now().Unix() > expires {
+		return "", fmt.Errorf("令牌已过期")
+	}
+	return userID, nil
+}
+
+// IssueClaimCode 为旧版本用户签发认领码
+// 旧版本用 IP+UserAgent 识别用户，无法证明所有权，由管理员核实后签发，格式与会话令牌相同但签名不通用
+func IssueClaimCode(userID string) string {
+	expires := time.Now().Add(claimCodeTTL).Unix()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s|%d", userID, expires)))
+	return payload + "." + signToken("claim:"+payload)
+}
+
+// parseClaimCode 校验认领码签名和有效期，返回旧版本用户ID
+// 认领后用户不再是游客，同一个认领码不能再次使用
+func parseClaimCode(code string) (string, error) {
+	payload, signature, ok := strings.Cut(code, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signToken("claim:"+payload))) {
+		return "", fmt.Errorf("认领码无效")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("认领码无效")
+	}
+	userID, expiresStr, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return "", fmt.Errorf("认领码无效")
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("认领码无效")
+	}
+	if time.Now().Unix() > expires {
+		return "", fmt.Errorf("认领码已过期")
+	}
+	return userID, nil
+}
+
+// signToken 计算令牌内容的签名
+func signToken(payload string) string {
+	mac := hmac.New(sha256.New, getTokenSecret())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tokenFromRequest 从请求中读取令牌
+// REST 接口使用 Authorization: Bearer 头，浏览器的 WebSocket 无法设置请求头，使用 ?token= 查询参数
+func tokenFromRequest(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return c.Query("token")
+}
+
+// AuthRequired 校验会话令牌的中间件，通过后把用户保存到上下文中
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := tokenFromRequest(c)
+		if token == "" {
+			c.AbortWithStatusJSON(401, gin.H{"error": "未登录"})
+			return
+		}
+
+		userID, err := ParseToken(token)
+		if err != nil {
+			log.Printf("[API] 令牌校验失败 - IP: %s, 原因: %v", c.ClientIP(), err)
+			c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := GetUser(userID)
+		if !ok {
+			c.AbortWithStatusJSON(401, gin.H{"error": "用户不存在"})
+			return
+		}
+
+		c.Set(contextUserKey, user)
+		c.Next()
+	}
+}
+
+// currentUser 获取通过令牌校验的当前用户
+func currentUser(c *gin.Context) *User {
+	if value, ok := c.Get(contextUserKey); ok {
+		return value.(*User)
+	}
+	return nil
+}
+
+// optionalUser 请求中带有有效令牌时返回对应用户
+func optionalUser(c *gin.Context) *User {
+	userID, err := ParseToken(tokenFromRequest(c))
+	if err != nil {
+		return nil
+	}
+	user, _ := GetUser(userID)
+	return user
+}
+
+// GuestHandler 创建游客用户并签发令牌
+// 请求中带有有效令牌时继续使用该用户，同一个 IP 创建游客的频率受到限制
+func GuestHandler(c *gin.Context) {
+	if user := optionalUser(c); user != nil {
+		c.JSON(200, AuthResponse{Token: IssueToken(user.ID), User: user})
+		return
+	}
+
+	if !allowGuest(c.ClientIP()) {
+		log.Printf("[API] Guest - 创建游客太频繁 - IP: %s", c.ClientIP())
+		c.JSON(429, gin.H{"error": "创建游客太频繁，请稍后再试"})
+		return
+	}
+
+	user := CreateGuestUser(c.ClientIP(), c.GetHeader("User-Agent"))
+	c.JSON(200, AuthResponse{Token: IssueToken(user.ID), User: user})
+}
+
+// allowGuest 检查 IP 在时间窗口内创建游客的次数，未超过限制时记录本次创建
+func allowGuest(ip string) bool {
+	guestMu.Lock()
+	defer guestMu.Unlock()
+
+	now := time.Now()
+	recent := make([]time.Time, 0, guestRateLimit)
+	for _, t := range guestTimes[ip] {
+		if now.Sub(t) < guestRateWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= guestRateLimit {
+		guestTimes[ip] = recent
+		return false
+	}
+	guestTimes[ip] = append(recent, now)
+
+	// 清理时间窗口外不再创建游客的 IP
+	for key, times := range guestTimes {
+		if now.Sub(times[len(times)-1]) >= guestRateWindow {
+			delete(guestTimes, key)
+		}
+	}
+	return true
+}
+
+// RegisterHandler 注册账号
+// 请求中带有游客令牌时，游客升级为正式账号并保留其数据
+func RegisterHandler(c *gin.Context) {
+	var req AuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || len(req.Username) > maxUsernameLen {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("账号长度必须在 1 到 %d 之间", maxUsernameLen)})
+		return
+	}
+	if len(req.Password) < minPasswordLen {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("密码至少需要 %d 位", minPasswordLen)})
+		return
+	}
+
+	var legacyID string
+	if req.ClaimCode != "" {
+		id, err := parseClaimCode(strings.TrimSpace(req.ClaimCode))
+		if err != nil {
+			log.Printf("[API] Register - 认领码校验失败 - IP: %s, 原因: %v", c.ClientIP(), err)
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		legacyID = id
+	}
+
+	guest := optionalUser(c)
+	user, err := RegisterUser(req.Username, req.Password, c.ClientIP(), c.GetHeader("User-Agent"), guest, legacyID)
+	if err != nil {
+		log.Printf("[API] Register - 注册失败: %v", err)
+		var codeErr *i18n.Error
+		if errors.As(err, &codeErr) {
+			var preference string
+			if guest != nil {
+				preference = guest.Language
+			}
+			lang := i18n.Resolve(preference, c.GetHeader("Accept-Language"))
+			c.JSON(409, gin.H{"error": codeErr.Message(lang), "code": codeErr.Code})
+			return
+		}
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, AuthResponse{Token: IssueToken(user.ID), User: user})
+}
+
+// LoginHandler 账号密码登录
+func LoginHandler(c *gin.Context) {
+	var req AuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := AuthenticateUser(strings.TrimSpace(req.Username), req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		log.Printf("[API] Login - 登录失败: %s, 原因: %v", req.Username, err)
+		c.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[API] Login - 登录成功: %s", user)
+	c.JSON(200, AuthResponse{Token: IssueToken(user.ID), User: user})
+}
+
+// generateUserID 生成随机的用户ID
+func generateUserID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// hashUserPassword 使用 PBKDF2-SHA256 计算密码哈希，格式为 迭代次数$盐$哈希
+func hashUserPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成密码盐失败: %v", err)
+	}
+	key := pbkdf2.Key([]byte(password), salt, passwordIter, passwordKeySize, sha256.New)
+	return fmt.Sprintf("%d$%s$%s", passwordIter, hex.EncodeToString(salt), hex.EncodeToString(key)), nil
+}
+
+// checkUserPassword 校验密码是否与哈希匹配
+func checkUserPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[0])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	expected, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key := pbkdf2.Key([]byte(password), salt, iter, len(expected), sha256.New)
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
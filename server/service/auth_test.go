@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/i18n"
+)
+
+func TestRegisterClaimCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		guest      string // 注册时带的游客令牌：空、没有买入过、已经买入过
+		wantStatus int
+		wantCode   i18n.Code
+		wantClaim  bool // 是否认领了旧用户
+	}{
+		{name: "没有游客令牌", wantStatus: 200, wantClaim: true},
+		{name: "没有买入过的游客", guest: "pending", wantStatus: 200, wantClaim: true},
+		{name: "已经买入过的游客", guest: "funded", wantStatus: 409, wantCode: i18n.ErrClaimGuestData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestStores(t)
+			router := gin.New()
+			router.POST("/auth/register", RegisterHandler)
+
+			// 旧版本的用户作为已保存的游客加载，资金在买入后变化
+			legacy := CreateGuestUser("10.0.0.1", "legacy")
+			if _, err := BuyIn(legacy.ID, "table", 300); err != nil {
+				t.Fatalf("买入失败: %v", err)
+			}
+
+			var guest *User
+			if tt.guest != "" {
+				guest = CreateGuestUser("10.0.0.2", "guest")
+			}
+			if tt.guest == "funded" {
+				if _, err := BuyIn(guest.ID, "table", 500); err != nil {
+					t.Fatalf("买入失败: %v", err)
+				}
+			}
+
+			body, _ := json.Marshal(AuthRequest{Username: "alice", Password: "password123", ClaimCode: IssueClaimCode(legacy.ID)})
+			req := httptest.NewRequest("POST", "/auth/register", bytes.NewReader(body))
+			if guest != nil {
+				req.Header.Set("Authorization", "Bearer "+IssueToken(guest.ID))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d, 期望 %d, 响应: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			var resp struct {
+				User *User     `json:"user"`
+				Code i18n.Code `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("错误码 = %q, 期望 %q", resp.Code, tt.wantCode)
+			}
+
+			claimed, _ := GetUser(legacy.ID)
+			if got := !claimed.IsGuest(); got != tt.wantClaim {
+				t.Fatalf("旧用户已认领 = %v, 期望 %v", got, tt.wantClaim)
+			}
+			if tt.wantClaim {
+				if resp.User.ID != legacy.ID || claimed.Bankroll != initialBankroll-300 {
+					t.Errorf("注册的用户 = %s, 资金 %d, 期望认领 %s, 资金 %d",
+						resp.User.ID, claimed.Bankroll, legacy.ID, initialBankroll-300)
+				}
+			}
+			if tt.guest == "funded" {
+				if kept, _ := GetUser(guest.ID); !kept.IsGuest() || kept.Bankroll != initialBankroll-500 {
+					t.Errorf("游客的数据被修改: 已注册 %v, 资金 %d", !kept.IsGuest(), kept.Bankroll)
+				}
+			}
+		})
+	}
+}
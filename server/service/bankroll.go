@@ -0,0 +1,177 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// initialBankroll 新用户的初始资金
+const initialBankroll = 10000
+
+// 资金流水类型
+const (
+	TxGrant   = "grant"    // 发放初始资金
+	TxBuyIn   = "buy_in"   // 落座买入
+	TxCashOut = "cash_out" // 离座兑现
+	TxRebuy   = "rebuy"    // 补码
+	TxTopUp   = "top_up"   // 加码
+)
+
+// Transaction 一条资金流水，Amount 是对资金的影响，买入、补码和加码为负数，兑现为正数
+type Transaction struct {
+	UserID  string `json:"userId"`            // 用户ID
+	Type    string `json:"type"`              // 流水类型
+	Amount  int    `json:"amount"`            // 资金变化
+	Balance int    `json:"balance"`           // 变化后的资金
+	TableID string `json:"tableId,omitempty"` // 发生的牌桌
+	Time    int64  `json:"time"`              // 发生时间（毫秒时间戳）
+}
+
+// GetLedgerRequest 获取资金流水的请求参数
+type GetLedgerRequest struct {
+	Limit int `form:"limit"`
+}
+
+// grantBankroll 发放初始资金，调用者需持有锁
+func grantBankroll(user *User) error {
+	user.Bankroll = initialBankroll
+	return recordTransaction(user, &Transaction{
+		UserID:  user.ID,
+		Type:    TxGrant,
+		Amount:  initialBankroll,
+		Balance: user.Bankroll,
+		Time:    time.Now().UnixMilli(),
+	})
+}
+
+// recordTransaction 保存用户和资金流水，调用者需持有锁
+func recordTransaction(user *User, tx *Transaction) error {
+	stored := &StoredUser{User: user.clone(), PasswordHash: user.passwordHash, HasBankroll: true}
+	return userStore.RecordTransaction(stored, tx)
+}
+
+// adjustBankroll 按 delta 调整用户资金并记录流水，资金不足时返回错误
+func adjustBankroll(userID, tableID, txType string, delta int) (*Transaction, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	user, ok := users[userID]
+	if !ok {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	// 游客第一次买入时才保存并发放初始资金
+	if user.pending {
+		if err := grantBankroll(user); err != nil {
+			return nil, fmt.Errorf("发放初始资金失败: %v", err)
+		}
+		user.pending = false
+	}
+	if user.Bankroll+delta < 0 {
+		return nil, requestError(i18n.ErrInsufficientFunds, user.Bankroll)
+	}
+
+	user.Bankroll += delta
+	tx := &Transaction{
+		UserID:  userID,
+		Type:    txType,
+		Amount:  delta,
+		Balance: user.Bankroll,
+		TableID: tableID,
+		Time:    time.Now().UnixMilli(),
+	}
+	if err := recordTransaction(user, tx); err != nil {
+		user.Bankroll -= delta
+		return nil, fmt.Errorf("保存资金流水失败: %v", err)
+	}
+
+	log.Printf("[资金] %s - 用户: %s, 牌桌: %s, 变化: %d, 余额: %d", txType, user, tableID, delta, user.Bankroll)
+	return tx, nil
+}
+
+// BuyIn 从用户资金中买入筹码
+func BuyIn(userID, tableID string, amount int) (*Transaction, error) {
+	return adjustBankroll(userID, tableID, TxBuyIn, -amount)
+}
+
+// CashOut 把筹码兑现回用户资金
+func CashOut(userID, tableID string, amount int) (*Transaction, error) {
+	return adjustBankroll(userID, tableID, TxCashOut, amount)
+}
+
+// GetUserLedger 获取用户最近的资金流水
+func GetUserLedger(userID string, limit int) ([]*Transaction, error) {
+	return userStore.ListTransactions(userID, limit)
+}
+
+// buyInAmount 确定落座的买入金额，requested 为 0 时买入 min(最大买入, 资金)
+func (h *Hub) buyInAmount(userID string, requested int) (int, error) {
+	config := h.game.Config
+	user, ok := GetUser(userID)
+	if !ok {
+		return 0, requestError(i18n.ErrUserNotFound)
+	}
+
+	amount := requested
+	if amount == 0 {
+		amount = min(config.MaxBuyIn, user.Bankroll)
+	}
+	if amount < config.MinBuyIn || amount > config.MaxBuyIn {
+		return 0, requestError(i18n.ErrInvalidBuyIn, config.MinBuyIn, config.MaxBuyIn)
+	}
+	if amount > user.Bankroll {
+		return 0, requestError(i18n.ErrInsufficientFunds, user.Bankroll)
+	}
+	return amount, nil
+}
+
+// vacateSeat 玩家离开座位，剩余筹码兑现回资金
+func (h *Hub) vacateSeat(player *poker.Player) {
+	h.cancelGraceTimer(player.UserId)
+	if player.Chips > 0 {
+		if _, err := CashOut(player.UserId, h.id, player.Chips); err != nil {
+			log.Printf("[资金] 兑现失败 - 玩家: %s, 筹码: %d, 原因: %v", player.Name, player.Chips, err)
+		}
+	}
+	player.Reset()
+}
+
+// cashOutAll 牌桌关闭时所有落座玩家离座
+func (h *Hub) cashOutAll() {
+	for i := range h.game.Players {
+		if !h.game.Players[i].IsEmpty() {
+			h.vacateSeat(&h.game.Players[i])
+		}
+	}
+}
+
+// GetLedgerHandler 获取当前用户资金流水的处理函数
+func GetLedgerHandler(c *gin.Context) {
+	var req GetLedgerRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request parameters"})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+
+	user := currentUser(c)
+	transactions, err := GetUserLedger(user.ID, req.Limit)
+	if err != nil {
+		log.Printf("[API] GetLedger - 获取资金流水失败: %v", err)
+		c.JSON(500, gin.H{"error": "Failed to get ledger"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"bankroll":     user.Bankroll,
+		"total":        len(transactions),
+		"transactions": transactions,
+	})
+}
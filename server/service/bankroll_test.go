@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lllllan02/holdem/i18n"
+)
+
+// errorCode 返回错误的 i18n 错误码，不是 i18n.Error 时返回空
+func errorCode(err error) i18n.Code {
+	var e *i18n.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+func TestBankrollLedger(t *testing.T) {
+	initTestStores(t)
+	user := CreateGuestUser("10.0.0.1", "test")
+
+	// 依次执行，每一步之后检查余额
+	steps := []struct {
+		name    string
+		adjust  func() (*Transaction, error)
+		code    i18n.Code
+		balance int
+	}{
+		{
+			name:    "第一次买入时发放初始资金",
+			adjust:  func() (*Transaction, error) { return BuyIn(user.ID, "t1", 1000) },
+			balance: initialBankroll - 1000,
+		},
+		{
+			name:    "资金不足",
+			adjust:  func() (*Transaction, error) { return BuyIn(user.ID, "t1", initialBankroll) },
+			code:    i18n.ErrInsufficientFunds,
+			balance: initialBankroll - 1000,
+		},
+		{
+			name:    "兑现赢得的筹码",
+			adjust:  func() (*Transaction, error) { return CashOut(user.ID, "t1", 1600) },
+			balance: initialBankroll + 600,
+		},
+		{
+			name:    "补码",
+			adjust:  func() (*Transaction, error) { return adjustBankroll(user.ID, "t2", TxRebuy, -400) },
+			balance: initialBankroll + 200,
+		},
+		{
+			name:    "买入全部资金",
+			adjust:  func() (*Transaction, error) { return BuyIn(user.ID, "t2", initialBankroll+200) },
+			balance: 0,
+		},
+	}
+
+	for _, step := range steps {
+		tx, err := step.adjust()
+		if code := errorCode(err); code != step.code || (err != nil && step.code == "") {
+			t.Fatalf("%s: 错误 = %v, 期望 %q", step.name, err, step.code)
+		}
+		if tx != nil && tx.Balance != step.balance {
+			t.Errorf("%s: 流水余额 = %d, 期望 %d", step.name, tx.Balance, step.balance)
+		}
+		if got, _ := GetUser(user.ID); got.Bankroll != step.balance {
+			t.Errorf("%s: 资金 = %d, 期望 %d", step.name, got.Bankroll, step.balance)
+		}
+	}
+
+	// 失败的买入不记录流水，每条流水的余额都是前一条余额加上变化
+	ledger, err := GetUserLedger(user.ID, 0)
+	if err != nil {
+		t.Fatalf("获取资金流水失败: %v", err)
+	}
+	var types []string
+	balance := 0
+	for i := len(ledger) - 1; i >= 0; i-- {
+		tx := ledger[i]
+		types = append(types, tx.Type)
+		if balance+tx.Amount != tx.Balance {
+			t.Errorf("%s 流水: %d%+d != %d", tx.Type, balance, tx.Amount, tx.Balance)
+		}
+		balance = tx.Balance
+	}
+	want := []string{TxGrant, TxBuyIn, TxCashOut, TxRebuy, TxBuyIn}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("流水类型 = %v, 期望 %v", types, want)
+	}
+}
+
+func TestBuyInAmount(t *testing.T) {
+	initTestStores(t)
+	hub := createTestTable(t, TableOptions{})
+	rich := CreateGuestUser("10.0.0.1", "test")
+	poor := CreateGuestUser("10.0.0.2", "test")
+	if _, err := BuyIn(poor.ID, "t", initialBankroll-500); err != nil {
+		t.Fatalf("买入失败: %v", err)
+	}
+
+	// 默认配置的买入范围是 400 到 1000
+	tests := []struct {
+		name      string
+		userID    string
+		requested int
+		want      int
+		code      i18n.Code
+	}{
+		{name: "默认买入最大值", userID: rich.ID, want: 1000},
+		{name: "资金不足最大值时买入全部资金", userID: poor.ID, want: 500},
+		{name: "指定金额", userID: rich.ID, requested: 600, want: 600},
+		{name: "低于最小买入", userID: rich.ID, requested: 300, code: i18n.ErrInvalidBuyIn},
+		{name: "高于最大买入", userID: rich.ID, requested: 1200, code: i18n.ErrInvalidBuyIn},
+		{name: "超出资金", userID: poor.ID, requested: 800, code: i18n.ErrInsufficientFunds},
+		{name: "用户不存在", userID: "nobody", code: i18n.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var amount int
+			var err error
+			hub.call(func() { amount, err = hub.buyInAmount(tt.userID, tt.requested) })
+			if code := errorCode(err); code != tt.code {
+				t.Fatalf("错误 = %v, 期望 %q", err, tt.code)
+			}
+			if amount != tt.want {
+				t.Errorf("买入金额 = %d, 期望 %d", amount, tt.want)
+			}
+		})
+	}
+}
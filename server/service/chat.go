@@ -0,0 +1,168 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// 聊天相关限制
+const (
+	chatHistorySize = 50               // 每个牌桌保留并在加入时发送的最近消息数
+	chatMaxLength   = 200              // 一条消息的最大字数
+	chatRateLimit   = 5                // 时间窗口内每个用户最多发送的消息数
+	chatRateWindow  = 10 * time.Second // 限制发言频率的时间窗口
+)
+
+// handleChat 处理聊天消息，被禁言或发言太频繁时拒绝
+func (c *Client) handleChat(req ChatRequest) error {
+	text := strings.TrimSpace(req.Text)
+	if text == "" || utf8.RuneCountInString(text) > chatMaxLength {
+		return requestError(i18n.ErrInvalidChat, chatMaxLength)
+	}
+	if c.hub.muted[c.user.ID] {
+		return requestError(i18n.ErrMuted)
+	}
+	if !c.hub.allowChat(c.user.ID) {
+		return requestError(i18n.ErrRateLimited)
+	}
+
+	log.Printf("[聊天] %s: %s", c.user, text)
+	c.hub.postChat(&poker.ChatMessage{UserId: c.user.ID, Name: c.user.Name, Text: text})
+	return nil
+}
+
+// allowChat 检查用户在时间窗口内的发言次数，未超过限制时记录本次发言
+func (h *Hub) allowChat(userID string) bool {
+	now := time.Now()
+	recent := make([]time.Time, 0, chatRateLimit)
+	for _, t := range h.chatTimes[userID] {
+		if now.Sub(t) < chatRateWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= chatRateLimit {
+		h.chatTimes[userID] = recent
+		return false
+	}
+	h.chatTimes[userID] = append(recent, now)
+	return true
+}
+
+// postChat 保存消息并发送给牌桌上的所有客户端
+// 消息归属于进行中或刚结束的一局，与对局记录保存在同一个存储中
+func (h *Hub) postChat(message *poker.ChatMessage) {
+	message.TableID = h.id
+	message.RoundID = h.game.RoundID
+	message.Time = time.Now().UnixMilli()
+
+	if len(h.chat) >= chatHistorySize {
+		copy(h.chat, h.chat[1:])
+		h.chat = h.chat[:len(h.chat)-1]
+	}
+	h.chat = append(h.chat, message)
+
+	if err := poker.SaveChatMessage(message); err != nil {
+		log.Printf("[聊天] 保存聊天消息失败 - 牌桌: %s, 原因: %v", h.id, err)
+	}
+	for _, client := range h.clients {
+		client.sendChat(message)
+	}
+}
+
+// dealerSay 发送荷官消息，内容按每个客户端的语言生成
+func (h *Hub) dealerSay(key string, args ...interface{}) {
+	h.postChat(&poker.ChatMessage{
+		Text:   i18n.T(i18n.Default, key, args...),
+		Dealer: true,
+		Key:    key,
+		Args:   args,
+	})
+}
+
+// announceHand 播报上次广播之后的行动和刚结束一局的赢家，在广播游戏状态时调用
+func (h *Hub) announceHand() {
+	if h.game.RoundID != h.announcedRound {
+		h.announcedRound = h.game.RoundID
+		h.announcedActions = 0
+	}
+	for ; h.announcedActions < len(h.game.Actions); h.announcedActions++ {
+		if key, args, ok := dealerAction(h.game.Actions[h.announcedActions]); ok {
+			h.dealerSay(key, args...)
+		}
+	}
+
+	round := h.game.CurrentRound
+	if round == nil || round.RoundID == h.announcedWinners {
+		return
+	}
+	h.announcedWinners = round.RoundID
+	for _, winner := range round.Winners {
+		// 没有摊牌时赢家的牌型已被清空，只播报赢得的筹码
+		if winner.Position >= 0 && winner.Position < len(h.game.Players) {
+			if rank := h.game.Players[winner.Position].HandRank; rank != nil {
+				h.dealerSay("DEALER_WIN_HAND", winner.Name, winner.WinAmount, i18n.HandKey(rank.Rank))
+				continue
+			}
+		}
+		h.dealerSay("DEALER_WIN", winner.Name, winner.WinAmount)
+	}
+}
+
+// dealerAction 返回播报一次行动的文本 key 和参数，盲注、前注和退还不播报
+func dealerAction(action poker.Action) (string, []interface{}, bool) {
+	switch action.Type {
+	case poker.ActionFold:
+		return "DEALER_FOLD", []interface{}{action.Name}, true
+	case poker.ActionCheck:
+		return "DEALER_CHECK", []interface{}{action.Name}, true
+	case poker.ActionCall, poker.ActionBet, poker.ActionRaise:
+	default:
+		return "", nil, false
+	}
+
+	switch {
+	case action.AllIn:
+		return "DEALER_ALL_IN", []interface{}{action.Name, max(action.RaiseTo, action.Amount)}, true
+	case action.Type == poker.ActionCall:
+		return "DEALER_CALL", []interface{}{action.Name, action.Amount}, true
+	case action.Type == poker.ActionBet:
+		return "DEALER_BET", []interface{}{action.Name, action.RaiseTo}, true
+	default:
+		return "DEALER_RAISE", []interface{}{action.Name, action.RaiseTo}, true
+	}
+}
+
+// chatData 生成发送给客户端的聊天消息
+func chatData(message *poker.ChatMessage, lang i18n.Lang) ChatData {
+	text := message.Text
+	if message.Key != "" {
+		text = i18n.T(lang, message.Key, message.Args...)
+	}
+	return ChatData{
+		Time:   message.Time,
+		UserID: message.UserId,
+		Name:   message.Name,
+		Text:   text,
+		Dealer: message.Dealer,
+	}
+}
+
+// sendChat 发送一条聊天消息给客户端
+func (c *Client) sendChat(message *poker.ChatMessage) {
+	c.sendMessage(WSMessage{Type: MSG_CHAT, Data: chatData(message, c.lang())})
+}
+
+// sendChatHistory 发送牌桌最近的聊天消息，加入牌桌时调用
+func (c *Client) sendChatHistory() {
+	lang := c.lang()
+	messages := make([]ChatData, 0, len(c.hub.chat))
+	for _, message := range c.hub.chat {
+		messages = append(messages, chatData(message, lang))
+	}
+	c.sendMessage(WSMessage{Type: MSG_CHAT_HISTORY, Data: ChatHistoryData{Messages: messages}})
+}
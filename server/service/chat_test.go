@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+func TestChatModeration(t *testing.T) {
+	table := newTestTable(t)
+	host, err := table.dial(CreateGuestUser("10.0.0.1", "holdem-test"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.conn.Close()
+	player, err := table.dial(CreateGuestUser("10.0.0.2", "holdem-test"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer player.conn.Close()
+
+	// 第一个落座的玩家成为房主
+	if err := host.mustSucceed(MSG_SIT_DOWN, SitDownRequest{SeatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 依次发送，后面的请求依赖前面的结果
+	tests := []struct {
+		name        string
+		client      *testClient
+		messageType MessageType
+		data        interface{}
+		want        i18n.Code
+	}{
+		{name: "发言", client: player, messageType: MSG_CHAT, data: ChatRequest{Text: "你好"}},
+		{name: "空消息", client: player, messageType: MSG_CHAT, data: ChatRequest{Text: "  "}, want: i18n.ErrInvalidChat},
+		{name: "消息过长", client: player, messageType: MSG_CHAT, data: ChatRequest{Text: strings.Repeat("长", chatMaxLength+1)}, want: i18n.ErrInvalidChat},
+		{name: "不是房主不能禁言", client: player, messageType: MSG_MUTE_PLAYER, data: MutePlayerRequest{UserID: host.user.ID, Mute: true}, want: i18n.ErrNotHost},
+		{name: "房主不能禁言自己", client: host, messageType: MSG_MUTE_PLAYER, data: MutePlayerRequest{UserID: host.user.ID, Mute: true}, want: i18n.ErrCannotMuteSelf},
+		{name: "房主禁言", client: host, messageType: MSG_MUTE_PLAYER, data: MutePlayerRequest{UserID: player.user.ID, Mute: true}},
+		{name: "被禁言后发言", client: player, messageType: MSG_CHAT, data: ChatRequest{Text: "你好"}, want: i18n.ErrMuted},
+		{name: "房主解除禁言", client: host, messageType: MSG_MUTE_PLAYER, data: MutePlayerRequest{UserID: player.user.ID, Mute: false}},
+		{name: "解除禁言后发言", client: player, messageType: MSG_CHAT, data: ChatRequest{Text: "谢谢"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := tt.client.request(tt.messageType, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Errorf("错误码 = %q, 期望 %q", code, tt.want)
+			}
+		})
+	}
+
+	// 历史中只有成功的发言和荷官播报的禁言消息
+	var texts []string
+	table.hub.call(func() {
+		for _, message := range table.hub.chat {
+			texts = append(texts, message.Text)
+		}
+	})
+	want := []string{
+		"你好",
+		i18n.T(i18n.Default, "DEALER_MUTED", player.user.Name),
+		i18n.T(i18n.Default, "DEALER_UNMUTED", player.user.Name),
+		"谢谢",
+	}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("聊天记录 = %q, 期望 %q", texts, want)
+	}
+}
+
+func TestAllowChat(t *testing.T) {
+	initTestStores(t)
+	hub := createTestTable(t, TableOptions{})
+
+	tests := []struct {
+		name   string
+		recent []time.Duration // 之前发言距现在的时间
+		want   bool
+	}{
+		{name: "没有发言", want: true},
+		{name: "未达到限制", recent: []time.Duration{1, 2, 3, 4}, want: true},
+		{name: "达到限制", recent: []time.Duration{1, 2, 3, 4, 5}, want: false},
+		{name: "超出时间窗口的发言不计入", recent: []time.Duration{1, 2, 3, 4, 11}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var allowed bool
+			var count int
+			hub.call(func() {
+				times := make([]time.Time, 0, len(tt.recent))
+				for _, ago := range tt.recent {
+					times = append(times, time.Now().Add(-ago*time.Second))
+				}
+				hub.chatTimes["u1"] = times
+				allowed = hub.allowChat("u1")
+				count = len(hub.chatTimes["u1"])
+			})
+			if allowed != tt.want {
+				t.Errorf("allowChat = %v, 期望 %v", allowed, tt.want)
+			}
+			if count > chatRateLimit {
+				t.Errorf("记录的发言次数 %d 超过限制", count)
+			}
+		})
+	}
+}
+
+func TestChatHistory(t *testing.T) {
+	initTestStores(t)
+	hub := createTestTable(t, TableOptions{})
+	roundID := poker.FormatRoundID(hub.id, 1)
+
+	// 只保留最近的消息
+	var texts []string
+	hub.call(func() {
+		hub.game.RoundID = roundID
+		for i := 1; i <= chatHistorySize+10; i++ {
+			hub.postChat(&poker.ChatMessage{UserId: "u1", Name: "玩家1", Text: fmt.Sprint(i)})
+		}
+		for _, message := range hub.chat {
+			texts = append(texts, message.Text)
+		}
+	})
+	if len(texts) != chatHistorySize || texts[0] != "11" || texts[len(texts)-1] != fmt.Sprint(chatHistorySize+10) {
+		t.Errorf("聊天记录 %d 条，从 %s 到 %s，期望 %d 条，从 11 到 %d",
+			len(texts), texts[0], texts[len(texts)-1], chatHistorySize, chatHistorySize+10)
+	}
+
+	// 所有消息都随所属的对局保存
+	saved, err := poker.GetRoundChat(roundID)
+	if err != nil {
+		t.Fatalf("读取聊天记录失败: %v", err)
+	}
+	if len(saved) != chatHistorySize+10 || saved[0].TableID != hub.id {
+		t.Errorf("保存的聊天记录 %d 条，期望 %d 条", len(saved), chatHistorySize+10)
+	}
+}
+
+func TestDealerAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		action poker.Action
+		want   string // 英文播报，为空表示不播报
+	}{
+		{name: "弃牌", action: poker.Action{Name: "Alice", Type: poker.ActionFold}, want: "Alice folds"},
+		{name: "过牌", action: poker.Action{Name: "Alice", Type: poker.ActionCheck}, want: "Alice checks"},
+		{name: "跟注", action: poker.Action{Name: "Alice", Type: poker.ActionCall, Amount: 40}, want: "Alice calls 40"},
+		{name: "下注", action: poker.Action{Name: "Alice", Type: poker.ActionBet, Amount: 60, RaiseTo: 60}, want: "Alice bets 60"},
+		{name: "加注", action: poker.Action{Name: "Alice", Type: poker.ActionRaise, Amount: 180, RaiseTo: 200}, want: "Alice raises to 200"},
+		{name: "加注全下", action: poker.Action{Name: "Alice", Type: poker.ActionRaise, Amount: 480, RaiseTo: 500, AllIn: true}, want: "Alice is all-in for 500"},
+		{name: "跟注全下", action: poker.Action{Name: "Alice", Type: poker.ActionCall, Amount: 35, AllIn: true}, want: "Alice is all-in for 35"},
+		{name: "盲注不播报", action: poker.Action{Name: "Alice", Type: poker.ActionBigBlind, Amount: 20}},
+		{name: "退还不播报", action: poker.Action{Name: "Alice", Type: poker.ActionUncalled, Amount: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if key, args, ok := dealerAction(tt.action); ok {
+				got = i18n.T(i18n.EN, key, args...)
+			}
+			if got != tt.want {
+				t.Errorf("播报 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
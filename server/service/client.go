@@ -2,11 +2,11 @@ package service
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/lllllan02/holdem/i18n"
 	"github.com/lllllan02/holdem/poker"
 )
 
@@ -26,62 +26,25 @@ type Client struct {
 
 	// 连接建立时间
 	connectedAt time.Time
-}
 
-// sendGameState 发送当前游戏状态给客户端
-func (c *Client) sendGameState() {
-	// 创建游戏状态的副本，用于隐藏其他玩家的手牌
-	gameStateCopy := *c.hub.game
-	playersCopy := make([]poker.Player, len(c.hub.game.Players))
-
-	// 复制玩家数据，隐藏其他玩家的手牌
-	for i, player := range c.hub.game.Players {
-		playersCopy[i] = player
-
-		// 如果不是当前用户且不是摊牌阶段，隐藏手牌
-		// 但如果玩家已经弃牌，则保留其状态信息
-		if player.UserId != c.user.ID && c.hub.game.GamePhase != "showdown" && c.hub.game.GamePhase != "showdown_reveal" {
-			playersCopy[i].HoleCards = make([]poker.Card, len(player.HoleCards))
-			// 保留手牌数量但不显示内容
-			for j := range player.HoleCards {
-				playersCopy[i].HoleCards[j] = poker.Card{} // 空牌
-			}
-		}
-	}
+	// 客户端重连时带来的会话恢复令牌
+	resumeToken string
 
-	gameStateCopy.Players = playersCopy
-
-	// 添加倒计时调试信息
-	if gameStateCopy.CountdownTimer > 0 {
-		log.Printf("[WS] 发送倒计时状态 - %s, 倒计时: %d", c.user, gameStateCopy.CountdownTimer)
-	}
-
-	gameStateMsg := WSMessage{
-		Type: MSG_GAME_STATE,
-		Data: GameStateData{
-			Game: &gameStateCopy,
-		},
-	}
+	// 用户已落座但本连接没有有效的恢复令牌，不接管座位、看不到手牌，只在 hub 协程中访问
+	detached bool
 
-	messageBytes, err := json.Marshal(gameStateMsg)
-	if err != nil {
-		log.Printf("[WS] 序列化游戏状态失败 - %s, 错误: %v\n", c.user, err)
-		return
-	}
+	// 连接时浏览器的 Accept-Language，用户没有设置语言偏好时按它选择错误消息的语言
+	acceptLanguage string
 
-	// 使用defer和recover来捕获panic
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("[WS] 发送游戏状态时发生panic - %s, 错误: %v\n", c.user, r)
-		}
-	}()
+	// 协议版本和增量更新的状态，只在 hub 协程中访问
+	protocol int
+	seq      int64     // 最后发送的游戏状态序号
+	lastView *gameView // 最后发送的游戏状态，增量的基准
+}
 
-	select {
-	case c.send <- messageBytes:
-		log.Printf("[WS] 游戏状态已发送 - %s\n", c.user)
-	default:
-		log.Printf("[WS] 发送游戏状态失败，通道已满或已关闭 - %s\n", c.user)
-	}
+// sendGameState 发送当前的完整游戏状态给客户端，其他玩家的手牌被隐藏
+func (c *Client) sendGameState() {
+	c.sendSnapshot(c.hub.publicView().forClient(c))
 }
 
 // writePump 将消息从 channel 写入 WebSocket 连接
@@ -123,7 +86,10 @@ func (c *Client) writePump() {
 // readPump 从 WebSocket 连接读取消息并发送到 hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.quit:
+		}
 		c.conn.Close()
 		log.Printf("[WS] 读取协程结束 - %s, 连接持续时间: %v\n",
 			c.user, time.Since(c.connectedAt))
@@ -143,369 +109,230 @@ func (c *Client) readPump() {
 
 		log.Printf("[WS] 收到消息 - %s, 长度: %d bytes\n", c.user, len(messageBytes))
 
-		// 解析消息
-		var message WSMessage
+		// 解析消息，格式错误时回复错误消息
+		var message ClientMessage
 		if err := json.Unmarshal(messageBytes, &message); err != nil {
 			log.Printf("[WS] 解析消息失败 - %s, 错误: %v\n", c.user, err)
+			if !c.hub.call(func() { c.sendError(i18n.ErrBadRequest) }) {
+				break
+			}
 			continue
 		}
 
-		// 处理消息
-		c.handleClientMessage(message)
+		// 在 hub 协程中处理消息，牌桌已关闭时退出
+		if !c.hub.call(func() { c.reply(message, c.handleClientMessage(message)) }) {
+			break
+		}
 	}
 }
 
-// handleClientMessage 处理客户端发送的消息
-func (c *Client) handleClientMessage(message WSMessage) {
-	log.Printf("[WS] 处理消息 - %s, 类型: %s\n", c.user, message.Type)
+// handleClientMessage 处理客户端发送的消息，在 hub 协程中执行
+func (c *Client) handleClientMessage(message ClientMessage) error {
+	log.Printf("[WS] 处理消息 - %s, 类型: %s, 请求ID: %s\n", c.user, message.Type, message.ID)
+
+	// 没有接管座位的连接只能聊天、同步状态和执行房主操作，座位被清空后恢复正常
+	if player, _ := c.hub.seatOf(c.user.ID); player == nil {
+		c.detached = false
+	}
+	if c.detached {
+		switch message.Type {
+		case MSG_CHAT, MSG_RESYNC, MSG_PAUSE_GAME, MSG_RESUME_GAME, MSG_SET_BLINDS, MSG_KICK_PLAYER, MSG_MUTE_PLAYER:
+		default:
+			return requestError(i18n.ErrSeatDetached)
+		}
+	}
 
 	switch message.Type {
 	case MSG_SIT_DOWN:
-		c.handleSitDown(message.Data)
+		return withPayload(message.Data, c.handleSitDown)
 	case MSG_LEAVE_SEAT:
-		c.handleLeaveSeat(message.Data)
+		return withPayload(message.Data, c.handleLeaveSeat)
 	case MSG_READY:
-		c.handleReady()
+		return c.handleReady()
 	case MSG_UNREADY:
-		c.handleUnready()
+		return c.handleUnready()
 	case MSG_START_GAME:
-		c.handleStartGame()
-	case MSG_FOLD:
-		c.handlePlayerAction("fold", message.Data)
-	case MSG_CALL:
-		c.handlePlayerAction("call", message.Data)
-	case MSG_RAISE:
-		c.handlePlayerAction("raise", message.Data)
-	case MSG_CHECK:
-		c.handlePlayerAction("check", message.Data)
+		return c.handleStartGame()
+	case MSG_FOLD, MSG_CALL, MSG_RAISE, MSG_CHECK, MSG_ALL_IN:
+		return withPayload(message.Data, func(req ActionRequest) error {
+			return c.handlePlayerAction(string(message.Type), req)
+		})
 	case MSG_END_GAME:
-		c.handleEndGame()
+		return c.handleEndGame()
+	case MSG_REBUY:
+		return withPayload(message.Data, func(req AddChipsRequest) error {
+			return c.handleAddChips(poker.ChipsRebuy, req)
+		})
+	case MSG_TOP_UP:
+		return withPayload(message.Data, func(req AddChipsRequest) error {
+			return c.handleAddChips(poker.ChipsTopUp, req)
+		})
+	case MSG_SIT_OUT:
+		return c.handleSitOut(true)
+	case MSG_SIT_IN:
+		return c.handleSitOut(false)
+	case MSG_CHAT:
+		return withPayload(message.Data, c.handleChat)
+	case MSG_RESYNC:
+		c.handleResync()
+		return nil
+	case MSG_PAUSE_GAME:
+		return c.handlePauseGame()
+	case MSG_RESUME_GAME:
+		return c.handleResumeGame()
+	case MSG_SET_BLINDS:
+		return withPayload(message.Data, c.handleSetBlinds)
+	case MSG_KICK_PLAYER:
+		return withPayload(message.Data, c.handleKickPlayer)
+	case MSG_MUTE_PLAYER:
+		return withPayload(message.Data, c.handleMutePlayer)
 	default:
-		log.Printf("[WS] 未知消息类型 - %s, 类型: %s\n", c.user, message.Type)
+		return requestError(i18n.ErrUnknownType, message.Type)
 	}
 }
 
-// handleSitDown 处理落座请求
-func (c *Client) handleSitDown(data interface{}) {
-	// 检查游戏状态，只有在等待状态或摊牌阶段才允许新玩家落座
-	if c.hub.game.GameStatus != "waiting" && c.hub.game.GamePhase != "showdown" {
-		log.Printf("[WS] 游戏进行中不允许落座 - %s\n", c.user)
-
-		// 发送错误消息给客户端
-		errorMsg := WSMessage{
-			Type: MSG_ERROR,
-			Data: ErrorData{
-				Message: "游戏进行中不能落座",
-			},
-		}
-
-		messageBytes, err := json.Marshal(errorMsg)
-		if err != nil {
-			log.Printf("[WS] 序列化错误消息失败 - %s, 错误: %v\n", c.user, err)
-			return
-		}
-
-		select {
-		case c.send <- messageBytes:
-			log.Printf("[WS] 错误消息已发送 - %s\n", c.user)
-		default:
-			log.Printf("[WS] 发送错误消息失败，通道已满 - %s\n", c.user)
-		}
-		return
+// seatIndex 把从 1 开始的座位号转换为数组索引
+func (c *Client) seatIndex(seatID int) (int, error) {
+	if seatID < 1 || seatID > len(c.hub.game.Players) {
+		return -1, requestError(i18n.ErrInvalidSeat, seatID)
 	}
+	return seatID - 1, nil
+}
 
-	// 解析座位ID
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		log.Printf("[WS] 落座数据格式错误 - %s\n", c.user)
-		return
-	}
+// betweenHands 检查是否在两局之间（等待开始或摊牌结束）
+func (c *Client) betweenHands() bool {
+	return c.hub.game.GameStatus == poker.GameStatusWaiting || c.hub.game.GamePhase == poker.GamePhaseShowdown
+}
 
-	seatId, ok := dataMap["seatId"].(float64)
-	if !ok {
-		log.Printf("[WS] 座位ID格式错误 - %s\n", c.user)
-		return
+// handleSitDown 处理落座请求，只有在等待状态或摊牌阶段才允许新玩家落座
+func (c *Client) handleSitDown(req SitDownRequest) error {
+	if !c.betweenHands() {
+		return requestError(i18n.ErrGameInProgress)
+	}
+	if c.hub.closing {
+		return requestError(i18n.ErrTableClosing)
 	}
 
-	seatIndex := int(seatId) - 1 // 转换为数组索引
-	if seatIndex < 0 || seatIndex >= len(c.hub.game.Players) {
-		log.Printf("[WS] 座位ID超出范围 - %s, 座位: %d\n", c.user, int(seatId))
-		return
+	seatIndex, err := c.seatIndex(req.SeatID)
+	if err != nil {
+		return err
 	}
 
 	// 检查座位是否已被占用
 	currentPlayer := &c.hub.game.Players[seatIndex]
 	if !currentPlayer.IsEmpty() {
-		log.Printf("[WS] 座位已被占用 - %s, 座位: %d, 占用者: %s\n", c.user, int(seatId), currentPlayer.Name)
-		return
+		return requestError(i18n.ErrSeatTaken, req.SeatID)
 	}
 
 	// 检查用户是否已经坐在其他座位
-	for i, player := range c.hub.game.Players {
-		if player.UserId == c.user.ID && !player.IsEmpty() {
-			log.Printf("[WS] 用户已在其他座位 - %s, 当前座位: %d\n", c.user, i+1)
-			return
-		}
+	if _, pos := c.hub.seatOf(c.user.ID); pos >= 0 {
+		return requestError(i18n.ErrAlreadySeated, pos+1)
+	}
+
+	// 从资金中买入，不指定金额时尽量按最大买入
+	buyIn, err := c.hub.buyInAmount(c.user.ID, req.BuyIn)
+	if err != nil {
+		return err
+	}
+	if _, err := BuyIn(c.user.ID, c.hub.id, buyIn); err != nil {
+		return err
 	}
 
 	// 落座 - 使用SitDown方法
-	currentPlayer.SitDown(c.user.ID, c.user.Name)
+	currentPlayer.SitDown(c.user.ID, c.user.Name, buyIn, c.hub.game.Config.TimeBank)
 
-	log.Printf("[WS] 用户落座成功 - %s, 座位: %d\n", c.user, int(seatId))
+	log.Printf("[WS] 用户落座成功 - %s, 座位: %d, 买入: %d\n", c.user, req.SeatID, buyIn)
 
 	// 广播游戏状态更新
 	c.hub.broadcastGameState()
+	return nil
 }
 
-// handleLeaveSeat 处理离开座位请求
-func (c *Client) handleLeaveSeat(data interface{}) {
-	// 检查游戏状态，只有在等待状态或摊牌阶段才允许离座
-	if c.hub.game.GameStatus != "waiting" && c.hub.game.GamePhase != "showdown" {
-		log.Printf("[WS] 游戏进行中不允许离座 - %s\n", c.user)
-
-		// 发送错误消息给客户端
-		errorMsg := WSMessage{
-			Type: MSG_ERROR,
-			Data: ErrorData{
-				Message: "游戏进行中不能离座",
-			},
-		}
-
-		messageBytes, err := json.Marshal(errorMsg)
-		if err != nil {
-			log.Printf("[WS] 序列化错误消息失败 - %s, 错误: %v\n", c.user, err)
-			return
-		}
-
-		select {
-		case c.send <- messageBytes:
-			log.Printf("[WS] 错误消息已发送 - %s\n", c.user)
-		default:
-			log.Printf("[WS] 发送错误消息失败，通道已满 - %s\n", c.user)
-		}
-		return
+// handleLeaveSeat 处理离开座位请求，只有在等待状态或摊牌阶段才允许离座
+func (c *Client) handleLeaveSeat(req LeaveSeatRequest) error {
+	if !c.betweenHands() {
+		return requestError(i18n.ErrGameInProgress)
 	}
 
-	// 解析座位ID
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		log.Printf("[WS] 离开座位数据格式错误 - %s\n", c.user)
-		return
-	}
-
-	seatIdFloat, ok := dataMap["seatId"].(float64)
-	if !ok {
-		log.Printf("[WS] 座位ID格式错误 - %s\n", c.user)
-		return
-	}
-
-	seatId := int(seatIdFloat)
-	log.Printf("[WS] 处理离开座位请求 - %s, 座位: %d\n", c.user, seatId)
-
-	// 检查座位索引是否有效
-	if seatId < 1 || seatId > len(c.hub.game.Players) {
-		log.Printf("[WS] 无效的座位ID - %s, 座位: %d\n", c.user, seatId)
-		return
+	seatIndex, err := c.seatIndex(req.SeatID)
+	if err != nil {
+		return err
 	}
 
-	// 转换为数组索引（座位1对应索引0）
-	seatIndex := seatId - 1
-	player := &c.hub.game.Players[seatIndex]
-
 	// 检查该座位是否是当前用户占用的
+	player := &c.hub.game.Players[seatIndex]
 	if player.IsEmpty() || player.UserId != c.user.ID {
-		log.Printf("[WS] 座位不是当前用户占用 - %s, 座位: %d, 座位用户: %s\n", c.user, seatId, player.UserId)
-		return
+		return requestError(i18n.ErrNotYourSeat, req.SeatID)
 	}
 
-	// 重置座位
-	player.Reset()
-	log.Printf("[WS] 玩家离开座位成功 - %s, 座位: %d\n", c.user, seatId)
+	// 兑现筹码并重置座位
+	c.hub.vacateSeat(player)
+	log.Printf("[WS] 玩家离开座位成功 - %s, 座位: %d\n", c.user, req.SeatID)
 
 	// 广播游戏状态更新
 	c.hub.broadcastGameState()
+	return nil
 }
 
-// handleStartGame 处理开始游戏请求
-func (c *Client) handleStartGame() {
+// handleStartGame 处理开始游戏请求，只有房主可以开始游戏
+func (c *Client) handleStartGame() error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+
 	// 检查游戏是否可以开始
 	if !c.hub.game.CanStartGame() {
 		log.Printf("[WS] 游戏无法开始 - %s, 当前状态: %s, 玩家数: %d\n",
 			c.user, c.hub.game.GameStatus, c.hub.game.GetSittingPlayersCount())
-
-		// 发送错误消息给客户端
-		errorMsg := WSMessage{
-			Type: MSG_ERROR,
-			Data: ErrorData{
-				Message: "游戏无法开始，需要至少2个玩家",
-			},
-		}
-
-		messageBytes, err := json.Marshal(errorMsg)
-		if err != nil {
-			log.Printf("[WS] 序列化错误消息失败 - %s, 错误: %v\n", c.user, err)
-			return
-		}
-
-		select {
-		case c.send <- messageBytes:
-			log.Printf("[WS] 错误消息已发送 - %s\n", c.user)
-		default:
-			log.Printf("[WS] 发送错误消息失败，通道已满 - %s\n", c.user)
-		}
-		return
+		return requestError(i18n.ErrCannotStart, c.hub.game.Config.MinPlayers)
 	}
 
 	// 开始游戏
-	if c.hub.game.StartGame() {
-		log.Printf("[WS] 游戏开始成功 - %s, 玩家数: %d\n",
-			c.user, c.hub.game.GetSittingPlayersCount())
-
-		// 广播游戏状态更新
-		c.hub.broadcastGameState()
-	} else {
-		log.Printf("[WS] 游戏开始失败 - %s\n", c.user)
+	if !c.hub.game.StartGame() {
+		return requestError(i18n.ErrCannotStart, c.hub.game.Config.MinPlayers)
 	}
+	log.Printf("[WS] 游戏开始成功 - %s, 玩家数: %d\n",
+		c.user, c.hub.game.GetSittingPlayersCount())
+	c.hub.removeAwayPlayers()
+
+	// 广播游戏状态更新
+	c.hub.broadcastGameState()
+	return nil
 }
 
 // handlePlayerAction 处理玩家行动消息
-func (c *Client) handlePlayerAction(action string, data interface{}) {
+func (c *Client) handlePlayerAction(action string, req ActionRequest) error {
 	log.Printf("[WS] 开始处理玩家行动 - 玩家: %s, 行动: %s\n", c.user, action)
 
-	// 解析行动数据
-	amount := 0
-	if data != nil {
-		if dataMap, ok := data.(map[string]interface{}); ok {
-			if amountFloat, ok := dataMap["amount"].(float64); ok {
-				amount = int(amountFloat)
-			}
-		}
-	}
-
-	// 检查游戏状态
-	log.Printf("[WS] 当前游戏状态: %s", c.hub.game.GameStatus)
-	if c.hub.game.GameStatus != "playing" {
-		c.sendError("游戏未开始")
-		return
-	}
-
-	// 找到玩家位置
-	playerPos := -1
-	for i, player := range c.hub.game.Players {
-		if player.UserId == c.user.ID && !player.IsEmpty() {
-			playerPos = i
-			break
-		}
-	}
-
-	log.Printf("[WS] 玩家位置: %d, 当前行动玩家: %d", playerPos+1, c.hub.game.CurrentPlayer+1)
-
-	if playerPos == -1 {
-		c.sendError("您未在游戏中")
-		return
-	}
-
-	// 检查是否轮到该玩家行动
-	if c.hub.game.CurrentPlayer != playerPos {
-		c.sendError("还没轮到您行动")
-		return
-	}
-
-	player := &c.hub.game.Players[playerPos]
-	log.Printf("[WS] 玩家状态: %s, HasActed: %v", player.Status, player.HasActed)
-
-	// 检查玩家是否可以行动
-	if !player.CanAct() {
-		log.Printf("[WS] 玩家无法行动 - 状态: %s, HasActed: %v", player.Status, player.HasActed)
-		c.sendError("您当前无法行动")
-		return
-	}
-
-	// 验证具体行动
-	var errorMsg string
-	switch action {
-	case "fold":
-		// 弃牌不需要验证
-		log.Printf("[游戏] 玩家 %s (座位%d) 准备弃牌", player.Name, playerPos+1)
-	case "call":
-		callAmount := c.hub.game.CurrentBet - player.CurrentBet
-		if callAmount > player.Chips {
-			errorMsg = "筹码不足以跟注"
-		}
-	case "check":
-		if c.hub.game.CurrentBet != player.CurrentBet {
-			errorMsg = "有人下注，无法过牌"
-		}
-	case "raise":
-		minRaise := c.hub.game.CurrentBet + c.hub.game.BigBlind
-		if amount < minRaise {
-			errorMsg = fmt.Sprintf("加注金额至少需要 %d", minRaise)
-		} else {
-			raiseAmount := amount - player.CurrentBet
-			if raiseAmount > player.Chips {
-				errorMsg = "筹码不足以加注"
-			}
-		}
-	default:
-		errorMsg = "无效的行动"
-	}
-
-	if errorMsg != "" {
-		c.sendError(errorMsg)
-		return
+	// 由游戏引擎统一校验行动是否合法
+	if err := c.hub.game.ValidateAction(c.user.ID, action, req.Amount); err != nil {
+		return err
 	}
 
 	// 调用游戏逻辑处理玩家行动
-	log.Printf("[WS] 准备调用游戏逻辑 - 玩家: %s, 行动: %s", player.Name, action)
-	success := c.hub.game.PlayerAction(c.user.ID, action, amount)
-
-	if !success {
-		log.Printf("[WS] 玩家行动失败 - %s, 行动: %s\n", c.user, action)
-		c.sendError("行动失败，请重试")
-		return
+	if !c.hub.game.PlayerAction(c.user.ID, action, req.Amount) {
+		return requestError(i18n.ErrActionFailed)
 	}
 
-	// 记录成功的行动
-	if action == "fold" {
-		log.Printf("[游戏] 玩家 %s (座位%d) 弃牌成功", player.Name, playerPos+1)
-	} else {
-		log.Printf("[WS] 玩家行动成功 - %s, 行动: %s, 金额: %d\n", c.user, action, amount)
-	}
+	log.Printf("[WS] 玩家行动成功 - %s, 行动: %s, 金额: %d\n", c.user, action, req.Amount)
+
+	// 这次行动可能结束了本局
+	c.hub.readyDisconnectedPlayers()
 
 	// 广播游戏状态更新
 	c.hub.broadcastGameState()
+	return nil
 }
 
-// sendError 发送错误消息的辅助方法
-func (c *Client) sendError(message string) {
-	errorMsg := WSMessage{
-		Type: MSG_ERROR,
-		Data: ErrorData{
-			Message: message,
-		},
-	}
-
-	messageBytes, err := json.Marshal(errorMsg)
-	if err != nil {
-		log.Printf("[WS] 序列化错误消息失败 - %s, 错误: %v\n", c.user, err)
-		return
-	}
-
-	select {
-	case c.send <- messageBytes:
-		log.Printf("[WS] 错误消息已发送 - %s: %s\n", c.user, message)
-	default:
-		log.Printf("[WS] 发送错误消息失败，通道已满 - %s\n", c.user)
+// handleEndGame 处理结束游戏请求，只有房主可以结束游戏
+func (c *Client) handleEndGame() error {
+	if err := c.requireHost(); err != nil {
+		return err
 	}
-}
 
-// handleEndGame 处理结束游戏请求
-func (c *Client) handleEndGame() {
 	// 检查游戏状态，只有在等待状态或摊牌阶段才能结束游戏
-	if c.hub.game.GameStatus == "playing" && c.hub.game.GamePhase != "showdown" {
-		log.Printf("[WS] 游戏进行中不允许结束游戏 - %s\n", c.user)
-		c.sendError("游戏进行中不能结束游戏")
-		return
+	if !c.betweenHands() {
+		return requestError(i18n.ErrGameInProgress)
 	}
 
 	// 结束游戏
@@ -514,62 +341,84 @@ func (c *Client) handleEndGame() {
 
 	// 广播游戏状态更新
 	c.hub.broadcastGameState()
+	return nil
 }
 
 // handleReady 处理玩家准备
-func (c *Client) handleReady() {
-	// 先检查玩家是否有筹码
-	for _, player := range c.hub.game.Players {
-		if player.UserId == c.user.ID && !player.IsEmpty() {
-			if player.Chips <= 0 {
-				c.sendError("筹码不足，请离开座位重新落座")
-				return
-			}
-			break
-		}
+func (c *Client) handleReady() error {
+	// 先检查玩家是否落座、是否有筹码
+	player, _ := c.hub.seatOf(c.user.ID)
+	if player == nil {
+		return requestError(i18n.ErrNotSeated)
+	}
+	if player.Chips <= 0 {
+		return requestError(i18n.ErrOutOfChips)
+	}
+	if player.SittingOut {
+		return requestError(i18n.ErrSittingOut)
 	}
 
-	if c.hub.game.SetPlayerReady(c.user.ID, true) {
-		log.Printf("[WS] 玩家准备成功 - %s", c.user)
+	if !c.hub.game.SetPlayerReady(c.user.ID, true) {
+		return requestError(i18n.ErrCannotReady)
+	}
+	log.Printf("[WS] 玩家准备成功 - %s", c.user)
 
-		// 获取当前准备的玩家数量
-		readyCount, totalCount := c.hub.game.GetReadyPlayersCount()
-		log.Printf("[WS] 当前准备状态：总玩家数=%d，已准备玩家数=%d", totalCount, readyCount)
+	// 获取当前准备的玩家数量
+	readyCount, totalCount := c.hub.game.GetReadyPlayersCount()
+	log.Printf("[WS] 当前准备状态：总玩家数=%d，已准备玩家数=%d", totalCount, readyCount)
 
-		// 检查是否所有玩家都已准备，且至少有2个玩家
-		if totalCount >= 2 && readyCount == totalCount {
-			log.Printf("[WS] 所有玩家已准备，开始倒计时")
-			// 如果在摊牌阶段，直接开始倒计时
-			if c.hub.game.GamePhase == "showdown" {
+	// 检查是否所有玩家都已准备，且达到最少玩家数
+	if totalCount >= c.hub.game.Config.MinPlayers && readyCount == totalCount {
+		log.Printf("[WS] 所有玩家已准备，开始倒计时")
+		// 如果在摊牌阶段，直接开始倒计时
+		if c.hub.game.GamePhase == "showdown" {
+			c.hub.startCountdown()
+		} else {
+			// 否则检查是否可以开始游戏
+			if c.hub.game.CanStartGame() {
 				c.hub.startCountdown()
 			} else {
-				// 否则检查是否可以开始游戏
-				if c.hub.game.CanStartGame() {
-					c.hub.startCountdown()
-				} else {
-					log.Printf("[WS] 无法开始倒计时 - 不满足开始游戏条件")
-				}
+				log.Printf("[WS] 无法开始倒计时 - 不满足开始游戏条件")
 			}
 		}
+	}
+
+	// 广播游戏状态更新
+	c.hub.broadcastGameState()
+	return nil
+}
 
-		// 广播游戏状态更新
-		c.hub.broadcastGameState()
+// handleSitOut 处理玩家暂时离开或回到座位
+// 暂时离开的玩家不再阻止开局，其余玩家都已准备时开始倒计时；回来的玩家需要重新准备
+func (c *Client) handleSitOut(away bool) error {
+	if err := c.hub.game.SetSittingOut(c.user.ID, away); err != nil {
+		return err
+	}
+	log.Printf("[WS] 玩家%s - %s", map[bool]string{true: "暂时离开", false: "回到座位"}[away], c.user)
+
+	if away {
+		if c.hub.game.CanStartGame() && c.hub.countdownTimer == nil {
+			c.hub.startCountdown()
+		}
 	} else {
-		c.sendError("无法设置准备状态")
+		c.hub.cancelCountdown()
 	}
+
+	c.hub.broadcastGameState()
+	return nil
 }
 
 // handleUnready 处理玩家取消准备
-func (c *Client) handleUnready() {
-	if c.hub.game.SetPlayerReady(c.user.ID, false) {
-		log.Printf("[WS] 玩家取消准备成功 - %s", c.user)
+func (c *Client) handleUnready() error {
+	if !c.hub.game.SetPlayerReady(c.user.ID, false) {
+		return requestError(i18n.ErrCannotUnready)
+	}
+	log.Printf("[WS] 玩家取消准备成功 - %s", c.user)
 
-		// 取消倒计时（如果正在倒计时）
-		c.hub.cancelCountdown()
+	// 取消倒计时（如果正在倒计时）
+	c.hub.cancelCountdown()
 
-		// 广播游戏状态更新
-		c.hub.broadcastGameState()
-	} else {
-		c.sendError("无法取消准备状态")
-	}
+	// 广播游戏状态更新
+	c.hub.broadcastGameState()
+	return nil
 }
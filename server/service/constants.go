@@ -7,4 +7,5 @@ var (
 	dataDir    = "data"                               // 数据根目录
 	dataFile   = filepath.Join(dataDir, "users.json") // 用户数据文件
 	avatarsDir = filepath.Join(dataDir, "avatars")    // 头像存储目录
+	secretFile = filepath.Join(dataDir, "token.key")  // 会话令牌签名密钥
 )
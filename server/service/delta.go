@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// 客户端协议版本，连接时通过 ?protocol= 指定，默认为 ProtocolSnapshot
+const (
+	ProtocolSnapshot = 1 // 每次变化都发送完整的游戏状态
+	ProtocolDelta    = 2 // 加入时发送带序号的完整状态，之后只发送变化的事件
+
+	ProtocolLatest = ProtocolDelta // 服务器支持的最高版本
+)
+
+// 增量更新的事件类型
+const (
+	EventPlayerActed   = "player_acted"   // 玩家行动，数据为新增的一条行动记录
+	EventCardDealt     = "card_dealt"     // 发出公共牌，数据为新增的公共牌
+	EventPotUpdated    = "pot_updated"    // 底池变化
+	EventPlayerUpdated = "player_updated" // 座位上玩家的字段变化
+	EventStateChanged  = "state_changed"  // 其余游戏字段变化
+	EventTableUpdated  = "table_updated"  // 牌桌信息变化
+)
+
+// gameView 发送给一个客户端的游戏状态，按字段序列化以便比较出变化
+type gameView struct {
+	fields  map[string]json.RawMessage // 除 players 外的顶层字段
+	players []json.RawMessage          // 每个座位的玩家
+	table   TableStateData
+}
+
+// publicView 序列化隐藏了所有手牌的游戏状态，一次广播只需序列化一次
+// 摊牌阶段不隐藏手牌
+func (h *Hub) publicView() *gameView {
+	gameCopy := *h.game
+	gameCopy.Players = make([]poker.Player, len(h.game.Players))
+	for i, player := range h.game.Players {
+		gameCopy.Players[i] = player
+		if h.hidesHoleCards() {
+			// 保留手牌数量但不显示内容
+			gameCopy.Players[i].HoleCards = make([]poker.Card, len(player.HoleCards))
+		}
+	}
+
+	data, err := json.Marshal(&gameCopy)
+	if err != nil {
+		log.Printf("[Hub] 序列化游戏状态失败 - 牌桌: %s, 错误: %v", h.id, err)
+		return &gameView{fields: map[string]json.RawMessage{}}
+	}
+
+	view := &gameView{}
+	json.Unmarshal(data, &view.fields)
+	json.Unmarshal(view.fields["players"], &view.players)
+	delete(view.fields, "players")
+	return view
+}
+
+// hidesHoleCards 非摊牌阶段只能看到自己的手牌
+func (h *Hub) hidesHoleCards() bool {
+	return h.game.GamePhase != poker.GamePhaseShowdown && h.game.GamePhase != "showdown_reveal"
+}
+
+// forClient 在公共状态上补充客户端自己的手牌和可见的牌桌信息
+func (v *gameView) forClient(c *Client) *gameView {
+	h := c.hub
+	view := &gameView{
+		fields:  v.fields,
+		players: v.players,
+		table: TableStateData{
+			ID:          h.id,
+			Name:        h.game.Config.Name,
+			HostID:      h.hostID,
+			Private:     h.private,
+			HasPassword: h.passwordHash != "",
+		},
+	}
+	if h.isHost(c.user.ID) {
+		view.table.InviteCode = h.inviteCode
+	}
+
+	if player, pos := h.seatOf(c.user.ID); player != nil && !c.detached && h.hidesHoleCards() && pos < len(v.players) {
+		if data, err := json.Marshal(player); err == nil {
+			view.players = append([]json.RawMessage(nil), v.players...)
+			view.players[pos] = data
+		}
+	}
+	return view
+}
+
+// gameJSON 组装完整的游戏状态
+func (v *gameView) gameJSON() json.RawMessage {
+	fields := make(map[string]json.RawMessage, len(v.fields)+1)
+	for key, value := range v.fields {
+		fields[key] = value
+	}
+	players, _ := json.Marshal(v.players)
+	fields["players"] = players
+	data, _ := json.Marshal(fields)
+	return data
+}
+
+// sendView 发送游戏状态的更新
+// 使用增量协议且已发送过完整状态的客户端只收到变化的事件，没有变化时不发送
+func (c *Client) sendView(public *gameView) {
+	view := public.forClient(c)
+	if c.protocol != ProtocolDelta || c.lastView == nil {
+		c.sendSnapshot(view)
+		return
+	}
+
+	events := diffViews(c.lastView, view)
+	if len(events) == 0 {
+		return
+	}
+	c.seq++
+	c.lastView = view
+	c.sendMessage(WSMessage{Type: MSG_GAME_DELTA, Data: GameDeltaData{Seq: c.seq, Events: events}})
+}
+
+// sendSnapshot 发送完整的游戏状态，增量协议的客户端以此作为之后增量的基准
+func (c *Client) sendSnapshot(view *gameView) {
+	data := GameStateData{Game: view.gameJSON(), Table: view.table}
+	if c.protocol == ProtocolDelta {
+		c.seq++
+		c.lastView = view
+		data.Seq = c.seq
+	}
+	c.sendMessage(WSMessage{Type: MSG_GAME_STATE, Data: data})
+}
+
+// sendMessage 序列化并发送消息，通道已满时丢弃
+// 增量协议的客户端丢失消息后会发现序号不连续并请求重新同步
+func (c *Client) sendMessage(message WSMessage) {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("[WS] 序列化消息失败 - %s, 类型: %s, 错误: %v\n", c.user, message.Type, err)
+		return
+	}
+
+	// 使用defer和recover来捕获panic
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[WS] 发送消息时发生panic - %s, 错误: %v\n", c.user, r)
+		}
+	}()
+
+	select {
+	case c.send <- messageBytes:
+	default:
+		log.Printf("[WS] 发送消息失败，通道已满或已关闭 - %s, 类型: %s\n", c.user, message.Type)
+	}
+}
+
+// handleResync 客户端发现增量序号不连续时请求重新发送完整状态
+func (c *Client) handleResync() {
+	log.Printf("[WS] 重新同步游戏状态 - %s, 序号: %d", c.user, c.seq)
+	c.lastView = nil
+	c.sendGameState()
+}
+
+// diffViews 比较两次发送的游戏状态，生成变化的事件
+// 行动记录和公共牌只增加时发送新增的部分，其余变化按字段发送，被省略的字段以 null 表示
+func diffViews(prev, next *gameView) []GameEvent {
+	events := make([]GameEvent, 0)
+	changed := changedFields(prev.fields, next.fields)
+
+	if value, ok := changed["actions"]; ok {
+		if added, ok := appendedItems(prev.fields["actions"], value); ok {
+			for _, action := range added {
+				events = append(events, GameEvent{Type: EventPlayerActed, Data: action})
+			}
+			delete(changed, "actions")
+		}
+	}
+	if value, ok := changed["communityCards"]; ok {
+		if added, ok := appendedItems(prev.fields["communityCards"], value); ok {
+			events = append(events, GameEvent{Type: EventCardDealt, Data: CardDealtData{Cards: added}})
+			delete(changed, "communityCards")
+		}
+	}
+
+	_, potChanged := changed["pot"]
+	_, potsChanged := changed["pots"]
+	if potChanged || potsChanged {
+		events = append(events, GameEvent{Type: EventPotUpdated, Data: PotUpdatedData{
+			Pot:  next.fields["pot"],
+			Pots: next.fields["pots"],
+		}})
+		delete(changed, "pot")
+		delete(changed, "pots")
+	}
+
+	for seat := range next.players {
+		if seat < len(prev.players) && bytes.Equal(prev.players[seat], next.players[seat]) {
+			continue
+		}
+		var before, after map[string]json.RawMessage
+		if seat < len(prev.players) {
+			json.Unmarshal(prev.players[seat], &before)
+		}
+		json.Unmarshal(next.players[seat], &after)
+		events = append(events, GameEvent{Type: EventPlayerUpdated, Data: PlayerUpdatedData{
+			Seat:   seat,
+			Fields: changedFields(before, after),
+		}})
+	}
+
+	if len(changed) > 0 {
+		events = append(events, GameEvent{Type: EventStateChanged, Data: StateChangedData{Fields: changed}})
+	}
+	if prev.table != next.table {
+		events = append(events, GameEvent{Type: EventTableUpdated, Data: next.table})
+	}
+	return events
+}
+
+// changedFields 返回 next 中与 prev 不同的字段，prev 中有而 next 中没有的字段为 null
+func changedFields(prev, next map[string]json.RawMessage) map[string]json.RawMessage {
+	changed := make(map[string]json.RawMessage)
+	for key, value := range next {
+		if !bytes.Equal(prev[key], value) {
+			changed[key] = value
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			changed[key] = json.RawMessage("null")
+		}
+	}
+	return changed
+}
+
+// appendedItems 比较两个 JSON 数组，next 只是在 prev 后面追加了元素时返回追加的元素
+func appendedItems(prev, next json.RawMessage) ([]json.RawMessage, bool) {
+	var before, after []json.RawMessage
+	if err := json.Unmarshal(prev, &before); err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(next, &after); err != nil {
+		return nil, false
+	}
+	if len(after) <= len(before) {
+		return nil, false
+	}
+	for i := range before {
+		if !bytes.Equal(before[i], after[i]) {
+			return nil, false
+		}
+	}
+	return after[len(before):], true
+}
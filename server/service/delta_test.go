@@ -0,0 +1,194 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testView 由 JSON 构造游戏状态，fields 为除 players 外的顶层字段
+func testView(t *testing.T, fields string, tableName string, players ...string) *gameView {
+	t.Helper()
+	view := &gameView{table: TableStateData{Name: tableName}}
+	if err := json.Unmarshal([]byte(fields), &view.fields); err != nil {
+		t.Fatalf("解析字段失败: %v", err)
+	}
+	for _, player := range players {
+		view.players = append(view.players, json.RawMessage(player))
+	}
+	return view
+}
+
+func TestDiffViews(t *testing.T) {
+	type event struct {
+		typ  string
+		data string // 事件数据的 JSON，为空时不比较
+	}
+	tests := []struct {
+		name       string
+		prev, next *gameView
+		want       []event
+	}{
+		{
+			name: "没有变化",
+			prev: testView(t, `{"pot":30}`, "a", `{"chips":500}`),
+			next: testView(t, `{"pot":30}`, "a", `{"chips":500}`),
+		},
+		{
+			name: "新增行动",
+			prev: testView(t, `{"actions":[{"type":"call"}]}`, "a"),
+			next: testView(t, `{"actions":[{"type":"call"},{"type":"fold"}]}`, "a"),
+			want: []event{{EventPlayerActed, `{"type":"fold"}`}},
+		},
+		{
+			name: "发出公共牌",
+			prev: testView(t, `{"communityCards":[]}`, "a"),
+			next: testView(t, `{"communityCards":[{"rank":"A"},{"rank":"K"},{"rank":"2"}]}`, "a"),
+			want: []event{{EventCardDealt, `{"cards":[{"rank":"A"},{"rank":"K"},{"rank":"2"}]}`}},
+		},
+		{
+			name: "底池变化",
+			prev: testView(t, `{"pot":30,"pots":null}`, "a"),
+			next: testView(t, `{"pot":90,"pots":null}`, "a"),
+			want: []event{{EventPotUpdated, `{"pot":90,"pots":null}`}},
+		},
+		{
+			// 新的一局清空行动记录不是追加，按字段发送
+			name: "行动记录清空",
+			prev: testView(t, `{"actions":[{"type":"call"}],"gamePhase":"showdown"}`, "a"),
+			next: testView(t, `{"actions":[],"gamePhase":"preflop"}`, "a"),
+			want: []event{{EventStateChanged, `{"fields":{"actions":[],"gamePhase":"preflop"}}`}},
+		},
+		{
+			name: "省略的字段",
+			prev: testView(t, `{"currentRound":{"roundId":"t-00000001"}}`, "a"),
+			next: testView(t, `{}`, "a"),
+			want: []event{{EventStateChanged, `{"fields":{"currentRound":null}}`}},
+		},
+		{
+			name: "只发送玩家变化的字段",
+			prev: testView(t, `{}`, "a", `{"name":"A","chips":500}`, `{"name":"B","chips":500}`),
+			next: testView(t, `{}`, "a", `{"name":"A","chips":480}`, `{"name":"B","chips":500}`),
+			want: []event{{EventPlayerUpdated, `{"seat":0,"fields":{"chips":480}}`}},
+		},
+		{
+			name: "牌桌信息变化",
+			prev: testView(t, `{}`, "a"),
+			next: testView(t, `{}`, "b"),
+			want: []event{{EventTableUpdated, ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := diffViews(tt.prev, tt.next)
+			if len(events) != len(tt.want) {
+				t.Fatalf("事件 = %+v, 期望 %d 个", events, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if events[i].Type != want.typ {
+					t.Errorf("第%d个事件类型 = %s, 期望 %s", i+1, events[i].Type, want.typ)
+				}
+				if want.data == "" {
+					continue
+				}
+				data, err := json.Marshal(events[i].Data)
+				if err != nil {
+					t.Fatalf("序列化事件失败: %v", err)
+				}
+				if string(data) != want.data {
+					t.Errorf("第%d个事件数据 = %s, 期望 %s", i+1, data, want.data)
+				}
+			}
+		})
+	}
+}
+
+// readSeq 读取消息直到收到游戏状态或增量更新，返回消息类型和序号
+func readSeq(conn *websocket.Conn) (MessageType, int64, error) {
+	conn.SetReadDeadline(time.Now().Add(testWaitTimeout))
+	for {
+		var message testMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return "", 0, err
+		}
+		if message.Type != MSG_GAME_STATE && message.Type != MSG_GAME_DELTA {
+			continue
+		}
+		var data struct {
+			Seq int64 `json:"seq"`
+		}
+		if err := json.Unmarshal(message.Data, &data); err != nil {
+			return "", 0, err
+		}
+		return message.Type, data.Seq, nil
+	}
+}
+
+func TestDeltaProtocol(t *testing.T) {
+	table := newTestTable(t)
+	spectator := CreateGuestUser("10.0.0.1", "holdem-test")
+	url := fmt.Sprintf("ws%s/ws/%s?token=%s&protocol=%d",
+		strings.TrimPrefix(table.server.URL, "http"), table.hub.id, IssueToken(spectator.ID), ProtocolDelta)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	// sitDown 让一个新玩家落座，产生一次状态变化
+	sitDown := func(seat int) {
+		t.Helper()
+		user := CreateGuestUser(fmt.Sprintf("10.0.1.%d", seat), "holdem-test")
+		client, err := table.dial(user, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.conn.Close() })
+		if err := client.mustSucceed(MSG_SIT_DOWN, SitDownRequest{SeatID: seat}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 加入时收到序号为 1 的完整状态，之后的变化是序号连续的增量，重新同步时发送完整状态并继续递增
+	steps := []struct {
+		name string
+		run  func()
+		want MessageType
+	}{
+		{name: "加入", run: func() {}, want: MSG_GAME_STATE},
+		{name: "玩家落座", run: func() { sitDown(1) }, want: MSG_GAME_DELTA},
+		{name: "请求重新同步", run: func() {
+			if err := conn.WriteJSON(ClientMessage{Type: MSG_RESYNC, ID: "resync-1"}); err != nil {
+				t.Fatalf("发送重新同步请求失败: %v", err)
+			}
+		}, want: MSG_GAME_STATE},
+		{name: "重新同步后的变化", run: func() { sitDown(2) }, want: MSG_GAME_DELTA},
+	}
+
+	var seq int64
+	for _, step := range steps {
+		step.run()
+		// 观众加入等变化也会产生增量，读到期望的消息类型为止，序号必须连续
+		for {
+			messageType, got, err := readSeq(conn)
+			if err != nil {
+				t.Fatalf("%s: 读取消息失败: %v", step.name, err)
+			}
+			if got != seq+1 {
+				t.Fatalf("%s: 序号 = %d, 期望 %d", step.name, got, seq+1)
+			}
+			seq = got
+			if messageType == step.want {
+				break
+			}
+			if messageType == MSG_GAME_STATE {
+				t.Fatalf("%s: 没有请求时收到了完整状态", step.name)
+			}
+		}
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/i18n"
 	"github.com/lllllan02/holdem/poker"
 )
 
@@ -16,6 +18,18 @@ type UpdateNameRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
+// UpdateLanguageRequest 更新语言偏好请求，语言为 zh 或 en
+type UpdateLanguageRequest struct {
+	Language string `json:"language" binding:"required"`
+}
+
+// CreateTableRequest 创建牌桌请求，牌桌配置字段与 poker.TableConfig 相同
+type CreateTableRequest struct {
+	poker.TableConfig
+	Private  bool   `json:"private"`  // 是否为私人牌桌
+	Password string `json:"password"` // 加入密码（可选）
+}
+
 type GetGameRecordsRequest struct {
 	Days  int `form:"days"`  // 查询最近几天的记录
 	Limit int `form:"limit"` // 限制返回的记录数量
@@ -23,11 +37,7 @@ type GetGameRecordsRequest struct {
 
 // GetUserHandler 获取用户信息的处理函数
 func GetUserHandler(c *gin.Context) {
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	log.Printf("[API] GetUser - IP: %s, UserAgent: %s", ip, userAgent)
-
-	user := GetOrCreateUser(ip, userAgent)
+	user := currentUser(c)
 	log.Printf("[API] GetUser - 用户: %s", user)
 	c.JSON(200, user)
 }
@@ -40,13 +50,7 @@ func UpdateUserNameHandler(c *gin.Context) {
 		return
 	}
 
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	log.Printf("[API] UpdateUserName - IP: %s, UserAgent: %s", ip, userAgent)
-
-	// 使用相同的方式获取用户
-	id := GetUserID(ip, userAgent)
-	user, err := UpdateUserName(id, req.Name)
+	user, err := UpdateUserName(currentUser(c).ID, req.Name)
 	if err != nil {
 		c.JSON(404, gin.H{"error": err.Error()})
 		return
@@ -56,15 +60,34 @@ func UpdateUserNameHandler(c *gin.Context) {
 	c.JSON(200, user)
 }
 
+// UpdateUserLanguageHandler 更新用户语言偏好的处理函数
+func UpdateUserLanguageHandler(c *gin.Context) {
+	var req UpdateLanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request"})
+		return
+	}
+	lang, ok := i18n.Supported(req.Language)
+	if !ok {
+		c.JSON(400, gin.H{"error": "Unsupported language"})
+		return
+	}
+
+	user, err := UpdateUserLanguage(currentUser(c).ID, string(lang))
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[API] UpdateUserLanguage - 用户: %s, 语言: %s", user, user.Language)
+	c.JSON(200, user)
+}
+
 // UpdateUserAvatarHandler 更新用户头像的处理函数
 func UpdateUserAvatarHandler(c *gin.Context) {
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	log.Printf("[API] UpdateUserAvatar - IP: %s, UserAgent: %s", ip, userAgent)
-
-	// 获取用户ID
-	id := GetUserID(ip, userAgent)
-	log.Printf("[API] UpdateUserAvatar - 用户ID: %s", id)
+	user := currentUser(c)
+	id := user.ID
+	log.Printf("[API] UpdateUserAvatar - 用户: %s", user)
 
 	// 获取上传的文件
 	file, err := c.FormFile("avatar")
@@ -89,8 +112,6 @@ func UpdateUserAvatarHandler(c *gin.Context) {
 		return
 	}
 
-	// 获取用户信息
-	user := GetOrCreateUser(ip, userAgent)
 	c.JSON(200, user)
 }
 
@@ -192,9 +213,259 @@ func GetGameRecordsHandler(c *gin.Context) {
 		return
 	}
 
+	records = visibleRecords(records, viewerID(c))
+
 	log.Printf("[API] GetGameRecords - 获取到 %d 条记录", len(records))
 	c.JSON(200, gin.H{
 		"total":   len(records),
 		"records": records,
 	})
 }
+
+// viewerID 返回请求中令牌对应的用户ID，未登录时为空
+func viewerID(c *gin.Context) string {
+	if user := optionalUser(c); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// visibleRecords 过滤掉不能对用户公开的私人牌桌对局
+func visibleRecords(records []*poker.GameRound, userID string) []*poker.GameRound {
+	visible := records[:0]
+	for _, record := range records {
+		if record.VisibleTo(userID) {
+			visible = append(visible, record)
+		}
+	}
+	return visible
+}
+
+// findVisibleRecord 查找能对用户公开的对局记录，私人牌桌的对局对其他人视为不存在
+func findVisibleRecord(roundID, userID string) (*poker.GameRound, error) {
+	record, err := poker.FindGameRecord(roundID)
+	if err != nil {
+		return nil, err
+	}
+	if !record.VisibleTo(userID) {
+		return nil, fmt.Errorf("对局记录不存在: %s", roundID)
+	}
+	return record, nil
+}
+
+// GetReplayHandler 获取对局回放的处理函数
+// 回放使用游戏引擎按记录重新进行一局，请求带有令牌时可以看到自己的手牌
+func GetReplayHandler(c *gin.Context) {
+	roundID := c.Param("roundId")
+	viewer := viewerID(c)
+	record, err := findVisibleRecord(roundID, viewer)
+	if err != nil {
+		log.Printf("[API] GetReplay - 查找记录失败: %v", err)
+		c.JSON(404, gin.H{"error": "Record not found"})
+		return
+	}
+
+	replay, err := poker.ReplayRound(record, viewer)
+	if err != nil {
+		log.Printf("[API] GetReplay - 回放失败: %s, 原因: %v", roundID, err)
+		c.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, replay)
+}
+
+// GetRoundChatHandler 获取对局期间以及结束后到下一局开始前的聊天消息，用于核查争议
+func GetRoundChatHandler(c *gin.Context) {
+	roundID := c.Param("roundId")
+	if _, err := findVisibleRecord(roundID, viewerID(c)); err != nil {
+		log.Printf("[API] GetRoundChat - 查找记录失败: %v", err)
+		c.JSON(404, gin.H{"error": "Record not found"})
+		return
+	}
+
+	messages, err := poker.GetRoundChat(roundID)
+	if err != nil {
+		log.Printf("[API] GetRoundChat - 获取聊天消息失败: %s, 原因: %v", roundID, err)
+		c.JSON(500, gin.H{"error": "Failed to get chat messages"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"total":    len(messages),
+		"messages": messages,
+	})
+}
+
+// ExportGameRecordHandler 以 PokerStars 格式导出单局手牌历史，路径为 /game/records/:roundId.txt
+// 请求带有令牌时输出自己的手牌
+func ExportGameRecordHandler(c *gin.Context) {
+	roundID, ok := strings.CutSuffix(c.Param("roundId"), ".txt")
+	if !ok {
+		c.JSON(404, gin.H{"error": "Not found"})
+		return
+	}
+
+	heroID := viewerID(c)
+	record, err := findVisibleRecord(roundID, heroID)
+	if err != nil {
+		log.Printf("[API] ExportGameRecord - 查找记录失败: %v", err)
+		c.JSON(404, gin.H{"error": "Record not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.txt"`, roundID))
+	c.Data(200, "text/plain; charset=utf-8", []byte(poker.ExportPokerStars(record, heroID)))
+}
+
+// maxExportDays 批量导出的最大天数
+const maxExportDays = 31
+
+// ExportGameRecordsRequest 批量导出手牌历史的请求参数，日期格式为 2006-01-02
+type ExportGameRecordsRequest struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to"`
+}
+
+// ExportGameRecordsHandler 以 PokerStars 格式批量导出日期范围内的手牌历史，合并为一个文件
+func ExportGameRecordsHandler(c *gin.Context) {
+	var req ExportGameRecordsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request parameters"})
+		return
+	}
+	if req.To == "" {
+		req.To = req.From
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", req.From, time.Local)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid from date"})
+		return
+	}
+	to, err := time.ParseInLocation("2006-01-02", req.To, time.Local)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid to date"})
+		return
+	}
+	if to.Sub(from) > maxExportDays*24*time.Hour {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("一次最多导出 %d 天的记录", maxExportDays)})
+		return
+	}
+
+	records, err := poker.GetGameRecordsByDate(from, to)
+	if err != nil {
+		log.Printf("[API] ExportGameRecords - 获取记录失败: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	heroID := viewerID(c)
+	records = visibleRecords(records, heroID)
+
+	// 手牌之间用空行分隔，这是分析工具识别多手牌文件的方式
+	hands := make([]string, 0, len(records))
+	for _, record := range records {
+		hands = append(hands, poker.ExportPokerStars(record, heroID))
+	}
+
+	log.Printf("[API] ExportGameRecords - 导出 %s 至 %s 的 %d 条记录", req.From, req.To, len(records))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="holdem_%s_%s.txt"`, req.From, req.To))
+	c.Data(200, "text/plain; charset=utf-8", []byte(strings.Join(hands, "\n\n")))
+}
+
+// ListTablesHandler 获取大厅牌桌列表的处理函数
+func ListTablesHandler(c *gin.Context) {
+	infos := tables.List()
+	c.JSON(200, gin.H{
+		"total":  len(infos),
+		"tables": infos,
+	})
+}
+
+// GetTableHandler 获取单个牌桌信息的处理函数
+// 私人牌桌只对房主和加入过的用户返回，其他人需要通过邀请码查找
+func GetTableHandler(c *gin.Context) {
+	hub, ok := tables.Get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "Table not found"})
+		return
+	}
+
+	userID := viewerID(c)
+	allowed := false
+	hub.call(func() {
+		allowed = hub.canAccess(userID)
+	})
+	if !allowed {
+		c.JSON(404, gin.H{"error": "Table not found"})
+		return
+	}
+	c.JSON(200, hub.Info())
+}
+
+// CreateTableHandler 创建牌桌的处理函数
+// 请求体中未提供的配置项使用服务器的基础配置，创建者成为房主
+func CreateTableHandler(c *gin.Context) {
+	req := CreateTableRequest{TableConfig: tables.BaseConfig()}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user := currentUser(c)
+	hub, err := tables.Create(req.TableConfig, TableOptions{
+		HostID:   user.ID,
+		Private:  req.Private,
+		Password: req.Password,
+	})
+	if err != nil {
+		log.Printf("[API] CreateTable - 创建牌桌失败: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[API] CreateTable - 创建牌桌成功: %s, 房主: %s", hub.id, user)
+	info := hub.Info()
+	info.InviteCode = hub.inviteCode
+	c.JSON(200, info)
+}
+
+// GetTableByInviteCodeHandler 根据邀请码查找私人牌桌的处理函数
+func GetTableByInviteCodeHandler(c *gin.Context) {
+	hub, ok := tables.GetByInviteCode(c.Param("code"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "Table not found"})
+		return
+	}
+	c.JSON(200, hub.Info())
+}
+
+// CloseTableHandler 关闭牌桌的处理函数，只有房主可以关闭
+func CloseTableHandler(c *gin.Context) {
+	id := c.Param("id")
+	hub, ok := tables.Get(id)
+	if !ok {
+		c.JSON(404, gin.H{"error": "Table not found"})
+		return
+	}
+
+	user := currentUser(c)
+	isHost := false
+	hub.call(func() {
+		isHost = hub.isHost(user.ID)
+	})
+	if !isHost {
+		c.JSON(403, gin.H{"error": "只有房主可以关闭牌桌"})
+		return
+	}
+
+	if err := tables.Close(id); err != nil {
+		log.Printf("[API] CloseTable - 关闭牌桌失败: %v", err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[API] CloseTable - 关闭牌桌成功: %s", id)
+	c.JSON(200, gin.H{"id": id})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"log"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// isHost 判断用户是否为牌桌房主
+func (h *Hub) isHost(userID string) bool {
+	return h.hostID != "" && h.hostID == userID
+}
+
+// ensureHost 没有固定房主的牌桌由落座的玩家担任房主
+// 房主离开座位后，移交给座位顺序上的下一个玩家
+func (h *Hub) ensureHost() {
+	if !h.autoHost {
+		return
+	}
+
+	for _, player := range h.game.Players {
+		if !player.IsEmpty() && player.UserId == h.hostID {
+			return
+		}
+	}
+
+	h.hostID = ""
+	for _, player := range h.game.Players {
+		if !player.IsEmpty() {
+			h.hostID = player.UserId
+			log.Printf("[Hub] 房主移交给玩家 %s - 牌桌: %s", player.Name, h.id)
+			return
+		}
+	}
+}
+
+// requireHost 检查当前用户是否为房主
+func (c *Client) requireHost() error {
+	if !c.hub.isHost(c.user.ID) {
+		return requestError(i18n.ErrNotHost)
+	}
+	return nil
+}
+
+// handlePauseGame 处理房主暂停游戏请求
+// 暂停后停止开局倒计时和行动计时，牌局中的玩家不能行动
+func (c *Client) handlePauseGame() error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+	if c.hub.game.Paused {
+		return requestError(i18n.ErrAlreadyPaused)
+	}
+
+	c.hub.game.Paused = true
+	c.hub.cancelCountdown()
+	c.hub.cancelActionTimer()
+	log.Printf("[WS] 房主暂停游戏 - %s, 牌桌: %s", c.user, c.hub.id)
+
+	c.hub.broadcastGameState()
+	return nil
+}
+
+// handleResumeGame 处理房主恢复游戏请求
+func (c *Client) handleResumeGame() error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+	if !c.hub.game.Paused {
+		return requestError(i18n.ErrNotPaused)
+	}
+
+	c.hub.game.Paused = false
+	log.Printf("[WS] 房主恢复游戏 - %s, 牌桌: %s", c.user, c.hub.id)
+
+	// 暂停期间所有玩家都已准备的，恢复后开始倒计时
+	if c.hub.game.CanStartGame() {
+		c.hub.startCountdown()
+	}
+
+	c.hub.broadcastGameState()
+	return nil
+}
+
+// handleSetBlinds 处理房主修改盲注请求，只能在两局之间修改
+func (c *Client) handleSetBlinds(req SetBlindsRequest) error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+
+	if err := c.hub.game.SetBlinds(req.SmallBlind, req.BigBlind, req.Ante); err != nil {
+		return err
+	}
+
+	log.Printf("[WS] 房主修改盲注 - %s, 盲注: %d/%d, 前注: %d", c.user, req.SmallBlind, req.BigBlind, req.Ante)
+	c.hub.broadcastGameState()
+	return nil
+}
+
+// handleKickPlayer 处理房主踢出玩家请求，只能在两局之间踢人
+// Ban 为 true 时同时封禁该用户，断开其连接且不能再加入牌桌
+func (c *Client) handleKickPlayer(req KickPlayerRequest) error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+
+	if c.hub.game.GameStatus == poker.GameStatusPlaying {
+		return requestError(i18n.ErrGameInProgress)
+	}
+
+	seatIndex, err := c.seatIndex(req.SeatID)
+	if err != nil {
+		return err
+	}
+
+	player := &c.hub.game.Players[seatIndex]
+	if player.IsEmpty() {
+		return requestError(i18n.ErrSeatEmpty)
+	}
+	if player.UserId == c.user.ID {
+		return requestError(i18n.ErrCannotKickSelf)
+	}
+
+	userID, name := player.UserId, player.Name
+	c.hub.vacateSeat(player)
+	c.hub.cancelCountdown()
+	log.Printf("[WS] 房主踢出玩家 - 房主: %s, 玩家: %s, 座位: %d", c.user, name, req.SeatID)
+
+	if req.Ban {
+		c.hub.banned[userID] = true
+		if client, exists := c.hub.clients[userID]; exists {
+			client.sendError(i18n.ErrBanned)
+			delete(c.hub.clients, userID)
+			c.hub.safeCloseClient(client)
+		}
+		log.Printf("[WS] 房主封禁玩家 - %s, 牌桌: %s", name, c.hub.id)
+	}
+
+	c.hub.broadcastGameState()
+	return nil
+}
+
+// handleMutePlayer 处理房主禁言或解除禁言请求，禁言在牌桌关闭前一直有效
+func (c *Client) handleMutePlayer(req MutePlayerRequest) error {
+	if err := c.requireHost(); err != nil {
+		return err
+	}
+	if req.UserID == c.user.ID {
+		return requestError(i18n.ErrCannotMuteSelf)
+	}
+	user, ok := GetUser(req.UserID)
+	if !ok {
+		return requestError(i18n.ErrUserNotFound)
+	}
+	if c.hub.muted[req.UserID] == req.Mute {
+		return nil
+	}
+
+	if req.Mute {
+		c.hub.muted[req.UserID] = true
+		c.hub.dealerSay("DEALER_MUTED", user.Name)
+	} else {
+		delete(c.hub.muted, req.UserID)
+		c.hub.dealerSay("DEALER_UNMUTED", user.Name)
+	}
+	log.Printf("[WS] 房主%s - 房主: %s, 用户: %s", map[bool]string{true: "禁言", false: "解除禁言"}[req.Mute], c.user, user)
+	return nil
+}
@@ -1,14 +1,36 @@
 package service
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/lllllan02/holdem/poker"
 )
 
-// Hub 维护活跃的客户端集合并广播消息
+// Hub 维护一个牌桌上活跃的客户端集合并广播消息
 type Hub struct {
+	// 牌桌ID
+	id string
+
+	// 牌桌创建时间
+	createdAt time.Time
+
+	// 关闭信号，牌桌关闭后停止消息循环
+	quit chan struct{}
+
+	// 牌桌正在关闭，落座的玩家已经兑现离座，不再允许落座，只能在 hub 协程中访问
+	closing bool
+
+	// 房主与访问控制
+	hostID       string          // 房主用户ID
+	autoHost     bool            // 没有固定房主时，由落座的玩家担任房主
+	private      bool            // 是否为私人牌桌
+	inviteCode   string          // 私人牌桌邀请码
+	passwordHash string          // 加入密码的哈希，为空表示无需密码
+	banned       map[string]bool // 被房主封禁的用户
+	members      map[string]bool // 通过邀请码加入过私人牌桌的用户
+
 	// 所有活跃的客户端，key 是用户 ID
 	clients map[string]*Client
 
@@ -21,31 +43,202 @@ type Hub struct {
 	// 注销请求通道
 	unregister chan *Client
 
-	// 游戏实例
+	// 命令通道，其他协程通过它在 hub 协程中执行操作
+	commands chan func()
+
+	// 游戏实例，只能在 hub 协程中访问
 	game *poker.Game
 
-	// 倒计时相关
-	countdownTicker *time.Ticker
-	countdownDone   chan bool
+	// 倒计时
+	countdownTimer *hubTimer
+
+	// 摊牌定时器
+	showdownTimer *hubTimer
+
+	// 行动计时器
+	actionTimer *hubTimer
+	actionTurn  string // 计时器对应的行动轮次（阶段+座位）
 
-	// 摊牌定时器相关
-	showdownTicker *time.Ticker
-	showdownDone   chan bool
+	// 断线重连
+	resumeTokens map[string]string    // 用户当前的会话恢复令牌
+	graceTimers  map[string]*hubTimer // 断线玩家的宽限期计时器，key 是用户 ID
+
+	// 聊天
+	chat             []*poker.ChatMessage   // 最近的聊天消息
+	muted            map[string]bool        // 被房主禁言的用户
+	chatTimes        map[string][]time.Time // 用户最近的发言时间，用于限制发言频率
+	announcedRound   string                 // 荷官已播报行动的对局ID
+	announcedActions int                    // 该局已播报的行动数
+	announcedWinners string                 // 荷官已播报赢家的对局ID
+
+	// 落座玩家的统计数据缓存
+	stats        map[string]*poker.PlayerStats
+	statsLoading map[string][]*poker.GameRound // 正在后台加载统计的玩家，以及加载期间结束的对局
+	statsRound   string                        // 最后一局计入统计的对局ID
+}
+
+// hubTimer 周期性地在 hub 协程中执行回调的计时器
+// 计时协程本身不访问牌桌状态，只负责把回调投递到命令通道
+type hubTimer struct {
+	ticker  *time.Ticker
+	stop    chan struct{}
+	stopped bool
+}
+
+// Stop 停止计时器，只能在 hub 协程中调用
+func (t *hubTimer) Stop() {
+	if t == nil || t.stopped {
+		return
+	}
+	t.stopped = true
+	t.ticker.Stop()
+	close(t.stop)
 }
 
-// NewHub 创建一个新的 Hub
-func NewHub() *Hub {
+// NewHub 根据牌桌配置创建一个新的 Hub
+func NewHub(id string, config poker.TableConfig) (*Hub, error) {
+	game, err := poker.NewGame(config)
+	if err != nil {
+		return nil, err
+	}
+	game.TableID = id
+
 	hub := &Hub{
-		clients:       make(map[string]*Client),
-		broadcast:     make(chan []byte),
-		register:      make(chan *Client),
-		unregister:    make(chan *Client),
-		game:          poker.NewGame(),
-		countdownDone: make(chan bool, 1),
-		showdownDone:  make(chan bool, 1),
+		id:           id,
+		createdAt:    time.Now(),
+		quit:         make(chan struct{}),
+		banned:       make(map[string]bool),
+		members:      make(map[string]bool),
+		clients:      make(map[string]*Client),
+		broadcast:    make(chan []byte),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		commands:     make(chan func()),
+		game:         game,
+		resumeTokens: make(map[string]string),
+		graceTimers:  make(map[string]*hubTimer),
+		muted:        make(map[string]bool),
+		chatTimes:    make(map[string][]time.Time),
+		stats:        make(map[string]*poker.PlayerStats),
+		statsLoading: make(map[string][]*poker.GameRound),
+	}
+	log.Printf("[Hub] 创建新的 Hub 实例 - 牌桌: %s\n", id)
+	return hub, nil
+}
+
+// Info 返回大厅展示用的牌桌信息，可以在任意协程中调用
+func (h *Hub) Info() TableInfo {
+	var info TableInfo
+	h.call(func() {
+		info = h.info()
+	})
+	return info
+}
+
+// info 返回牌桌信息，只能在 hub 协程中调用
+func (h *Hub) info() TableInfo {
+	config := h.game.Config
+	seated := h.game.GetSittingPlayersCount()
+
+	sittingPlayers := make(map[string]bool)
+	for _, player := range h.game.Players {
+		if !player.IsEmpty() {
+			sittingPlayers[player.UserId] = true
+		}
+	}
+	spectators := 0
+	for userID := range h.clients {
+		if !sittingPlayers[userID] {
+			spectators++
+		}
+	}
+
+	return TableInfo{
+		ID:               h.id,
+		Name:             config.Name,
+		SmallBlind:       h.game.SmallBlind,
+		BigBlind:         h.game.BigBlind,
+		Ante:             h.game.Ante,
+		BettingStructure: config.BettingStructure,
+		Seated:           seated,
+		MaxSeats:         len(h.game.Players),
+		Spectators:       spectators,
+		GameStatus:       h.game.GameStatus,
+		Private:          h.private,
+		HasPassword:      h.passwordHash != "",
+		HostID:           h.hostID,
+		CreatedAt:        h.createdAt,
+	}
+}
+
+// Close 关闭牌桌：停止所有计时器并结束消息循环
+func (h *Hub) Close() {
+	h.call(func() {
+		h.cancelCountdown()
+		h.cancelShowdownTimer()
+		h.cancelActionTimer()
+		h.cancelGraceTimers()
+	})
+	close(h.quit)
+}
+
+// call 在 hub 协程中执行 fn 并等待其完成，牌桌已关闭时返回 false
+// 不能在 hub 协程中调用，否则会死锁
+func (h *Hub) call(fn func()) bool {
+	done := make(chan struct{})
+	select {
+	case h.commands <- func() {
+		defer close(done)
+		fn()
+	}:
+	case <-h.quit:
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-h.quit:
+		return false
 	}
-	log.Printf("[Hub] 创建新的 Hub 实例\n")
-	return hub
+}
+
+// startTimer 启动一个计时器，每个周期在 hub 协程中执行一次 tick
+// tick 返回 false 时计时器自动停止
+func (h *Hub) startTimer(interval time.Duration, tick func() bool) *hubTimer {
+	timer := &hubTimer{
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+
+	go func() {
+		for {
+			select {
+			case <-timer.ticker.C:
+				select {
+				case h.commands <- func() {
+					// 投递之后计时器可能已经被取消
+					if timer.stopped {
+						return
+					}
+					if !tick() {
+						timer.Stop()
+					}
+				}:
+				case <-timer.stop:
+					return
+				case <-h.quit:
+					return
+				}
+			case <-timer.stop:
+				return
+			case <-h.quit:
+				return
+			}
+		}
+	}()
+
+	return timer
 }
 
 // updateSpectatorCount 更新观众数量
@@ -72,20 +265,35 @@ func (h *Hub) updateSpectatorCount() {
 
 // broadcastGameState 广播游戏状态给所有客户端
 func (h *Hub) broadcastGameState() {
-	// 更新观众数量
+	// 更新观众数量和房主
 	h.updateSpectatorCount()
+	h.ensureHost()
+	h.updateSeatStats()
 
 	log.Printf("[Hub] 广播游戏状态更新, 目标客户端数: %d\n", len(h.clients))
 
 	// 检查是否需要启动摊牌定时器
-	if h.game.GamePhase == "showdown_reveal" && h.showdownTicker == nil {
+	if h.game.GamePhase == "showdown_reveal" && h.showdownTimer == nil {
 		log.Printf("[Hub] 检测到摊牌阶段，启动摊牌定时器")
 		h.startShowdownTimer()
 	}
 
-	// 为每个客户端单独发送定制的游戏状态
+	// 行动轮次变化时重新开始行动计时，游戏不在进行中或暂停时停止计时
+	if h.game.GameStatus == poker.GameStatusPlaying && h.game.CurrentPlayer >= 0 && !h.game.Paused {
+		if turn := h.currentActionTurn(); turn != h.actionTurn {
+			h.startActionTimer()
+		}
+	} else if h.actionTimer != nil {
+		h.cancelActionTimer()
+	}
+
+	// 荷官播报新的行动和结果
+	h.announceHand()
+
+	// 公共状态只序列化一次，再为每个客户端补充自己的手牌后发送
+	view := h.publicView()
 	for _, client := range h.clients {
-		client.sendGameState()
+		client.sendView(view)
 	}
 }
 
@@ -101,21 +309,27 @@ func (h *Hub) Run() {
 				log.Printf("[Hub] 关闭用户旧连接 - %s\n", client.user)
 			}
 			h.clients[client.user.ID] = client
-			log.Printf("[Hub] 新客户端注册 - %s, 当前在线: %d\n",
-				client.user, len(h.clients))
+			log.Printf("[Hub] 新客户端注册 - %s, 牌桌: %s, 当前在线: %d\n",
+				client.user, h.id, len(h.clients))
+
+			// 恢复会话并发送当前游戏状态给新连接的客户端
+			h.resumeSession(client)
 
 		case client := <-h.unregister:
-			if _, ok := h.clients[client.user.ID]; ok {
+			// 只注销当前连接，同一用户的新连接已经替换了旧连接时不处理
+			if current, ok := h.clients[client.user.ID]; ok && current == client {
 				delete(h.clients, client.user.ID)
 				h.safeCloseClient(client)
 				log.Printf("[Hub] 客户端注销 - %s, 当前在线: %d\n",
 					client.user, len(h.clients))
+				h.disconnectPlayer(client.user.ID)
 			}
 
 		case message := <-h.broadcast:
 			log.Printf("[Hub] 广播消息 - 长度: %d bytes, 目标客户端数: %d\n",
 				len(message), len(h.clients))
 
+			removed := make([]string, 0)
 			for userID, client := range h.clients {
 				select {
 				case client.send <- message:
@@ -124,9 +338,25 @@ func (h *Hub) Run() {
 					// 客户端通道已满或已关闭，移除客户端
 					delete(h.clients, userID)
 					h.safeCloseClient(client)
+					removed = append(removed, userID)
 					log.Printf("[Hub] 移除无响应客户端 - %s\n", client.user)
 				}
 			}
+			for _, userID := range removed {
+				h.disconnectPlayer(userID)
+			}
+
+		case command := <-h.commands:
+			command()
+
+		case <-h.quit:
+			// 牌桌关闭，断开所有客户端
+			for userID, client := range h.clients {
+				delete(h.clients, userID)
+				h.safeCloseClient(client)
+			}
+			log.Printf("[Hub] 牌桌已关闭，停止运行 - 牌桌: %s\n", h.id)
+			return
 		}
 	}
 }
@@ -154,9 +384,9 @@ func (h *Hub) safeCloseClient(client *Client) {
 // startCountdown 开始倒计时
 func (h *Hub) startCountdown() {
 	// 先停止之前的倒计时
-	if h.countdownTicker != nil {
-		h.countdownTicker.Stop()
-		h.countdownTicker = nil
+	if h.countdownTimer != nil {
+		h.countdownTimer.Stop()
+		h.countdownTimer = nil
 		log.Printf("[Hub] 停止之前的倒计时")
 	}
 
@@ -166,75 +396,58 @@ func (h *Hub) startCountdown() {
 		return
 	}
 
-	log.Printf("[Hub] 开始倒计时，初始值: 3")
-	h.game.CountdownTimer = 3
+	log.Printf("[Hub] 开始倒计时，初始值: %d", h.game.Config.StartCountdown)
+	h.game.CountdownTimer = h.game.Config.StartCountdown
 	h.broadcastGameState()
 
-	// 创建新的 done 通道
-	h.countdownDone = make(chan bool, 1)
-
-	// 使用 ticker 而不是 timer 来确保每秒都触发
-	h.countdownTicker = time.NewTicker(time.Second)
+	// 每秒触发一次
+	var timer *hubTimer
+	timer = h.startTimer(time.Second, func() bool {
+		h.game.CountdownTimer--
+		log.Printf("[Hub] 倒计时更新: %d", h.game.CountdownTimer)
 
-	go func() {
-		ticker := h.countdownTicker
-		done := h.countdownDone
-
-		defer func() {
-			if ticker != nil {
-				ticker.Stop()
-			}
-			log.Printf("[Hub] 倒计时协程结束")
-		}()
+		if h.game.CountdownTimer > 0 {
+			h.broadcastGameState()
+			return true
+		}
 
-		for {
-			select {
-			case <-ticker.C:
-				h.game.CountdownTimer--
-				log.Printf("[Hub] 倒计时更新: %d", h.game.CountdownTimer)
-				h.broadcastGameState()
-
-				if h.game.CountdownTimer <= 0 {
-					// 倒计时结束，再次检查是否可以开始游戏
-					if h.game.CanStartGame() {
-						log.Printf("[Hub] 倒计时结束，开始游戏")
-						h.game.CountdownTimer = -1 // 设置为-1表示倒计时已结束
-						if h.game.StartGame() {
-							log.Printf("[Hub] 游戏自动开始成功")
-						} else {
-							log.Printf("[Hub] 游戏自动开始失败")
-						}
-					} else {
-						log.Printf("[Hub] 倒计时结束，但不满足开始游戏条件")
-						h.game.CountdownTimer = -1 // 设置为-1表示倒计时已结束
-					}
-					h.broadcastGameState()
-					return
-				}
-			case <-done:
-				log.Printf("[Hub] 倒计时被取消")
-				return
+		// 倒计时结束，再次检查是否可以开始游戏
+		if h.countdownTimer == timer {
+			h.countdownTimer = nil
+		}
+		h.game.CountdownTimer = -1 // 设置为-1表示倒计时已结束
+		if h.game.CanStartGame() {
+			log.Printf("[Hub] 倒计时结束，开始游戏")
+			if h.game.StartGame() {
+				log.Printf("[Hub] 游戏自动开始成功")
+				h.removeAwayPlayers()
+			} else {
+				log.Printf("[Hub] 游戏自动开始失败")
 			}
+		} else {
+			log.Printf("[Hub] 倒计时结束，但不满足开始游戏条件")
 		}
-	}()
+		h.broadcastGameState()
+		return false
+	})
+	h.countdownTimer = timer
 }
 
-// cancelCountdown 取消倒计时
-func (h *Hub) cancelCountdown() {
-	if h.countdownTicker != nil {
-		h.countdownTicker.Stop()
-		h.countdownTicker = nil
-		log.Printf("[Hub] 停止倒计时 ticker")
+// removeAwayPlayers 暂时离开太久的玩家自动离座，在新一局开始、离开圈数更新之后调用
+func (h *Hub) removeAwayPlayers() {
+	for _, pos := range h.game.AwayTooLong() {
+		player := &h.game.Players[pos]
+		log.Printf("[Hub] 玩家暂时离开 %d 圈，自动离座 - %s, 座位: %d", player.OrbitsAway, player.Name, pos+1)
+		h.vacateSeat(player)
 	}
+}
 
-	// 发送取消信号（非阻塞）
-	if h.countdownDone != nil {
-		select {
-		case h.countdownDone <- true:
-			log.Printf("[Hub] 发送倒计时取消信号")
-		default:
-			log.Printf("[Hub] 倒计时取消信号通道已满或已关闭")
-		}
+// cancelCountdown 取消倒计时
+func (h *Hub) cancelCountdown() {
+	if h.countdownTimer != nil {
+		h.countdownTimer.Stop()
+		h.countdownTimer = nil
+		log.Printf("[Hub] 倒计时被取消")
 	}
 
 	if h.game.CountdownTimer > 0 {
@@ -246,75 +459,114 @@ func (h *Hub) cancelCountdown() {
 // startShowdownTimer 开始摊牌定时器
 func (h *Hub) startShowdownTimer() {
 	// 先停止之前的摊牌定时器
-	if h.showdownTicker != nil {
-		h.showdownTicker.Stop()
-		h.showdownTicker = nil
-		log.Printf("[Hub] 停止之前的摊牌定时器")
-	}
-
-	log.Printf("[Hub] 开始摊牌定时器，每1秒推进一次")
-
-	// 创建新的 done 通道
-	h.showdownDone = make(chan bool, 1)
+	h.cancelShowdownTimer()
 
-	// 使用 ticker 来定时推进摊牌
-	h.showdownTicker = time.NewTicker(1 * time.Second) // 每1秒推进一次
+	interval := time.Duration(h.game.Config.ShowdownInterval) * time.Second
+	log.Printf("[Hub] 开始摊牌定时器，每%v推进一次", interval)
 
-	go func() {
-		ticker := h.showdownTicker
-		done := h.showdownDone
-
-		defer func() {
-			if ticker != nil {
-				ticker.Stop()
-			}
-			log.Printf("[Hub] 摊牌定时器协程结束")
-		}()
+	var timer *hubTimer
+	timer = h.startTimer(interval, func() bool {
+		// 检查是否还在摊牌阶段
+		if h.game.GamePhase == "showdown_reveal" {
+			log.Printf("[Hub] 定时器触发，推进下一个摊牌")
+			h.game.AdvanceShowdown()
+		}
 
-		for {
-			select {
-			case <-ticker.C:
-				// 检查是否还在摊牌阶段
-				if h.game.GamePhase == "showdown_reveal" {
-					log.Printf("[Hub] 定时器触发，推进下一个摊牌")
-					h.game.AdvanceShowdown()
-					h.broadcastGameState()
-
-					// 检查是否摊牌已完成（游戏阶段已改变）
-					if h.game.GamePhase != "showdown_reveal" {
-						log.Printf("[Hub] 摊牌阶段结束，停止定时器")
-						h.cancelShowdownTimer()
-						return
-					}
-				} else {
-					// 摊牌结束，停止定时器
-					log.Printf("[Hub] 摊牌阶段结束，停止定时器")
-					h.cancelShowdownTimer()
-					return
-				}
-			case <-done:
-				log.Printf("[Hub] 摊牌定时器被取消")
-				return
+		// 摊牌已完成（游戏阶段已改变），停止定时器
+		if h.game.GamePhase != "showdown_reveal" {
+			log.Printf("[Hub] 摊牌阶段结束，停止定时器")
+			if h.showdownTimer == timer {
+				h.showdownTimer = nil
 			}
+			h.readyDisconnectedPlayers()
+			h.broadcastGameState()
+			return false
 		}
-	}()
+
+		h.broadcastGameState()
+		return true
+	})
+	h.showdownTimer = timer
 }
 
 // cancelShowdownTimer 取消摊牌定时器
 func (h *Hub) cancelShowdownTimer() {
-	if h.showdownTicker != nil {
-		h.showdownTicker.Stop()
-		h.showdownTicker = nil
-		log.Printf("[Hub] 停止摊牌定时器 ticker")
+	if h.showdownTimer != nil {
+		h.showdownTimer.Stop()
+		h.showdownTimer = nil
+		log.Printf("[Hub] 停止摊牌定时器")
 	}
+}
 
-	// 发送取消信号（非阻塞）
-	if h.showdownDone != nil {
-		select {
-		case h.showdownDone <- true:
-			log.Printf("[Hub] 发送摊牌定时器取消信号")
-		default:
-			log.Printf("[Hub] 摊牌定时器取消信号通道已满或已关闭")
+// currentActionTurn 返回当前行动轮次的标识（阶段+座位）
+func (h *Hub) currentActionTurn() string {
+	return fmt.Sprintf("%s:%d", h.game.GamePhase, h.game.CurrentPlayer)
+}
+
+// startActionTimer 开始当前行动玩家的行动计时
+// 基础时间用完后消耗该座位的时间银行，全部用完则自动过牌或弃牌
+func (h *Hub) startActionTimer() {
+	// 先停止之前的行动计时器
+	h.cancelActionTimer()
+
+	turn := h.currentActionTurn()
+	h.actionTurn = turn
+	h.game.ActionTimer = h.game.Config.ActionTimeout
+	h.game.UsingTimeBank = false
+	log.Printf("[Hub] 开始行动计时 - 座位%d, 时限: %d秒", h.game.CurrentPlayer+1, h.game.ActionTimer)
+
+	var timer *hubTimer
+	timer = h.startTimer(time.Second, func() bool {
+		// 行动轮次已经改变（玩家已行动），停止计时
+		if h.game.GameStatus != poker.GameStatusPlaying || h.currentActionTurn() != turn {
+			return false
+		}
+
+		player := &h.game.Players[h.game.CurrentPlayer]
+
+		// 断线的玩家在宽限期内保留行动机会，暂停计时；宽限期已过则立即代为行动
+		if h.awaitingReconnect(player) {
+			return true
 		}
+		if player.Disconnected {
+			h.game.ActionTimer = 0
+		} else if h.game.ActionTimer > 0 {
+			h.game.ActionTimer--
+		} else if player.TimeBank > 0 {
+			h.game.UsingTimeBank = true
+			player.TimeBank--
+		}
+
+		if h.game.ActionTimer <= 0 && (player.TimeBank <= 0 || player.Disconnected) {
+			log.Printf("[Hub] 玩家 %s 行动超时", player.Name)
+			if h.actionTimer == timer {
+				h.actionTimer = nil
+			}
+			h.actionTurn = ""
+			h.game.UsingTimeBank = false
+			if action, ok := h.game.AutoAction(); ok {
+				log.Printf("[Hub] 已为超时玩家自动执行: %s", action)
+			}
+			h.readyDisconnectedPlayers()
+			h.broadcastGameState()
+			return false
+		}
+
+		h.broadcastGameState()
+		return true
+	})
+	h.actionTimer = timer
+}
+
+// cancelActionTimer 取消行动计时器
+func (h *Hub) cancelActionTimer() {
+	if h.actionTimer != nil {
+		h.actionTimer.Stop()
+		h.actionTimer = nil
+		log.Printf("[Hub] 停止行动计时")
 	}
+
+	h.actionTurn = ""
+	h.game.ActionTimer = 0
+	h.game.UsingTimeBank = false
 }
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// 等待服务器消息的超时时间，需要大于开局倒计时和摊牌展示的时间
+const testWaitTimeout = 30 * time.Second
+
+// testTable 运行在 httptest 服务器上的牌桌，用户和对局记录保存在临时目录
+type testTable struct {
+	server *httptest.Server
+	hub    *Hub
+}
+
+// initTestStores 把用户和对局记录的存储设置到临时目录，返回该目录
+func initTestStores(t *testing.T) string {
+	t.Helper()
+	t.Setenv(tokenSecretEnv, "test-secret")
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	if err := InitUsers(NewFileUserStore(filepath.Join(dir, "users.json"))); err != nil {
+		t.Fatalf("加载用户失败: %v", err)
+	}
+	poker.SetRecordStore(poker.NewFileRecordStore(filepath.Join(dir, "records")))
+	return dir
+}
+
+// newTestTable 创建测试牌桌，测试结束时关闭服务器和牌桌
+func newTestTable(t *testing.T) *testTable {
+	t.Helper()
+	initTestStores(t)
+
+	config := poker.DefaultTableConfig()
+	config.StartCountdown = 1
+	hub, err := tables.Create(config, TableOptions{})
+	if err != nil {
+		t.Fatalf("创建牌桌失败: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/ws/:tableId", AuthRequired(), WebSocketHandler)
+	server := httptest.NewServer(router)
+
+	t.Cleanup(func() {
+		server.Close()
+		tables.mu.Lock()
+		delete(tables.tables, hub.id)
+		tables.mu.Unlock()
+		hub.Close()
+	})
+	return &testTable{server: server, hub: hub}
+}
+
+// testGame 测试关心的游戏状态字段
+type testGame struct {
+	GameStatus    string `json:"gameStatus"`
+	GamePhase     string `json:"gamePhase"`
+	CurrentBet    int    `json:"currentBet"`
+	CurrentPlayer int    `json:"currentPlayer"`
+	RoundID       string `json:"roundId"`
+	CurrentRound  *struct {
+		RoundID string `json:"roundId"`
+	} `json:"currentRound"`
+	Players []struct {
+		UserId     string       `json:"userId"`
+		Status     string       `json:"status"`
+		Chips      int          `json:"chips"`
+		CurrentBet int          `json:"currentBet"`
+		HoleCards  []poker.Card `json:"holeCards"`
+	} `json:"players"`
+}
+
+// handFinished 本局是否已经结算
+func (g *testGame) handFinished() bool {
+	return g.RoundID != "" && g.CurrentRound != nil && g.CurrentRound.RoundID == g.RoundID
+}
+
+// testClient 测试用的 WebSocket 客户端，后台协程读取消息并保存最新的状态
+type testClient struct {
+	user *User
+	conn *websocket.Conn
+
+	mu      sync.Mutex
+	game    *testGame
+	session *SessionData
+	closed  bool
+	updated chan struct{}    // 收到新的游戏状态或连接关闭
+	replies chan testMessage // 请求的确认和错误
+
+	nextID int
+}
+
+// testMessage 服务器发送的消息
+type testMessage struct {
+	Type MessageType     `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// dial 以用户身份连接牌桌，resume 不为空时带上恢复令牌
+func (tt *testTable) dial(user *User, resume string) (*testClient, error) {
+	url := fmt.Sprintf("ws%s/ws/%s?token=%s&resume=%s",
+		strings.TrimPrefix(tt.server.URL, "http"), tt.hub.id, IssueToken(user.ID), resume)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s 连接失败: %v", user.ID, err)
+	}
+
+	client := &testClient{
+		user:    user,
+		conn:    conn,
+		updated: make(chan struct{}, 1),
+		replies: make(chan testMessage, 64),
+	}
+	go client.read()
+
+	// 连接后先收到会话和完整状态
+	if _, err := client.waitState(func(*testGame) bool { return true }); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// read 读取服务器消息直到连接关闭
+func (c *testClient) read() {
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		c.notify()
+	}()
+
+	for {
+		var message testMessage
+		if err := c.conn.ReadJSON(&message); err != nil {
+			return
+		}
+
+		switch message.Type {
+		case MSG_GAME_STATE:
+			var data struct {
+				Game testGame `json:"game"`
+			}
+			if err := json.Unmarshal(message.Data, &data); err != nil {
+				return
+			}
+			c.mu.Lock()
+			c.game = &data.Game
+			c.mu.Unlock()
+			c.notify()
+		case MSG_SESSION:
+			var session SessionData
+			if err := json.Unmarshal(message.Data, &session); err != nil {
+				return
+			}
+			c.mu.Lock()
+			c.session = &session
+			c.mu.Unlock()
+		case MSG_ACK, MSG_ERROR:
+			c.replies <- message
+		}
+	}
+}
+
+// notify 通知等待状态的协程
+func (c *testClient) notify() {
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+}
+
+// waitState 等待满足条件的游戏状态
+func (c *testClient) waitState(match func(*testGame) bool) (*testGame, error) {
+	timeout := time.After(testWaitTimeout)
+	for {
+		c.mu.Lock()
+		game, closed := c.game, c.closed
+		c.mu.Unlock()
+		if game != nil && match(game) {
+			return game, nil
+		}
+		if closed {
+			return nil, fmt.Errorf("%s 的连接已关闭", c.user.ID)
+		}
+
+		select {
+		case <-c.updated:
+		case <-timeout:
+			return nil, fmt.Errorf("%s 等待游戏状态超时", c.user.ID)
+		}
+	}
+}
+
+// currentSession 返回最近收到的会话信息
+func (c *testClient) currentSession() *SessionData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+// request 发送请求并等待回复，成功时返回空错误码
+func (c *testClient) request(messageType MessageType, data interface{}) (i18n.Code, error) {
+	c.nextID++
+	id := fmt.Sprintf("%s-%d", c.user.ID, c.nextID)
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	if err := c.conn.WriteJSON(ClientMessage{Type: messageType, ID: id, Data: payload}); err != nil {
+		return "", fmt.Errorf("%s 发送 %s 失败: %v", c.user.ID, messageType, err)
+	}
+
+	timeout := time.After(testWaitTimeout)
+	for {
+		select {
+		case message := <-c.replies:
+			var reply struct {
+				ID   string    `json:"id"`
+				Code i18n.Code `json:"code"`
+			}
+			if err := json.Unmarshal(message.Data, &reply); err != nil {
+				return "", err
+			}
+			if reply.ID == id {
+				return reply.Code, nil
+			}
+		case <-timeout:
+			return "", fmt.Errorf("%s 等待 %s 的回复超时", c.user.ID, messageType)
+		}
+	}
+}
+
+// mustSucceed 发送请求，失败时返回错误
+func (c *testClient) mustSucceed(messageType MessageType, data interface{}) error {
+	code, err := c.request(messageType, data)
+	if err != nil {
+		return err
+	}
+	if code != "" {
+		return fmt.Errorf("%s 的 %s 请求失败: %s", c.user.ID, messageType, code)
+	}
+	return nil
+}
+
+// playHand 轮到自己时过牌或跟注，直到本局结束
+func (c *testClient) playHand(seat int) error {
+	for {
+		game, err := c.waitState(func(g *testGame) bool {
+			return g.handFinished() ||
+				(g.GameStatus == poker.GameStatusPlaying && g.GamePhase != poker.GamePhaseShowdown && g.CurrentPlayer == seat)
+		})
+		if err != nil {
+			return err
+		}
+		if game.handFinished() {
+			return nil
+		}
+
+		action := MSG_CHECK
+		if game.CurrentBet > game.Players[seat].CurrentBet {
+			action = MSG_CALL
+		}
+		code, err := c.request(action, nil)
+		if err != nil {
+			return err
+		}
+		// 行动计时的广播可能让同一轮状态收到多次，轮次已经过去的请求会被拒绝
+		if code != "" && code != i18n.ErrNotYourTurn {
+			return fmt.Errorf("%s 行动失败: %s", c.user.ID, code)
+		}
+
+		// 等待本次行动生效
+		if _, err := c.waitState(func(g *testGame) bool {
+			return g.handFinished() || g.CurrentPlayer != seat || g.GamePhase != game.GamePhase
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// TestHubConcurrentClients 多个客户端并发加入、行动、离开和重连，运行 go test -race 检查数据竞争
+func TestHubConcurrentClients(t *testing.T) {
+	table := newTestTable(t)
+
+	const seats = 4
+	users := make([]*User, seats)
+	bankroll := 0
+	for i := range users {
+		users[i] = CreateGuestUser(fmt.Sprintf("10.0.0.%d", i+1), "holdem-test")
+		bankroll += users[i].Bankroll
+	}
+
+	// 所有玩家同时连接、落座并准备
+	players := make([]*testClient, seats)
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := table.dial(users[i], "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			players[i] = client
+			if err := client.mustSucceed(MSG_SIT_DOWN, SitDownRequest{SeatID: i + 1}); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := client.mustSucceed(MSG_READY, nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	if _, err := players[0].waitState(func(g *testGame) bool { return g.GameStatus == poker.GameStatusPlaying }); err != nil {
+		t.Fatal(err)
+	}
+
+	// 观众反复加入、聊天和断开，与牌局同时进行
+	stop := make(chan struct{})
+	var spectators sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		user := CreateGuestUser(fmt.Sprintf("10.0.1.%d", i+1), "holdem-test")
+		spectators.Add(1)
+		go func() {
+			defer spectators.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				client, err := table.dial(user, "")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if code, err := client.request(MSG_CHAT, ChatRequest{Text: "hi"}); err != nil {
+					t.Error(err)
+				} else if code != "" && code != i18n.ErrRateLimited {
+					t.Errorf("观众聊天失败: %s", code)
+				}
+				client.conn.Close()
+			}
+		}()
+	}
+
+	// 玩家同时行动，其中一个玩家断线后带着恢复令牌重连，另一个玩家的座位被没有令牌的新连接顶替后再用令牌恢复
+	for i := range players {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client := players[i]
+			var err error
+
+			switch i {
+			case 1:
+				token := client.currentSession().ResumeToken
+				client.conn.Close()
+				if client, err = table.dial(users[i], token); err != nil {
+					t.Error(err)
+					return
+				}
+				if session := client.currentSession(); !session.Resumed || session.Seat != i || len(session.HoleCards) != 2 {
+					t.Errorf("带令牌重连没有恢复座位: %+v", session)
+				}
+
+			case 2:
+				token := client.currentSession().ResumeToken
+				intruder, err := table.dial(users[i], "")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if session := intruder.currentSession(); session.Seat != -1 || len(session.HoleCards) != 0 {
+					t.Errorf("没有令牌的连接接管了座位: %+v", session)
+				}
+				if game, _ := intruder.waitState(func(*testGame) bool { return true }); game != nil {
+					for _, card := range game.Players[i].HoleCards {
+						if card.Rank != "" {
+							t.Errorf("没有令牌的连接收到了手牌: %+v", game.Players[i].HoleCards)
+							break
+						}
+					}
+				}
+				if code, err := intruder.request(MSG_FOLD, nil); err != nil || code != i18n.ErrSeatDetached {
+					t.Errorf("没有令牌的连接可以行动: %s, %v", code, err)
+				}
+				if client, err = table.dial(users[i], token); err != nil {
+					t.Error(err)
+					return
+				}
+				if session := client.currentSession(); !session.Resumed || session.Seat != i {
+					t.Errorf("带令牌重连没有恢复座位: %+v", session)
+				}
+			}
+
+			players[i] = client
+			if err := client.playHand(i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	spectators.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// 本局结束后玩家同时离开座位，筹码兑现回资金，总资金不变
+	for i := range players {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := players[i].waitState(func(g *testGame) bool {
+				return g.GamePhase == poker.GamePhaseShowdown || g.GameStatus == poker.GameStatusWaiting
+			}); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := players[i].mustSucceed(MSG_LEAVE_SEAT, LeaveSeatRequest{SeatID: i + 1}); err != nil {
+				t.Error(err)
+			}
+			players[i].conn.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, user := range users {
+		current, _ := GetUser(user.ID)
+		total += current.Bankroll
+	}
+	if total != bankroll {
+		t.Errorf("离开座位后总资金为 %d，应为 %d", total, bankroll)
+	}
+}
@@ -1,6 +1,11 @@
 package service
 
-import "github.com/lllllan02/holdem/poker"
+import (
+	"encoding/json"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
 
 // WebSocket消息类型
 type MessageType string
@@ -8,9 +13,13 @@ type MessageType string
 const (
 	// 服务器发送给客户端的消息类型
 	MSG_GAME_STATE    MessageType = "game_state"
+	MSG_GAME_DELTA    MessageType = "game_delta"
+	MSG_SESSION       MessageType = "session"
 	MSG_PLAYER_ACTION MessageType = "player_action"
 	MSG_GAME_UPDATE   MessageType = "game_update"
 	MSG_ERROR         MessageType = "error"
+	MSG_ACK           MessageType = "ack"
+	MSG_CHAT_HISTORY  MessageType = "chat_history"
 
 	// 客户端发送给服务器的消息类型
 	MSG_SIT_DOWN   MessageType = "sit_down"
@@ -22,27 +31,168 @@ const (
 	MSG_CALL       MessageType = "call"
 	MSG_RAISE      MessageType = "raise"
 	MSG_CHECK      MessageType = "check"
+	MSG_ALL_IN     MessageType = "allin"
 	MSG_END_GAME   MessageType = "end_game"
+	MSG_REBUY      MessageType = "rebuy"
+	MSG_TOP_UP     MessageType = "top_up"
+	MSG_SIT_OUT    MessageType = "sit_out"
+	MSG_SIT_IN     MessageType = "sit_in"
+	MSG_RESYNC     MessageType = "resync"
+	MSG_CHAT       MessageType = "chat" // 服务器转发的聊天消息也使用该类型
+
+	// 房主操作的消息类型
+	MSG_PAUSE_GAME  MessageType = "pause_game"
+	MSG_RESUME_GAME MessageType = "resume_game"
+	MSG_SET_BLINDS  MessageType = "set_blinds"
+	MSG_KICK_PLAYER MessageType = "kick_player"
+	MSG_MUTE_PLAYER MessageType = "mute_player"
 )
 
-// WebSocket消息结构
+// WebSocket消息结构，服务器发送给客户端
 type WSMessage struct {
 	Type MessageType `json:"type"`
 	Data interface{} `json:"data"`
 }
 
-// 游戏状态消息数据
+// 客户端发送的消息，Data 按 Type 解析为对应的请求结构
+// 带有 ID 的请求处理成功后回复 ack，失败时在 error 中带回 ID
+type ClientMessage struct {
+	Type MessageType     `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// 落座请求，BuyIn 为 0 时尽量按最大买入
+type SitDownRequest struct {
+	SeatID int `json:"seatId"` // 座位号，从 1 开始
+	BuyIn  int `json:"buyIn"`  // 买入金额
+}
+
+// 离开座位请求
+type LeaveSeatRequest struct {
+	SeatID int `json:"seatId"`
+}
+
+// 玩家行动请求，Amount 只用于加注，表示加注到的金额
+type ActionRequest struct {
+	Amount int `json:"amount"`
+}
+
+// 补码或加码请求，Amount 为 0 时补足到最大买入
+type AddChipsRequest struct {
+	Amount int `json:"amount"`
+}
+
+// 房主修改盲注请求
+type SetBlindsRequest struct {
+	SmallBlind int `json:"smallBlind"`
+	BigBlind   int `json:"bigBlind"`
+	Ante       int `json:"ante"`
+}
+
+// 房主踢出玩家请求，Ban 为 true 时同时封禁
+type KickPlayerRequest struct {
+	SeatID int  `json:"seatId"`
+	Ban    bool `json:"ban"`
+}
+
+// 房主禁言或解除禁言请求，观众也可以被禁言，所以按用户ID指定
+type MutePlayerRequest struct {
+	UserID string `json:"userId"`
+	Mute   bool   `json:"mute"`
+}
+
+// 聊天请求
+type ChatRequest struct {
+	Text string `json:"text"`
+}
+
+// 聊天消息数据，荷官消息按接收者的语言生成内容
+type ChatData struct {
+	Time   int64  `json:"time"`             // 发送时间（毫秒时间戳）
+	UserID string `json:"userId,omitempty"` // 发言的用户，荷官消息为空
+	Name   string `json:"name,omitempty"`   // 发言的用户名称
+	Text   string `json:"text"`             // 内容
+	Dealer bool   `json:"dealer,omitempty"` // 是否为荷官消息
+}
+
+// 聊天记录消息数据，加入牌桌时发送最近的消息
+type ChatHistoryData struct {
+	Messages []ChatData `json:"messages"`
+}
+
+// 请求成功的确认消息数据
+type AckData struct {
+	ID   string      `json:"id"`   // 请求ID
+	Type MessageType `json:"type"` // 请求的消息类型
+}
+
+// 游戏状态消息数据，Seq 只发送给使用增量协议的客户端
 type GameStateData struct {
-	Game *poker.Game `json:"game"`
+	Game  json.RawMessage `json:"game"`
+	Table TableStateData  `json:"table"`
+	Seq   int64           `json:"seq,omitempty"`
+}
+
+// 增量更新消息数据，Seq 与上一条游戏状态或增量连续
+type GameDeltaData struct {
+	Seq    int64       `json:"seq"`
+	Events []GameEvent `json:"events"`
+}
+
+// 增量更新中的一个事件
+type GameEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// 发出公共牌的事件数据
+type CardDealtData struct {
+	Cards []json.RawMessage `json:"cards"`
+}
+
+// 底池变化的事件数据
+type PotUpdatedData struct {
+	Pot  json.RawMessage `json:"pot"`
+	Pots json.RawMessage `json:"pots"`
+}
+
+// 玩家字段变化的事件数据
+type PlayerUpdatedData struct {
+	Seat   int                        `json:"seat"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+// 其余游戏字段变化的事件数据
+type StateChangedData struct {
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+// 游戏状态中附带的牌桌信息
+type TableStateData struct {
+	ID          string `json:"id"`                   // 牌桌ID
+	Name        string `json:"name"`                 // 牌桌名称
+	HostID      string `json:"hostId"`               // 房主用户ID
+	Private     bool   `json:"private"`              // 是否为私人牌桌
+	HasPassword bool   `json:"hasPassword"`          // 是否需要密码
+	InviteCode  string `json:"inviteCode,omitempty"` // 邀请码（只发送给房主）
 }
 
-// 玩家行动消息数据
-type PlayerActionData struct {
-	Action string `json:"action"`
-	Amount int    `json:"amount"`
+// 会话消息数据，连接建立后发送给该客户端
+// 重连时通过 ?resume= 带上 ResumeToken 恢复会话
+type SessionData struct {
+	Protocol    int          `json:"protocol"`            // 本次连接使用的协议版本
+	MaxProtocol int          `json:"maxProtocol"`         // 服务器支持的最高协议版本
+	ResumeToken string       `json:"resumeToken"`         // 下次重连使用的恢复令牌
+	Resumed     bool         `json:"resumed"`             // 是否恢复了上一次的会话
+	Seat        int          `json:"seat"`                // 所在座位，未落座为 -1
+	HoleCards   []poker.Card `json:"holeCards,omitempty"` // 自己的手牌
 }
 
-// 错误消息数据
+// 错误消息数据，对应某个请求时带有请求ID和消息类型
 type ErrorData struct {
-	Message string `json:"message"`
+	ID      string      `json:"id,omitempty"`   // 请求ID
+	Type    MessageType `json:"type,omitempty"` // 请求的消息类型
+	Code    i18n.Code   `json:"code"`           // 错误码
+	Message string      `json:"message"`        // 错误描述
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/lllllan02/holdem/i18n"
+)
+
+// requestError 创建带错误码的请求错误，消息在回复时按客户端的语言生成
+func requestError(code i18n.Code, args ...interface{}) error {
+	return i18n.NewError(code, args...)
+}
+
+// withPayload 把消息数据解析为 T 后交给 handle 处理，数据格式错误时返回 i18n.ErrBadRequest
+// 没有数据的消息按 T 的零值处理
+func withPayload[T any](data json.RawMessage, handle func(T) error) error {
+	var payload T
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &payload); err != nil {
+			log.Printf("[WS] 解析消息数据失败 - 错误: %v\n", err)
+			return requestError(i18n.ErrBadRequest)
+		}
+	}
+	return handle(payload)
+}
+
+// reply 回复客户端的请求：成功时对带请求ID的消息发送确认，失败时发送带错误码的错误消息
+func (c *Client) reply(message ClientMessage, err error) {
+	if err == nil {
+		if message.ID != "" {
+			c.sendMessage(WSMessage{Type: MSG_ACK, Data: AckData{ID: message.ID, Type: message.Type}})
+		}
+		return
+	}
+
+	// 没有错误码的错误视为内部错误，不把内部细节发给客户端
+	reqErr := i18n.NewError(i18n.ErrInternal)
+	errors.As(err, &reqErr)
+	log.Printf("[WS] 处理消息失败 - %s, 类型: %s, 错误码: %s, 原因: %v\n", c.user, message.Type, reqErr.Code, err)
+	c.sendMessage(WSMessage{Type: MSG_ERROR, Data: ErrorData{
+		ID:      message.ID,
+		Type:    message.Type,
+		Code:    reqErr.Code,
+		Message: reqErr.Message(c.lang()),
+	}})
+}
+
+// sendError 发送不对应任何请求的错误消息
+func (c *Client) sendError(code i18n.Code, args ...interface{}) {
+	c.sendMessage(WSMessage{Type: MSG_ERROR, Data: ErrorData{
+		Code:    code,
+		Message: i18n.NewError(code, args...).Message(c.lang()),
+	}})
+}
+
+// lang 返回客户端使用的语言：用户设置的语言偏好优先，否则按连接时的 Accept-Language
+func (c *Client) lang() i18n.Lang {
+	preference := ""
+	if user, ok := GetUser(c.user.ID); ok {
+		preference = user.Language
+	}
+	return i18n.Resolve(preference, c.acceptLanguage)
+}
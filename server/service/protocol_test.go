@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/i18n"
+)
+
+func TestClientReply(t *testing.T) {
+	initTestStores(t)
+	user := CreateGuestUser("10.0.0.1", "holdem-test")
+
+	tests := []struct {
+		name           string
+		id             string
+		err            error
+		acceptLanguage string
+		wantType       MessageType // 为空表示不回复
+		wantData       string
+	}{
+		{
+			name:     "成功的请求回复确认",
+			id:       "r1",
+			wantType: MSG_ACK,
+			wantData: `{"id":"r1","type":"sit_down"}`,
+		},
+		{
+			name: "没有请求ID的成功请求不回复",
+		},
+		{
+			name:           "错误码和参数按客户端的语言生成消息",
+			id:             "r2",
+			err:            requestError(i18n.ErrInvalidSeat, 9),
+			acceptLanguage: "en-US,en;q=0.9",
+			wantType:       MSG_ERROR,
+			wantData:       `{"id":"r2","type":"sit_down","code":"INVALID_SEAT","message":"Invalid seat: 9"}`,
+		},
+		{
+			name:           "包装过的错误保留错误码",
+			id:             "r3",
+			err:            fmt.Errorf("落座失败: %w", requestError(i18n.ErrSeatTaken, 1)),
+			acceptLanguage: "zh-CN",
+			wantType:       MSG_ERROR,
+			wantData:       `{"id":"r3","type":"sit_down","code":"SEAT_TAKEN","message":"座位 1 已被占用"}`,
+		},
+		{
+			// 没有错误码的错误不把内部细节发给客户端
+			name:           "内部错误",
+			err:            errors.New("写入文件失败"),
+			acceptLanguage: "en",
+			wantType:       MSG_ERROR,
+			wantData:       `{"type":"sit_down","code":"INTERNAL","message":"Internal server error, please retry"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{user: user, send: make(chan []byte, 1), acceptLanguage: tt.acceptLanguage}
+			client.reply(ClientMessage{Type: MSG_SIT_DOWN, ID: tt.id}, tt.err)
+
+			select {
+			case data := <-client.send:
+				var message testMessage
+				if err := json.Unmarshal(data, &message); err != nil {
+					t.Fatalf("解析回复失败: %v", err)
+				}
+				if message.Type != tt.wantType || string(message.Data) != tt.wantData {
+					t.Errorf("回复 = %s %s, 期望 %s %s", message.Type, message.Data, tt.wantType, tt.wantData)
+				}
+			default:
+				if tt.wantType != "" {
+					t.Errorf("没有回复，期望 %s", tt.wantType)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestErrorCodes(t *testing.T) {
+	table := newTestTable(t)
+	client, err := table.dial(CreateGuestUser("10.0.0.1", "holdem-test"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.conn.Close()
+
+	// 依次发送，后面的请求依赖前面的结果
+	tests := []struct {
+		name        string
+		messageType MessageType
+		data        string
+		want        i18n.Code
+	}{
+		{name: "数据格式错误", messageType: MSG_SIT_DOWN, data: `"seat"`, want: i18n.ErrBadRequest},
+		{name: "未知的消息类型", messageType: "dance", want: i18n.ErrUnknownType},
+		{name: "座位号无效", messageType: MSG_SIT_DOWN, data: `{"seatId":99}`, want: i18n.ErrInvalidSeat},
+		{name: "没有落座时准备", messageType: MSG_READY, want: i18n.ErrNotSeated},
+		{name: "落座", messageType: MSG_SIT_DOWN, data: `{"seatId":1}`},
+		{name: "重复落座", messageType: MSG_SIT_DOWN, data: `{"seatId":2}`, want: i18n.ErrAlreadySeated},
+		{name: "没有开局时行动", messageType: MSG_FOLD, want: i18n.ErrGameNotPlaying},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := fmt.Sprintf("req-%d", i+1)
+			message := ClientMessage{Type: tt.messageType, ID: id}
+			if tt.data != "" {
+				message.Data = json.RawMessage(tt.data)
+			}
+			if err := client.conn.WriteJSON(message); err != nil {
+				t.Fatalf("发送请求失败: %v", err)
+			}
+
+			select {
+			case reply := <-client.replies:
+				var data ErrorData
+				if err := json.Unmarshal(reply.Data, &data); err != nil {
+					t.Fatalf("解析回复失败: %v", err)
+				}
+				// 回复带有请求的ID和类型，成功时是确认，失败时是带错误码和消息的错误
+				if data.ID != id || data.Type != tt.messageType {
+					t.Errorf("回复的请求 = %s %s, 期望 %s %s", data.ID, data.Type, id, tt.messageType)
+				}
+				if data.Code != tt.want {
+					t.Errorf("错误码 = %q, 期望 %q", data.Code, tt.want)
+				}
+				if wantType := map[bool]MessageType{true: MSG_ACK, false: MSG_ERROR}[tt.want == ""]; reply.Type != wantType {
+					t.Errorf("回复类型 = %s, 期望 %s", reply.Type, wantType)
+				}
+				if tt.want != "" && data.Message == "" {
+					t.Errorf("错误消息为空")
+				}
+			case <-time.After(testWaitTimeout):
+				t.Fatalf("等待回复超时")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"log"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// handleAddChips 处理补码和加码请求，只能在两局之间进行
+// 请求数据中的 amount 是补充的筹码数，不指定时补足到最大买入
+func (c *Client) handleAddChips(kind string, req AddChipsRequest) error {
+	if !c.betweenHands() {
+		return requestError(i18n.ErrGameInProgress)
+	}
+
+	player, _ := c.hub.seatOf(c.user.ID)
+	if player == nil {
+		return requestError(i18n.ErrNotSeated)
+	}
+
+	amount, err := c.hub.addChipsAmount(player, kind, req.Amount)
+	if err != nil {
+		return err
+	}
+
+	txType := TxTopUp
+	if kind == poker.ChipsRebuy {
+		txType = TxRebuy
+	}
+	if _, err := adjustBankroll(c.user.ID, c.hub.id, txType, -amount); err != nil {
+		return err
+	}
+
+	player.AddChips(kind, amount)
+	log.Printf("[WS] 补充筹码成功 - %s, 方式: %s, 筹码: %d, 当前筹码: %d\n", c.user, kind, amount, player.Chips)
+
+	c.hub.broadcastGameState()
+	return nil
+}
+
+// addChipsAmount 确定补充的筹码数，requested 为 0 时补足到最大买入，资金不够时尽量多补
+// 筹码少于最小买入时只能补码，补码后不少于最小买入，次数受 MaxRebuys 限制；
+// 筹码不少于最小买入时只能加码，两者补充后都不能超过最大买入
+func (h *Hub) addChipsAmount(player *poker.Player, kind string, requested int) (int, error) {
+	config := h.game.Config
+	user, ok := GetUser(player.UserId)
+	if !ok {
+		return 0, requestError(i18n.ErrUserNotFound)
+	}
+
+	minAmount := 1
+	switch kind {
+	case poker.ChipsRebuy:
+		if player.Chips >= config.MinBuyIn {
+			return 0, requestError(i18n.ErrRebuyNotAllowed, config.MinBuyIn)
+		}
+		if player.Rebuys >= config.MaxRebuys {
+			return 0, requestError(i18n.ErrRebuyLimit, config.MaxRebuys)
+		}
+		minAmount = config.MinBuyIn - player.Chips
+	case poker.ChipsTopUp:
+		if player.Chips < config.MinBuyIn {
+			return 0, requestError(i18n.ErrTopUpNotAllowed, config.MinBuyIn)
+		}
+		if player.Chips >= config.MaxBuyIn {
+			return 0, requestError(i18n.ErrAtMaxBuyIn, config.MaxBuyIn)
+		}
+	default:
+		return 0, requestError(i18n.ErrBadRequest)
+	}
+	maxAmount := config.MaxBuyIn - player.Chips
+
+	amount := requested
+	if amount == 0 {
+		amount = min(maxAmount, user.Bankroll)
+	}
+	if amount < minAmount || amount > maxAmount {
+		return 0, requestError(i18n.ErrInvalidAddChips, minAmount, maxAmount)
+	}
+	if amount > user.Bankroll {
+		return 0, requestError(i18n.ErrInsufficientFunds, user.Bankroll)
+	}
+	return amount, nil
+}
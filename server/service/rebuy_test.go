@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/lllllan02/holdem/i18n"
+	"github.com/lllllan02/holdem/poker"
+)
+
+func TestAddChipsAmount(t *testing.T) {
+	initTestStores(t)
+	hub := createTestTable(t, TableOptions{})
+	rich := CreateGuestUser("10.0.0.1", "test")
+	poor := CreateGuestUser("10.0.0.2", "test")
+	if _, err := BuyIn(poor.ID, "t", initialBankroll-300); err != nil {
+		t.Fatalf("买入失败: %v", err)
+	}
+
+	// 默认配置的买入范围是 400 到 1000，最多补码 3 次
+	tests := []struct {
+		name      string
+		userID    string
+		chips     int
+		rebuys    int
+		kind      string
+		requested int
+		want      int
+		code      i18n.Code
+	}{
+		{name: "输光后补码到最大买入", userID: rich.ID, kind: poker.ChipsRebuy, want: 1000},
+		{name: "补码到最小买入", userID: rich.ID, chips: 100, kind: poker.ChipsRebuy, requested: 300, want: 300},
+		{name: "补码后少于最小买入", userID: rich.ID, chips: 100, kind: poker.ChipsRebuy, requested: 200, code: i18n.ErrInvalidAddChips},
+		{name: "补码后超过最大买入", userID: rich.ID, chips: 100, kind: poker.ChipsRebuy, requested: 1000, code: i18n.ErrInvalidAddChips},
+		{name: "筹码不少于最小买入时不能补码", userID: rich.ID, chips: 400, kind: poker.ChipsRebuy, code: i18n.ErrRebuyNotAllowed},
+		{name: "补码次数达到上限", userID: rich.ID, rebuys: 3, kind: poker.ChipsRebuy, code: i18n.ErrRebuyLimit},
+		{name: "资金不足时补码全部资金", userID: poor.ID, chips: 200, kind: poker.ChipsRebuy, want: 300},
+		{name: "资金不够补到最小买入", userID: poor.ID, kind: poker.ChipsRebuy, code: i18n.ErrInvalidAddChips},
+		{name: "资金不足", userID: poor.ID, chips: 200, kind: poker.ChipsRebuy, requested: 400, code: i18n.ErrInsufficientFunds},
+		{name: "加码到最大买入", userID: rich.ID, chips: 700, kind: poker.ChipsTopUp, want: 300},
+		{name: "加码指定金额", userID: rich.ID, chips: 700, kind: poker.ChipsTopUp, requested: 100, want: 100},
+		{name: "加码超过最大买入", userID: rich.ID, chips: 700, kind: poker.ChipsTopUp, requested: 400, code: i18n.ErrInvalidAddChips},
+		{name: "筹码少于最小买入时不能加码", userID: rich.ID, chips: 300, kind: poker.ChipsTopUp, code: i18n.ErrTopUpNotAllowed},
+		{name: "已达到最大买入", userID: rich.ID, chips: 1000, kind: poker.ChipsTopUp, code: i18n.ErrAtMaxBuyIn},
+		{name: "未知的方式", userID: rich.ID, kind: "bonus", code: i18n.ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := &poker.Player{UserId: tt.userID, Chips: tt.chips, Rebuys: tt.rebuys}
+			var amount int
+			var err error
+			hub.call(func() { amount, err = hub.addChipsAmount(player, tt.kind, tt.requested) })
+			if code := errorCode(err); code != tt.code {
+				t.Fatalf("错误 = %v, 期望 %q", err, tt.code)
+			}
+			if amount != tt.want {
+				t.Errorf("补充筹码 = %d, 期望 %d", amount, tt.want)
+			}
+		})
+	}
+}
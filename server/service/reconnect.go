@@ -0,0 +1,230 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// generateResumeToken 生成随机的会话恢复令牌
+func generateResumeToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// seatOf 返回用户所在的座位，未落座时返回 nil
+func (h *Hub) seatOf(userID string) (*poker.Player, int) {
+	for i := range h.game.Players {
+		if h.game.Players[i].UserId == userID && !h.game.Players[i].IsEmpty() {
+			return &h.game.Players[i], i
+		}
+	}
+	return nil, -1
+}
+
+// resumeSession 新连接注册后恢复用户的会话
+// 每次接管座位的连接都会换发新的恢复令牌，客户端带着上一次的令牌重连时视为恢复原会话；
+// 只有恢复原会话的连接才能接管座位并收到手牌，随后发送最近的聊天消息和完整状态。
+// 没有有效令牌的连接作为新会话观战，原座位按断线处理，宽限期结束后才由新会话接管
+func (h *Hub) resumeSession(client *Client) {
+	userID := client.user.ID
+	resumed := client.resumeToken != "" && client.resumeToken == h.resumeTokens[userID]
+	session := SessionData{
+		Protocol:    client.protocol,
+		MaxProtocol: ProtocolLatest,
+		ResumeToken: generateResumeToken(),
+		Resumed:     resumed,
+		Seat:        -1,
+	}
+	player, pos := h.seatOf(userID)
+	reconnected, detach := false, false
+	if player != nil {
+		if resumed || (player.Disconnected && !h.awaitingReconnect(player)) {
+			session.Seat = pos
+			session.HoleCards = player.HoleCards
+			if player.Disconnected {
+				h.reconnectPlayer(player)
+				reconnected = true
+			}
+		} else {
+			client.detached = true
+			detach = !player.Disconnected
+		}
+	}
+	// 没有接管座位的连接不能让原会话的令牌失效
+	if !client.detached {
+		h.resumeTokens[userID] = session.ResumeToken
+	}
+	log.Printf("[Hub] 建立会话 - %s, 恢复: %v, 座位: %d, 接管座位: %v", client.user, resumed, pos, !client.detached)
+
+	client.sendSession(session)
+	client.sendChatHistory()
+	switch {
+	case detach:
+		// 旧连接已被关闭，座位进入断线宽限期，等待持有令牌的连接恢复
+		h.disconnectPlayer(userID)
+	case reconnected:
+		h.broadcastGameState()
+	default:
+		client.sendGameState()
+	}
+}
+
+// attachDetached 宽限期结束后，由没有令牌的新会话接管座位
+func (h *Hub) attachDetached(userID string) {
+	client, ok := h.clients[userID]
+	if !ok || !client.detached {
+		return
+	}
+	client.detached = false
+
+	session := SessionData{
+		Protocol:    client.protocol,
+		MaxProtocol: ProtocolLatest,
+		ResumeToken: generateResumeToken(),
+		Seat:        -1,
+	}
+	h.resumeTokens[userID] = session.ResumeToken
+	if player, pos := h.seatOf(userID); player != nil {
+		session.Seat = pos
+		session.HoleCards = player.HoleCards
+		h.reconnectPlayer(player)
+	}
+	log.Printf("[Hub] 新会话接管座位 - %s, 座位: %d", client.user, session.Seat)
+	client.sendSession(session)
+}
+
+// disconnectPlayer 落座的玩家断线后在宽限期内保留座位、手牌和行动机会
+// 宽限期内轮到该玩家时行动计时暂停，宽限期结束后按配置自动弃牌或暂时离开
+func (h *Hub) disconnectPlayer(userID string) {
+	player, pos := h.seatOf(userID)
+	if player == nil || player.Disconnected {
+		return
+	}
+	player.Disconnected = true
+
+	grace := h.game.Config.DisconnectGrace
+	log.Printf("[Hub] 玩家断线 - %s, 座位: %d, 宽限期: %d秒", player.Name, pos+1, grace)
+	if grace == 0 {
+		h.expireGrace(userID)
+		return
+	}
+
+	h.cancelGraceTimer(userID)
+	var timer *hubTimer
+	timer = h.startTimer(time.Duration(grace)*time.Second, func() bool {
+		if h.graceTimers[userID] == timer {
+			delete(h.graceTimers, userID)
+			h.expireGrace(userID)
+		}
+		return false
+	})
+	h.graceTimers[userID] = timer
+	h.broadcastGameState()
+}
+
+// reconnectPlayer 断线的玩家重新连接，停止宽限期计时，暂停的行动计时继续倒数
+func (h *Hub) reconnectPlayer(player *poker.Player) {
+	player.Disconnected = false
+	h.cancelGraceTimer(player.UserId)
+	log.Printf("[Hub] 玩家重新连接 - %s", player.Name)
+}
+
+// expireGrace 宽限期结束仍未重连，按配置处理断线的玩家
+func (h *Hub) expireGrace(userID string) {
+	player, _ := h.seatOf(userID)
+	if player == nil || !player.Disconnected {
+		return
+	}
+
+	switch h.game.Config.DisconnectAction {
+	case poker.DisconnectSitOut:
+		log.Printf("[Hub] 断线宽限期结束，转为暂时离开 - %s", player.Name)
+		if err := h.game.SetSittingOut(userID, true); err != nil {
+			log.Printf("[Hub] 设置暂时离开失败 - %s, 原因: %v", player.Name, err)
+		}
+	default:
+		log.Printf("[Hub] 断线宽限期结束，自动过牌或弃牌 - %s", player.Name)
+		h.readyDisconnectedPlayers()
+	}
+
+	// 正轮到该玩家时立即代为行动
+	if h.isCurrentPlayer(userID) {
+		h.actionTurn = ""
+	}
+
+	// 用户已经通过新会话连接时，由新会话接管处理后的座位
+	h.attachDetached(userID)
+
+	// 其余玩家都已准备时开始倒计时
+	readyCount, totalCount := h.game.GetReadyPlayersCount()
+	if h.countdownTimer == nil && totalCount >= h.game.Config.MinPlayers && readyCount == totalCount &&
+		(h.game.GamePhase == poker.GamePhaseShowdown || h.game.CanStartGame()) {
+		h.startCountdown()
+	}
+	h.broadcastGameState()
+}
+
+// awaitingReconnect 检查断线的玩家是否仍在宽限期内
+func (h *Hub) awaitingReconnect(player *poker.Player) bool {
+	return player.Disconnected && h.graceTimers[player.UserId] != nil
+}
+
+// readyDisconnectedPlayers 宽限期已过且配置为自动弃牌的玩家在两局之间自动准备，不阻止开局
+// 筹码输光的断线玩家自动离座。在宽限期结束和可能结束一局的行动、摊牌之后调用，游戏进行中不做处理
+func (h *Hub) readyDisconnectedPlayers() {
+	if h.game.Config.DisconnectAction != poker.DisconnectFold {
+		return
+	}
+	if h.game.GameStatus == poker.GameStatusPlaying && h.game.GamePhase != poker.GamePhaseShowdown {
+		return
+	}
+	for i := range h.game.Players {
+		player := &h.game.Players[i]
+		if player.IsEmpty() || !player.Disconnected || h.awaitingReconnect(player) {
+			continue
+		}
+		if player.Chips <= 0 {
+			log.Printf("[Hub] 断线玩家筹码输光，自动离座 - %s", player.Name)
+			h.vacateSeat(player)
+			continue
+		}
+		if !player.IsReady && !player.SittingOut {
+			h.game.SetPlayerReady(player.UserId, true)
+		}
+	}
+}
+
+// isCurrentPlayer 检查是否正轮到该用户行动
+func (h *Hub) isCurrentPlayer(userID string) bool {
+	pos := h.game.CurrentPlayer
+	return h.game.GameStatus == poker.GameStatusPlaying && pos >= 0 && pos < len(h.game.Players) &&
+		h.game.Players[pos].UserId == userID
+}
+
+// cancelGraceTimer 停止用户的断线宽限期计时
+func (h *Hub) cancelGraceTimer(userID string) {
+	if timer, ok := h.graceTimers[userID]; ok {
+		timer.Stop()
+		delete(h.graceTimers, userID)
+	}
+}
+
+// cancelGraceTimers 停止所有断线宽限期计时，牌桌关闭时调用
+func (h *Hub) cancelGraceTimers() {
+	for userID := range h.graceTimers {
+		h.cancelGraceTimer(userID)
+	}
+}
+
+// sendSession 发送会话信息给客户端
+func (c *Client) sendSession(session SessionData) {
+	c.sendMessage(WSMessage{Type: MSG_SESSION, Data: session})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// waitSeat 在 hub 协程中检查座位，直到满足条件或超时
+func waitSeat(hub *Hub, pos int, match func(*poker.Player) bool) error {
+	deadline := time.Now().Add(testWaitTimeout)
+	for time.Now().Before(deadline) {
+		var ok bool
+		hub.call(func() { ok = match(&hub.game.Players[pos]) })
+		if ok {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("等待座位%d 的状态超时", pos+1)
+}
+
+// waitSession 等待满足条件的会话信息
+func waitSession(c *testClient, match func(*SessionData) bool) (*SessionData, error) {
+	deadline := time.Now().Add(testWaitTimeout)
+	for time.Now().Before(deadline) {
+		if session := c.currentSession(); session != nil && match(session) {
+			return session, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("%s 等待会话信息超时", c.user.ID)
+}
+
+func TestResumeSession(t *testing.T) {
+	disconnected := func(p *poker.Player) bool { return p.Disconnected }
+
+	tests := []struct {
+		name   string
+		grace  int
+		action string // 宽限期结束后的处理，为空时暂时离开
+		// run 在玩家坐到第一个座位后执行，返回最后建立的连接，没有时返回 nil
+		run          func(table *testTable, player *testClient) (*testClient, error)
+		wantResumed  bool
+		wantSeat     int
+		wantSitOut   bool // 宽限期结束后是否转为暂时离开
+		wantReady    bool // 宽限期结束后是否自动准备
+		wantAttached bool // 最终的连接是否接管了座位
+	}{
+		{
+			name:  "断线后带令牌重连",
+			grace: 30,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				token := player.currentSession().ResumeToken
+				player.conn.Close()
+				if err := waitSeat(table.hub, 0, disconnected); err != nil {
+					return nil, err
+				}
+				return table.dial(player.user, token)
+			},
+			wantResumed:  true,
+			wantSeat:     0,
+			wantAttached: true,
+		},
+		{
+			// 每次恢复都会换发新令牌，旧令牌不能再用
+			name:  "使用过的令牌",
+			grace: 30,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				token := player.currentSession().ResumeToken
+				player.conn.Close()
+				resumed, err := table.dial(player.user, token)
+				if err != nil {
+					return nil, err
+				}
+				resumed.conn.Close()
+				if err := waitSeat(table.hub, 0, disconnected); err != nil {
+					return nil, err
+				}
+				return table.dial(player.user, token)
+			},
+			wantSeat: -1,
+		},
+		{
+			name:  "宽限期内没有令牌的新连接只能观战",
+			grace: 30,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				return table.dial(player.user, "")
+			},
+			wantSeat: -1,
+		},
+		{
+			name:  "宽限期结束后由新连接接管",
+			grace: 1,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				client, err := table.dial(player.user, "")
+				if err != nil {
+					return nil, err
+				}
+				_, err = waitSession(client, func(s *SessionData) bool { return s.Seat == 0 })
+				return client, err
+			},
+			wantSeat:     0,
+			wantSitOut:   true,
+			wantAttached: true,
+		},
+		{
+			name:  "宽限期结束后没有重连",
+			grace: 1,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				player.conn.Close()
+				err := waitSeat(table.hub, 0, func(p *poker.Player) bool { return p.SittingOut })
+				return nil, err
+			},
+			wantSeat:   -1,
+			wantSitOut: true,
+		},
+		{
+			// 配置为自动弃牌时，断线的玩家在两局之间自动准备，不阻止开局
+			name:   "宽限期结束后自动准备",
+			grace:  1,
+			action: poker.DisconnectFold,
+			run: func(table *testTable, player *testClient) (*testClient, error) {
+				player.conn.Close()
+				err := waitSeat(table.hub, 0, func(p *poker.Player) bool { return p.IsReady })
+				return nil, err
+			},
+			wantSeat:  -1,
+			wantReady: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newTestTable(t)
+			table.hub.call(func() {
+				table.hub.game.Config.DisconnectGrace = tt.grace
+				if tt.action != "" {
+					table.hub.game.Config.DisconnectAction = tt.action
+				}
+			})
+
+			user := CreateGuestUser("10.0.0.1", "holdem-test")
+			player, err := table.dial(user, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := player.mustSucceed(MSG_SIT_DOWN, SitDownRequest{SeatID: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := tt.run(table, player)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client != nil {
+				defer client.conn.Close()
+				session := client.currentSession()
+				if session.Resumed != tt.wantResumed || session.Seat != tt.wantSeat {
+					t.Errorf("会话 恢复 %v，座位 %d，期望 %v、%d", session.Resumed, session.Seat, tt.wantResumed, tt.wantSeat)
+				}
+			}
+
+			var seat poker.Player
+			table.hub.call(func() { seat = table.hub.game.Players[0] })
+			if seat.UserId != user.ID {
+				t.Fatalf("座位被释放了")
+			}
+			if seat.SittingOut != tt.wantSitOut {
+				t.Errorf("暂时离开 = %v, 期望 %v", seat.SittingOut, tt.wantSitOut)
+			}
+			if seat.IsReady != tt.wantReady {
+				t.Errorf("已准备 = %v, 期望 %v", seat.IsReady, tt.wantReady)
+			}
+			if attached := !seat.Disconnected; attached != tt.wantAttached {
+				t.Errorf("座位已连接 = %v, 期望 %v", attached, tt.wantAttached)
+			}
+		})
+	}
+}
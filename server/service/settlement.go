@@ -0,0 +1,355 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettlementPlayer 玩家在一场牌桌中的输赢
+type SettlementPlayer struct {
+	UserID  string  `json:"userId"`  // 用户ID
+	Name    string  `json:"name"`    // 用户名
+	BuyIn   int     `json:"buyIn"`   // 买入的筹码总数
+	CashOut int     `json:"cashOut"` // 兑现的筹码总数
+	Stack   int     `json:"stack"`   // 仍在座位上的筹码
+	Net     int     `json:"net"`     // 筹码净输赢
+	Amount  float64 `json:"amount"`  // 按比例换算的金额
+}
+
+// Transfer 一笔结算转账，From 向 To 支付
+type Transfer struct {
+	From     string  `json:"from"`     // 付款的用户ID
+	FromName string  `json:"fromName"` // 付款的用户名
+	To       string  `json:"to"`       // 收款的用户ID
+	ToName   string  `json:"toName"`   // 收款的用户名
+	Chips    int     `json:"chips"`    // 筹码数
+	Amount   float64 `json:"amount"`   // 金额
+}
+
+// Settlement 牌桌一场游戏的结算报告
+// 仍有玩家落座时按他们当前的筹码计算，结果会随游戏继续而变化
+type Settlement struct {
+	TableID   string              `json:"tableId"`   // 牌桌ID
+	Since     int64               `json:"since"`     // 统计的起始时间（毫秒时间戳）
+	Rate      float64             `json:"rate"`      // 每个筹码对应的金额
+	Final     bool                `json:"final"`     // 是否所有玩家都已离座
+	Imbalance int                 `json:"imbalance"` // 所有玩家净输赢之和，正常为 0
+	Players   []*SettlementPlayer `json:"players"`   // 按净输赢从高到低排序
+	Transfers []*Transfer         `json:"transfers"` // 结算转账
+}
+
+// SettlementRequest 结算报告的查询参数
+type SettlementRequest struct {
+	Rate   float64 `form:"rate"`   // 每个筹码对应的金额，默认为 1
+	Format string  `form:"format"` // json 或 text，默认为 json
+}
+
+// ComputeSettlement 根据牌桌的资金流水和仍在座位上的筹码计算每个玩家的输赢和结算转账
+// 流水中资金减少的（买入、补码）计入买入，资金增加的计入兑现
+func ComputeSettlement(tableID string, transactions []*Transaction, stacks map[string]int, rate float64) *Settlement {
+	players := make(map[string]*SettlementPlayer)
+	player := func(userID string) *SettlementPlayer {
+		p, ok := players[userID]
+		if !ok {
+			p = &SettlementPlayer{UserID: userID, Name: userID}
+			if user, ok := GetUser(userID); ok {
+				p.Name = user.Name
+			}
+			players[userID] = p
+		}
+		return p
+	}
+
+	for _, tx := range transactions {
+		if tx.Amount < 0 {
+			player(tx.UserID).BuyIn -= tx.Amount
+		} else {
+			player(tx.UserID).CashOut += tx.Amount
+		}
+	}
+	for userID, chips := range stacks {
+		player(userID).Stack += chips
+	}
+
+	settlement := &Settlement{
+		TableID:   tableID,
+		Rate:      rate,
+		Final:     len(stacks) == 0,
+		Players:   make([]*SettlementPlayer, 0, len(players)),
+		Transfers: make([]*Transfer, 0),
+	}
+	for _, p := range players {
+		p.Net = p.CashOut + p.Stack - p.BuyIn
+		p.Amount = roundCents(float64(p.Net) * rate)
+		settlement.Imbalance += p.Net
+		settlement.Players = append(settlement.Players, p)
+	}
+	sort.Slice(settlement.Players, func(i, j int) bool {
+		if settlement.Players[i].Net != settlement.Players[j].Net {
+			return settlement.Players[i].Net > settlement.Players[j].Net
+		}
+		return settlement.Players[i].UserID < settlement.Players[j].UserID
+	})
+
+	settlement.Transfers = settleTransfers(settlement.Players, rate)
+	return settlement
+}
+
+// settleExactLimit 精确计算最少转账的最大人数，计算量随人数指数增长
+const settleExactLimit = 16
+
+// settleTransfers 计算结算转账，使转账笔数最少
+// 把有输赢的玩家分成尽可能多的净输赢之和为 0 的组，每组 k 人用 k-1 笔转账结清，
+// 总笔数即为最少。有输赢的玩家超过 settleExactLimit 人时不再分组，
+// 直接在所有人之间结算，最多 n-1 笔，不保证最少
+// players 需按净输赢从高到低排序
+func settleTransfers(players []*SettlementPlayer, rate float64) []*Transfer {
+	var nonzero []*SettlementPlayer
+	for _, p := range players {
+		if p.Net != 0 {
+			nonzero = append(nonzero, p)
+		}
+	}
+
+	transfers := make([]*Transfer, 0)
+	for _, group := range zeroSumGroups(nonzero) {
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Net != group[j].Net {
+				return group[i].Net > group[j].Net
+			}
+			return group[i].UserID < group[j].UserID
+		})
+		transfers = append(transfers, greedyTransfers(group, rate)...)
+	}
+	return transfers
+}
+
+// zeroSumGroups 把玩家分成尽可能多的净输赢之和为 0 的组
+// best[mask] 为 mask 中的玩家依次加入时前缀和为 0 的最多次数，即最多能分成的组数
+// 账目不平时最后剩下的一组之和不为 0
+func zeroSumGroups(players []*SettlementPlayer) [][]*SettlementPlayer {
+	n := len(players)
+	if n == 0 {
+		return nil
+	}
+	if n > settleExactLimit {
+		return [][]*SettlementPlayer{players}
+	}
+
+	full := 1<<n - 1
+	sum := make([]int, full+1)
+	best := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		sum[mask] = sum[mask&(mask-1)] + players[bits.TrailingZeros(uint(mask))].Net
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				best[mask] = max(best[mask], best[mask^1<<i])
+			}
+		}
+		if sum[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	// 从全部玩家开始逐个移除，和为 0 的前缀之间的玩家组成一组
+	var groups [][]*SettlementPlayer
+	var group []*SettlementPlayer
+	for mask := full; mask != 0; {
+		want := best[mask]
+		if sum[mask] == 0 {
+			want--
+		}
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && best[mask^1<<i] == want {
+				group = append(group, players[i])
+				mask ^= 1 << i
+				break
+			}
+		}
+		if sum[mask] == 0 {
+			groups = append(groups, group)
+			group = nil
+		}
+	}
+	return groups
+}
+
+// greedyTransfers 每次由输得最多的玩家向赢得最多的玩家支付两者中较小的数额，
+// 每笔转账至少结清一个玩家，n 个玩家最多 n-1 笔转账
+// players 需按净输赢从高到低排序
+func greedyTransfers(players []*SettlementPlayer, rate float64) []*Transfer {
+	type balance struct {
+		player *SettlementPlayer
+		chips  int
+	}
+	var winners, losers []*balance
+	for _, p := range players {
+		if p.Net > 0 {
+			winners = append(winners, &balance{p, p.Net})
+		} else if p.Net < 0 {
+			losers = append(losers, &balance{p, -p.Net})
+		}
+	}
+	sort.SliceStable(losers, func(i, j int) bool { return losers[i].chips > losers[j].chips })
+
+	var transfers []*Transfer
+	for len(winners) > 0 && len(losers) > 0 {
+		winner, loser := winners[0], losers[0]
+		chips := min(winner.chips, loser.chips)
+		transfers = append(transfers, &Transfer{
+			From:     loser.player.UserID,
+			FromName: loser.player.Name,
+			To:       winner.player.UserID,
+			ToName:   winner.player.Name,
+			Chips:    chips,
+			Amount:   roundCents(float64(chips) * rate),
+		})
+
+		winner.chips -= chips
+		loser.chips -= chips
+		if winner.chips == 0 {
+			winners = winners[1:]
+		}
+		if loser.chips == 0 {
+			losers = losers[1:]
+		}
+
+		// 保持按剩余数额从大到小排序
+		sort.SliceStable(winners, func(i, j int) bool { return winners[i].chips > winners[j].chips })
+		sort.SliceStable(losers, func(i, j int) bool { return losers[i].chips > losers[j].chips })
+	}
+	return transfers
+}
+
+// Text 生成纯文本格式的结算报告
+func (s *Settlement) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "牌桌 %s 结算，1 筹码 = %s\n", s.TableID, formatAmount(s.Rate))
+	if s.Since > 0 {
+		fmt.Fprintf(&b, "统计自 %s\n", time.UnixMilli(s.Since).Format("2006-01-02 15:04:05"))
+	}
+	if !s.Final {
+		b.WriteString("仍有玩家在座，按当前筹码计算\n")
+	}
+	b.WriteString("\n")
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "玩家\t买入\t兑现\t在座\t净输赢\t金额\t")
+	for _, p := range s.Players {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%+d\t%s\t\n", p.Name, p.BuyIn, p.CashOut, p.Stack, p.Net, formatAmount(p.Amount))
+	}
+	w.Flush()
+
+	b.WriteString("\n")
+	if len(s.Transfers) == 0 {
+		b.WriteString("无需转账\n")
+	} else {
+		b.WriteString("转账:\n")
+		for _, t := range s.Transfers {
+			fmt.Fprintf(&b, "  %s -> %s: %s（%d 筹码）\n", t.FromName, t.ToName, formatAmount(t.Amount), t.Chips)
+		}
+	}
+	if s.Imbalance != 0 {
+		fmt.Fprintf(&b, "\n注意: 净输赢之和为 %+d 筹码，账目不平\n", s.Imbalance)
+	}
+	return b.String()
+}
+
+// roundCents 金额保留两位小数
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// formatAmount 格式化金额，整数不显示小数
+func formatAmount(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// seatedStacks 返回落座玩家的筹码，游戏进行中按本局开始时的筹码计算，只能在 hub 协程中调用
+func (h *Hub) seatedStacks() map[string]int {
+	stacks := make(map[string]int)
+	for _, player := range h.game.Players {
+		if !player.IsEmpty() {
+			stacks[player.UserId] += player.Chips + player.TotalBet
+		}
+	}
+	return stacks
+}
+
+// SettlementHandler 获取牌桌结算报告的处理函数
+// 牌桌仍存在时统计它创建以来的流水，已关闭的牌桌统计该ID下的全部流水
+// 只有房主、在座的玩家和在该牌桌有资金流水的用户可以查看
+func SettlementHandler(c *gin.Context) {
+	user := currentUser(c)
+	var req SettlementRequest
+	if err := c.ShouldBindQuery(&req); err != nil || req.Rate < 0 {
+		c.JSON(400, gin.H{"error": "Invalid request parameters"})
+		return
+	}
+	if req.Rate == 0 {
+		req.Rate = 1
+	}
+
+	tableID := c.Param("id")
+	since := time.UnixMilli(0)
+	var stacks map[string]int
+	isHost := false
+	if hub, ok := tables.Get(tableID); ok {
+		since = hub.createdAt
+		hub.call(func() {
+			stacks = hub.seatedStacks()
+			isHost = hub.isHost(user.ID)
+		})
+	}
+
+	transactions, err := userStore.ListTableTransactions(tableID, since)
+	if err != nil {
+		log.Printf("[API] Settlement - 获取资金流水失败: %v", err)
+		c.JSON(500, gin.H{"error": "Failed to get ledger"})
+		return
+	}
+	if len(transactions) == 0 && len(stacks) == 0 {
+		c.JSON(404, gin.H{"error": "No transactions for this table"})
+		return
+	}
+	if !isHost && !inSettlement(user.ID, transactions, stacks) {
+		c.JSON(403, gin.H{"error": "只有牌桌上的玩家可以查看结算"})
+		return
+	}
+
+	settlement := ComputeSettlement(tableID, transactions, stacks, req.Rate)
+	settlement.Since = since.UnixMilli()
+
+	switch req.Format {
+	case "", "json":
+		c.JSON(200, settlement)
+	case "text":
+		c.Data(200, "text/plain; charset=utf-8", []byte(settlement.Text()))
+	default:
+		c.JSON(400, gin.H{"error": "Invalid format"})
+	}
+}
+
+// inSettlement 用户是否在座或在牌桌有资金流水
+func inSettlement(userID string, transactions []*Transaction, stacks map[string]int) bool {
+	if _, ok := stacks[userID]; ok {
+		return true
+	}
+	for _, tx := range transactions {
+		if tx.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// settlementPlayers 按给定的净输赢生成按从高到低排序的玩家
+func settlementPlayers(nets ...int) []*SettlementPlayer {
+	players := make([]*SettlementPlayer, 0, len(nets))
+	for i, net := range nets {
+		id := fmt.Sprintf("p%02d", i)
+		players = append(players, &SettlementPlayer{UserID: id, Name: id, Net: net})
+	}
+	sort.SliceStable(players, func(i, j int) bool { return players[i].Net > players[j].Net })
+	return players
+}
+
+func TestSettleTransfersMinimal(t *testing.T) {
+	tests := []struct {
+		name      string
+		nets      []int
+		transfers int
+	}{
+		{"无人输赢", []int{0, 0}, 0},
+		{"一对一", []int{100, -100}, 1},
+		{"一人赢多人输", []int{30, -10, -10, -10}, 3},
+		{"两对相等", []int{5, 5, -5, -5}, 2},
+		// 按数额从大到小结算需要 4 笔: 7<-5, 5<-4, 2<-3, 1<-3
+		{"优于贪心", []int{7, 5, -5, -4, -3}, 3},
+		{"三组", []int{10, -10, 6, -4, -2, 3, -1, -1, -1}, 6},
+		{"无法分组", []int{8, 3, -6, -5}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := settlementPlayers(tt.nets...)
+			transfers := settleTransfers(players, 1)
+			if len(transfers) != tt.transfers {
+				t.Errorf("转账笔数 = %d, 期望 %d", len(transfers), tt.transfers)
+			}
+			checkSettled(t, players, transfers)
+		})
+	}
+}
+
+// 超过精确计算人数时退回按数额从大到小结算，仍然结清所有人
+func TestSettleTransfersFallback(t *testing.T) {
+	nets := make([]int, 0, settleExactLimit+2)
+	for i := 0; i < cap(nets)/2; i++ {
+		nets = append(nets, i+1, -(i + 1))
+	}
+	players := settlementPlayers(nets...)
+	transfers := settleTransfers(players, 1)
+	if len(transfers) > len(nets)-1 {
+		t.Errorf("转账笔数 = %d, 不应超过 %d", len(transfers), len(nets)-1)
+	}
+	checkSettled(t, players, transfers)
+}
+
+// 账目不平时结清能结清的部分
+func TestSettleTransfersImbalance(t *testing.T) {
+	players := settlementPlayers(10, -10, 5, -3)
+	transfers := settleTransfers(players, 0.5)
+	if len(transfers) != 2 {
+		t.Fatalf("转账笔数 = %d, 期望 2", len(transfers))
+	}
+	for _, transfer := range transfers {
+		if transfer.Amount != float64(transfer.Chips)*0.5 {
+			t.Errorf("%s -> %s 金额 = %v, 筹码 %d", transfer.From, transfer.To, transfer.Amount, transfer.Chips)
+		}
+	}
+}
+
+// checkSettled 检查转账后所有玩家的净输赢都归零
+func checkSettled(t *testing.T, players []*SettlementPlayer, transfers []*Transfer) {
+	t.Helper()
+	balance := make(map[string]int)
+	for _, p := range players {
+		balance[p.UserID] = p.Net
+	}
+	for _, transfer := range transfers {
+		if transfer.Chips <= 0 {
+			t.Errorf("%s -> %s 转账筹码 = %d", transfer.From, transfer.To, transfer.Chips)
+		}
+		balance[transfer.From] += transfer.Chips
+		balance[transfer.To] -= transfer.Chips
+	}
+	for userID, chips := range balance {
+		if chips != 0 {
+			t.Errorf("%s 转账后剩余 %d", userID, chips)
+		}
+	}
+}
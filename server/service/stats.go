@@ -0,0 +1,148 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// UserStatsRequest 玩家统计的查询参数，日期格式为 2006-01-02，都为空时统计全部记录
+type UserStatsRequest struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+}
+
+// UserStatsHandler 获取玩家统计数据的处理函数
+func UserStatsHandler(c *gin.Context) {
+	userID := c.Param("id")
+	if _, ok := GetUser(userID); !ok {
+		c.JSON(404, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req UserStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request parameters"})
+		return
+	}
+
+	from := time.Unix(0, 0)
+	to := time.Now().Add(time.Second)
+	if req.From != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.From, time.Local)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid from date"})
+			return
+		}
+		from = date
+	}
+	if req.To != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.To, time.Local)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid to date"})
+			return
+		}
+		to = date.AddDate(0, 0, 1)
+	}
+
+	records, err := poker.GetUserGameRecords(userID, from, to)
+	if err != nil {
+		log.Printf("[API] UserStats - 获取记录失败: %v", err)
+		c.JSON(500, gin.H{"error": "Failed to get game records"})
+		return
+	}
+
+	// 私人牌桌的对局只统计查看者自己也参与了的
+	records = visibleRecords(records, viewerID(c))
+	c.JSON(200, poker.ComputePlayerStats(userID, records))
+}
+
+// updateSeatStats 更新落座玩家的统计数据，随座位一起广播
+// 新落座的玩家在后台从记录中加载统计，之后每结束一局只把这一局累加进去
+// 广播的统计所有人都能看到，因此不计入私人牌桌的对局
+func (h *Hub) updateSeatStats() {
+	// 先把刚结束的一局计入已缓存的玩家，正在加载的玩家等加载完成后再核对
+	if round := h.game.CurrentRound; round != nil && !round.Private && round.RoundID != h.statsRound {
+		h.statsRound = round.RoundID
+		for _, player := range round.Players {
+			if stats, ok := h.stats[player.UserId]; ok {
+				stats.Add(round)
+			} else if pending, ok := h.statsLoading[player.UserId]; ok {
+				h.statsLoading[player.UserId] = append(pending, round)
+			}
+		}
+	}
+
+	seated := make(map[string]bool)
+	for i := range h.game.Players {
+		player := &h.game.Players[i]
+		if player.IsEmpty() {
+			continue
+		}
+		seated[player.UserId] = true
+
+		if stats, ok := h.stats[player.UserId]; ok {
+			player.Stats = stats.Summary()
+		} else {
+			h.loadStats(player.UserId)
+		}
+	}
+
+	// 离开座位的玩家不再缓存，正在加载的结果也会被丢弃
+	for userID := range h.stats {
+		if !seated[userID] {
+			delete(h.stats, userID)
+		}
+	}
+	for userID := range h.statsLoading {
+		if !seated[userID] {
+			delete(h.statsLoading, userID)
+		}
+	}
+}
+
+// loadStats 在后台协程中从记录计算玩家的统计，完成后回到 hub 协程写入缓存
+// 玩家的记录可能很多，读取和计算不能阻塞 hub 协程
+func (h *Hub) loadStats(userID string) {
+	if _, ok := h.statsLoading[userID]; ok {
+		return
+	}
+	h.statsLoading[userID] = nil
+
+	go func() {
+		records, err := poker.GetUserGameRecords(userID, time.Unix(0, 0), time.Now().Add(time.Second))
+		if err != nil {
+			log.Printf("[Hub] 加载玩家统计失败 - 用户: %s, 原因: %v", userID, err)
+		}
+		records = visibleRecords(records, "")
+		stats := poker.ComputePlayerStats(userID, records)
+		loaded := make(map[string]bool, len(records))
+		for _, record := range records {
+			loaded[record.RoundID] = true
+		}
+
+		select {
+		case h.commands <- func() { h.finishLoadStats(userID, stats, loaded) }:
+		case <-h.quit:
+		}
+	}()
+}
+
+// finishLoadStats 写入后台加载的统计并广播，补上加载期间结束、但没有读到记录的对局
+func (h *Hub) finishLoadStats(userID string, stats *poker.PlayerStats, loaded map[string]bool) {
+	pending, ok := h.statsLoading[userID]
+	if !ok {
+		return
+	}
+	delete(h.statsLoading, userID)
+
+	for _, round := range pending {
+		if !loaded[round.RoundID] {
+			stats.Add(round)
+		}
+	}
+	h.stats[userID] = stats
+	h.broadcastGameState()
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lllllan02/holdem/poker"
+)
+
+// saveTestRound 保存一局所有玩家都在翻前跟注的对局记录
+func saveTestRound(t *testing.T, roundID string, private bool, userIDs ...string) {
+	t.Helper()
+	record := &poker.GameRound{
+		RoundID:   roundID,
+		Private:   private,
+		StartTime: time.Now().Unix(),
+		BigBlind:  20,
+	}
+	for i, userID := range userIDs {
+		record.Players = append(record.Players, poker.PlayerRoundInfo{
+			UserId:   userID,
+			Position: i,
+			Status:   poker.PlayerStatusSitting,
+		})
+		record.Actions = append(record.Actions, poker.Action{
+			Street:   poker.GamePhasePreFlop,
+			Position: i,
+			UserId:   userID,
+			Type:     poker.ActionCall,
+		})
+	}
+	if err := poker.SaveGameRecord(record); err != nil {
+		t.Fatalf("保存对局记录失败: %v", err)
+	}
+}
+
+func TestUserStatsPrivateRounds(t *testing.T) {
+	initTestStores(t)
+	player := CreateGuestUser("10.0.0.1", "test")
+	partner := CreateGuestUser("10.0.0.2", "test")
+	other := CreateGuestUser("10.0.0.3", "test")
+
+	saveTestRound(t, "public-00000001", false, player.ID, other.ID)
+	saveTestRound(t, "private-00000001", true, player.ID, partner.ID)
+	saveTestRound(t, "private-00000002", true, player.ID, partner.ID)
+
+	router := gin.New()
+	router.GET("/users/:id/stats", UserStatsHandler)
+
+	tests := []struct {
+		name   string
+		viewer *User
+		hands  int
+	}{
+		{name: "未登录只统计公开牌桌", hands: 1},
+		{name: "没有参与私人牌桌的用户", viewer: other, hands: 1},
+		{name: "一起参与私人牌桌的用户", viewer: partner, hands: 3},
+		{name: "玩家本人", viewer: player, hands: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/users/"+player.ID+"/stats", nil)
+			if tt.viewer != nil {
+				req.Header.Set("Authorization", "Bearer "+IssueToken(tt.viewer.ID))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Fatalf("状态码 = %d, 响应: %s", w.Code, w.Body.String())
+			}
+
+			var stats poker.PlayerStats
+			if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+				t.Fatalf("解析统计失败: %v", err)
+			}
+			if stats.Hands != tt.hands {
+				t.Errorf("手数 = %d, 期望 %d", stats.Hands, tt.hands)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// DefaultTableID 默认牌桌ID，不指定牌桌时加入该牌桌
+const DefaultTableID = "default"
+
+// TableInfo 大厅中展示的牌桌信息
+type TableInfo struct {
+	ID               string    `json:"id"`                   // 牌桌ID
+	Name             string    `json:"name"`                 // 牌桌名称
+	SmallBlind       int       `json:"smallBlind"`           // 小盲注
+	BigBlind         int       `json:"bigBlind"`             // 大盲注
+	Ante             int       `json:"ante"`                 // 前注
+	BettingStructure string    `json:"bettingStructure"`     // 下注结构
+	Seated           int       `json:"seated"`               // 已落座人数
+	MaxSeats         int       `json:"maxSeats"`             // 座位数量
+	Spectators       int       `json:"spectators"`           // 观众数量
+	GameStatus       string    `json:"gameStatus"`           // 游戏状态
+	Private          bool      `json:"private"`              // 是否为私人牌桌
+	HasPassword      bool      `json:"hasPassword"`          // 是否需要密码
+	HostID           string    `json:"hostId"`               // 房主用户ID
+	InviteCode       string    `json:"inviteCode,omitempty"` // 邀请码（只返回给房主）
+	CreatedAt        time.Time `json:"createdAt"`            // 创建时间
+}
+
+// TableOptions 创建牌桌时的附加选项
+type TableOptions struct {
+	HostID   string // 房主用户ID，为空时由第一个落座的玩家担任
+	Private  bool   // 私人牌桌不在大厅中显示，通过邀请码加入
+	Password string // 加入密码（可选）
+}
+
+// TableRegistry 管理所有牌桌，每个牌桌对应一个 hub
+type TableRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]*Hub
+
+	// 私人牌桌邀请码到牌桌ID的映射
+	inviteCodes map[string]string
+
+	// 新建牌桌时使用的基础配置
+	baseConfig poker.TableConfig
+}
+
+// 全局牌桌注册表
+var tables = &TableRegistry{
+	tables:      make(map[string]*Hub),
+	inviteCodes: make(map[string]string),
+	baseConfig:  poker.DefaultTableConfig(),
+}
+
+// InitTables 设置基础牌桌配置并创建默认牌桌
+func InitTables(config poker.TableConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	tables.mu.Lock()
+	tables.baseConfig = config
+	tables.mu.Unlock()
+
+	_, err := tables.create(DefaultTableID, config, TableOptions{})
+	return err
+}
+
+// BaseConfig 返回新建牌桌时使用的基础配置
+func (r *TableRegistry) BaseConfig() poker.TableConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.baseConfig
+}
+
+// Create 按配置创建一个新牌桌并启动它的 hub
+func (r *TableRegistry) Create(config poker.TableConfig, opts TableOptions) (*Hub, error) {
+	return r.create(generateTableID(), config, opts)
+}
+
+// create 使用指定ID创建牌桌
+func (r *TableRegistry) create(id string, config poker.TableConfig, opts TableOptions) (*Hub, error) {
+	hub, err := NewHub(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	hub.hostID = opts.HostID
+	hub.autoHost = opts.HostID == ""
+	hub.private = opts.Private
+	hub.game.Private = opts.Private
+	if opts.Password != "" {
+		if hub.passwordHash, err = hashUserPassword(opts.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	if _, exists := r.tables[id]; exists {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("牌桌已存在: %s", id)
+	}
+	if hub.private {
+		// 生成不重复的邀请码
+		for {
+			hub.inviteCode = generateInviteCode()
+			if _, exists := r.inviteCodes[hub.inviteCode]; !exists {
+				break
+			}
+		}
+		r.inviteCodes[hub.inviteCode] = id
+	}
+	r.tables[id] = hub
+	r.mu.Unlock()
+
+	go hub.Run()
+	log.Printf("[牌桌] 创建牌桌 - ID: %s, 名称: %s, 盲注: %d/%d", id, config.Name, config.SmallBlind, config.BigBlind)
+	return hub, nil
+}
+
+// Get 根据ID获取牌桌
+func (r *TableRegistry) Get(id string) (*Hub, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hub, ok := r.tables[id]
+	return hub, ok
+}
+
+// GetByInviteCode 根据邀请码获取私人牌桌
+func (r *TableRegistry) GetByInviteCode(code string) (*Hub, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.inviteCodes[strings.ToUpper(code)]
+	if !ok {
+		return nil, false
+	}
+	hub, ok := r.tables[id]
+	return hub, ok
+}
+
+// List 列出大厅中所有公开牌桌的信息，按创建时间排序
+func (r *TableRegistry) List() []TableInfo {
+	r.mu.RLock()
+	hubs := make([]*Hub, 0, len(r.tables))
+	for _, hub := range r.tables {
+		if !hub.private {
+			hubs = append(hubs, hub)
+		}
+	}
+	r.mu.RUnlock()
+
+	infos := make([]TableInfo, 0, len(hubs))
+	for _, hub := range hubs {
+		infos = append(infos, hub.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+	return infos
+}
+
+// Close 关闭并移除牌桌，落座的玩家筹码兑现回资金，游戏进行中或默认牌桌不能关闭
+func (r *TableRegistry) Close(id string) error {
+	if id == DefaultTableID {
+		return fmt.Errorf("默认牌桌不能关闭")
+	}
+
+	hub, ok := r.Get(id)
+	if !ok {
+		return fmt.Errorf("牌桌不存在: %s", id)
+	}
+
+	// 兑现筹码需要读写资金，在 hub 协程中进行，不持有注册表的锁
+	var err error
+	hub.call(func() {
+		switch {
+		case hub.closing:
+			err = fmt.Errorf("牌桌正在关闭: %s", id)
+		case hub.game.GameStatus == poker.GameStatusPlaying:
+			err = fmt.Errorf("游戏进行中不能关闭牌桌")
+		default:
+			hub.closing = true
+			hub.cashOutAll()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.tables, id)
+	if hub.inviteCode != "" {
+		delete(r.inviteCodes, hub.inviteCode)
+	}
+	r.mu.Unlock()
+
+	hub.Close()
+	log.Printf("[牌桌] 关闭牌桌 - ID: %s", id)
+	return nil
+}
+
+// generateTableID 生成随机的牌桌ID
+func generateTableID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// 邀请码字符集，去掉了容易混淆的 0/O/1/I
+const inviteCodeChars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateInviteCode 生成6位邀请码
+func generateInviteCode() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return strings.ToUpper(generateTableID()[:6])
+	}
+	for i := range buf {
+		buf[i] = inviteCodeChars[int(buf[i])%len(inviteCodeChars)]
+	}
+	return string(buf)
+}
+
+// canAccess 判断用户能否通过牌桌ID访问牌桌，只能在 hub 协程中调用
+// 私人牌桌只对房主和通过邀请码加入过的用户开放，其他用户必须使用邀请码
+func (h *Hub) canAccess(userID string) bool {
+	return !h.private || h.isHost(userID) || h.members[userID]
+}
+
+// checkPassword 校验牌桌密码，未设置密码的牌桌总是通过
+// 牌桌密码与账号密码使用同样的加盐哈希，校验耗时较长，不能在 hub 协程中调用
+func (h *Hub) checkPassword(password string) bool {
+	if h.passwordHash == "" {
+		return true
+	}
+	return checkUserPassword(password, h.passwordHash)
+}
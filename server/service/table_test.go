@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// createTestTable 创建牌桌，测试结束时关闭
+func createTestTable(t *testing.T, opts TableOptions) *Hub {
+	t.Helper()
+	hub, err := tables.Create(poker.DefaultTableConfig(), opts)
+	if err != nil {
+		t.Fatalf("创建牌桌失败: %v", err)
+	}
+	t.Cleanup(func() { tables.Close(hub.id) })
+	return hub
+}
+
+func TestTablePassword(t *testing.T) {
+	initTestStores(t)
+	hub := createTestTable(t, TableOptions{Password: "secret"})
+	other := createTestTable(t, TableOptions{Password: "secret"})
+	if strings.Contains(hub.passwordHash, "secret") || hub.passwordHash == other.passwordHash {
+		t.Fatalf("相同的密码应该得到加盐的不同哈希: %s, %s", hub.passwordHash, other.passwordHash)
+	}
+
+	open := createTestTable(t, TableOptions{})
+	tests := []struct {
+		name     string
+		hub      *Hub
+		password string
+		want     bool
+	}{
+		{name: "密码正确", hub: hub, password: "secret", want: true},
+		{name: "密码错误", hub: hub, password: "Secret", want: false},
+		{name: "没有提供密码", hub: hub, password: "", want: false},
+		{name: "没有设置密码的牌桌", hub: open, password: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hub.checkPassword(tt.password); got != tt.want {
+				t.Errorf("checkPassword(%q) = %v, 期望 %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableClose(t *testing.T) {
+	initTestStores(t)
+	user := CreateGuestUser("10.0.0.1", "test")
+	hub := createTestTable(t, TableOptions{})
+
+	var sitErr error
+	hub.call(func() {
+		if _, sitErr = BuyIn(user.ID, hub.id, 1000); sitErr == nil {
+			hub.game.Players[0].SitDown(user.ID, user.Name, 1000, 0)
+			hub.game.Players[0].Chips = 1500
+		}
+	})
+	if sitErr != nil {
+		t.Fatalf("买入失败: %v", sitErr)
+	}
+
+	// 兑现期间 hub 协程被占用，其他请求仍然可以访问注册表
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go hub.call(func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+	closed := make(chan error, 1)
+	go func() { closed <- tables.Close(hub.id) }()
+	time.Sleep(100 * time.Millisecond) // 等待 Close 开始等待 hub 协程
+
+	looked := make(chan struct{})
+	go func() {
+		tables.Get(hub.id)
+		close(looked)
+	}()
+	select {
+	case <-looked:
+		close(release)
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatalf("关闭牌桌时注册表被锁住")
+	}
+
+	if err := <-closed; err != nil {
+		t.Fatalf("关闭牌桌失败: %v", err)
+	}
+	if _, ok := tables.Get(hub.id); ok {
+		t.Errorf("关闭后牌桌仍在注册表中")
+	}
+	if err := tables.Close(hub.id); err == nil {
+		t.Errorf("重复关闭牌桌应该失败")
+	}
+	if got, _ := GetUser(user.ID); got.Bankroll != initialBankroll+500 {
+		t.Errorf("资金 = %d, 期望 %d", got.Bankroll, initialBankroll+500)
+	}
+}
@@ -3,22 +3,33 @@ package service
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/lllllan02/holdem/i18n"
 )
 
 // User 用户信息结构
 type User struct {
-	ID        string    `json:"id"`         // 用户唯一标识
-	Name      string    `json:"name"`       // 用户名
-	IP        string    `json:"ip"`         // IP地址
-	UserAgent string    `json:"user_agent"` // 浏览器信息
-	CreatedAt time.Time `json:"created_at"` // 首次访问时间
+	ID        string    `json:"id"`                 // 用户唯一标识
+	Name      string    `json:"name"`               // 用户名
+	Username  string    `json:"username,omitempty"` // 登录账号，游客为空
+	IP        string    `json:"ip"`                 // 最近一次登录的IP地址
+	UserAgent string    `json:"user_agent"`         // 最近一次登录的浏览器信息
+	CreatedAt time.Time `json:"created_at"`         // 首次访问时间
+	Bankroll  int       `json:"bankroll"`           // 不在牌桌上的资金
+	Language  string    `json:"language,omitempty"` // 语言偏好，为空时按浏览器的 Accept-Language
+
+	// 密码哈希，不返回给客户端
+	passwordHash string
+
+	// 游客第一次买入前只保存在内存中，买入时才保存并发放初始资金
+	pending bool
 }
 
 // String 实现 Stringer 接口
@@ -26,63 +37,77 @@ func (u *User) String() string {
 	// 如果用户有名字，就用名字；否则用 ID 的前 8 位
 	name := u.Name
 	if name == "" {
-		name = u.ID[:8]
+		name = u.ID[:min(8, len(u.ID))]
 	}
 	// 使用规范化后的 IP
 	normalizedIP := normalizeIP(u.IP)
 	return fmt.Sprintf("%s@%s", name, normalizedIP)
 }
 
-var users = make(map[string]*User)
-
-// CleanupUserData 清理和重置用户数据（用于解决历史数据不一致问题）
-func CleanupUserData() {
-	log.Printf("[用户数据] 清理开始，原有用户数量: %d", len(users))
-
-	// 创建新的用户映射，基于规范化的数据
-	newUsers := make(map[string]*User)
-
-	for _, user := range users {
-		// 使用规范化的IP重新生成ID
-		normalizedIP := normalizeIP(user.IP)
-		newID := GetUserID(normalizedIP, user.UserAgent)
-
-		// 更新用户数据
-		user.ID = newID
-		user.IP = normalizedIP
-
-		// 如果新ID已存在，保留最新的用户数据
-		if existingUser, exists := newUsers[newID]; exists {
-			if user.CreatedAt.After(existingUser.CreatedAt) {
-				newUsers[newID] = user
-				log.Printf("[用户数据] 替换重复用户 - 新: %s, 旧: %s", user, existingUser)
-			}
-		} else {
-			newUsers[newID] = user
-		}
-	}
+// clone 返回用户信息的副本
+// users 中的用户会被并发修改，交给其他协程使用的都是副本
+func (u *User) clone() *User {
+	copied := *u
+	return &copied
+}
 
-	users = newUsers
-	saveUsers()
-	log.Printf("[用户数据] 清理完成，清理后用户数量: %d", len(users))
+// IsGuest 是否为未注册账号的游客
+func (u *User) IsGuest() bool {
+	return u.Username == ""
 }
 
+var (
+	usersMu   sync.RWMutex
+	users     = make(map[string]*User)
+	userStore UserStore
+)
+
 func init() {
 	// 确保数据目录存在
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		log.Printf("[警告] 创建用户数据目录失败: %v", err)
 	}
+}
+
+// NewDefaultUserStore 创建默认的用户存储，即数据目录下的 users.json
+func NewDefaultUserStore() *FileUserStore {
+	return NewFileUserStore(dataFile)
+}
+
+// InitUsers 设置用户数据的存储并加载已有用户，需要在处理请求之前调用
+// 旧版本的 users.json 只有以 IP+UserAgent 哈希为 ID 的用户，它们会作为游客保留，
+// 凭管理员签发的认领码注册账号时可以认领对应的旧用户，保留用户名、头像和历史记录
+func InitUsers(store UserStore) error {
+	records, err := store.LoadUsers()
+	if err != nil {
+		return err
+	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	userStore = store
+	users = make(map[string]*User, len(records))
+	for _, record := range records {
+		record.User.passwordHash = record.PasswordHash
+		users[record.ID] = record.User
+	}
+	log.Printf("[用户数据] 加载用户 %d 个", len(users))
 
-	// 尝试加载已存在的用户数据
-	data, err := os.ReadFile(dataFile)
-	if err == nil {
-		json.Unmarshal(data, &users)
-		// 清理历史数据以确保一致性
-		CleanupUserData()
+	// 旧版本的用户没有资金，发放初始资金
+	for _, record := range records {
+		if record.HasBankroll {
+			continue
+		}
+		if err := grantBankroll(record.User); err != nil {
+			return fmt.Errorf("发放初始资金失败: %v", err)
+		}
 	}
+	return nil
 }
 
-// GetUserID 根据IP和UserAgent生成用户ID
+// GetUserID 根据IP和UserAgent生成旧版本的用户ID
+// 同一网络出口下的不同用户可能得到相同的ID，不能作为身份凭证，认领旧用户需要认领码
 func GetUserID(ip, userAgent string) string {
 	// 规范化 IP 地址以确保一致性
 	normalizedIP := normalizeIP(ip)
@@ -156,85 +181,187 @@ func generateInitialName(ip, userAgent string) string {
 	return fmt.Sprintf("Player_%s_%s", browser, ipEnd)
 }
 
-// FindUserByUserAgent 根据 UserAgent 查找用户
-func FindUserByUserAgent(userAgent string) *User {
+// GetUser 根据ID获取用户
+func GetUser(id string) (*User, bool) {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	user, ok := users[id]
+	if !ok {
+		return nil, false
+	}
+	return user.clone(), true
+}
+
+// findUserByUsername 根据登录账号查找用户，调用者需持有锁
+func findUserByUsername(username string) *User {
 	for _, user := range users {
-		if user.UserAgent == userAgent {
+		if strings.EqualFold(user.Username, username) {
 			return user
 		}
 	}
 	return nil
 }
 
-func GetOrCreateUser(ip, userAgent string) *User {
+// CreateGuestUser 创建一个新的游客用户
+// 游客先只保存在内存中，显示的资金在第一次买入时才真正发放，没有落座过的游客重启后不再保留
+func CreateGuestUser(ip, userAgent string) *User {
 	normalizedIP := normalizeIP(ip)
-	id := GetUserID(normalizedIP, userAgent)
 
-	log.Printf("[用户查找] IP: %s -> 规范化IP: %s, UserAgent: %s, ID: %s", ip, normalizedIP, userAgent, id)
+	usersMu.Lock()
+	defer usersMu.Unlock()
 
-	// 首先尝试精确匹配
-	if user, ok := users[id]; ok {
-		log.Printf("[用户查找] 精确匹配成功 - 用户: %s", user)
-		return user
+	id := generateUserID()
+	for users[id] != nil {
+		id = generateUserID()
 	}
 
-	// 如果精确匹配失败，尝试通过 UserAgent 查找现有用户
-	if existingUser := FindUserByUserAgent(userAgent); existingUser != nil {
-		log.Printf("[用户查找] 通过UserAgent找到现有用户 - 原用户: %s, 当前请求IP: %s", existingUser, normalizedIP)
-
-		// 更新用户的IP信息为最新的IP
-		existingUser.IP = normalizedIP
-
-		// 也在 users map 中用新的 ID 存储这个用户
-		users[id] = existingUser
-		saveUsers()
-
-		log.Printf("[用户查找] 用户IP已更新 - 更新后用户: %s", existingUser)
-		return existingUser
-	}
-
-	// 如果没有找到匹配的用户，创建新用户
 	user := &User{
 		ID:        id,
 		Name:      generateInitialName(normalizedIP, userAgent),
 		IP:        normalizedIP,
 		UserAgent: userAgent,
 		CreatedAt: time.Now(),
+		Bankroll:  initialBankroll,
+		pending:   true,
 	}
 	users[id] = user
-	saveUsers()
 
-	log.Printf("[用户创建] 新用户 - ID: %s, 用户: %s", id, user)
-	return user
+	log.Printf("[用户创建] 新游客 - ID: %s, 用户: %s", id, user)
+	return user.clone()
+}
+
+// RegisterUser 注册账号
+// guest 不为空时把该游客升级为正式账号；legacyID 不为空时认领该旧版本用户，调用者需先校验认领码。
+// 认领旧用户时不合并游客的数据，已经买入过的游客不能认领，避免丢失游客的资金和对局记录
+func RegisterUser(username, password, ip, userAgent string, guest *User, legacyID string) (*User, error) {
+	hash, err := hashUserPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	normalizedIP := normalizeIP(ip)
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	if findUserByUsername(username) != nil {
+		return nil, fmt.Errorf("账号已存在")
+	}
+
+	var user *User
+	if guest != nil {
+		user = users[guest.ID]
+		if user != nil && !user.IsGuest() {
+			return nil, fmt.Errorf("当前用户已注册账号")
+		}
+	}
+	if legacyID != "" {
+		if user != nil && !user.pending && user.ID != legacyID {
+			return nil, i18n.NewError(i18n.ErrClaimGuestData)
+		}
+		legacy, ok := users[legacyID]
+		if !ok || !legacy.IsGuest() {
+			return nil, fmt.Errorf("旧用户不存在或已被认领")
+		}
+		log.Printf("[用户注册] 认领旧用户 - %s", legacy)
+		user = legacy
+	}
+	created := user == nil
+	if created {
+		id := generateUserID()
+		for users[id] != nil {
+			id = generateUserID()
+		}
+		user = &User{
+			ID:        id,
+			Name:      username,
+			CreatedAt: time.Now(),
+		}
+		users[id] = user
+	}
+
+	user.Username = username
+	user.passwordHash = hash
+	user.IP = normalizedIP
+	user.UserAgent = userAgent
+	if created || user.pending {
+		user.pending = false
+		if err := grantBankroll(user); err != nil {
+			log.Printf("[警告] 保存用户数据失败: %v", err)
+		}
+	} else {
+		saveUser(user)
+	}
+
+	log.Printf("[用户注册] 注册成功 - 账号: %s, 用户: %s", username, user)
+	return user.clone(), nil
+}
+
+// AuthenticateUser 校验账号密码，成功时返回用户
+// 校验密码耗时较长，在锁外进行，不阻塞其他用户的读写
+func AuthenticateUser(username, password, ip, userAgent string) (*User, error) {
+	usersMu.RLock()
+	var id, hash string
+	if user := findUserByUsername(username); user != nil {
+		id, hash = user.ID, user.passwordHash
+	}
+	usersMu.RUnlock()
+
+	if id == "" || !checkUserPassword(password, hash) {
+		return nil, fmt.Errorf("账号或密码错误")
+	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	// 校验期间账号可能被修改，密码哈希不变才算登录成功
+	user, ok := users[id]
+	if !ok || user.passwordHash != hash {
+		return nil, fmt.Errorf("账号或密码错误")
+	}
+
+	user.IP = normalizeIP(ip)
+	user.UserAgent = userAgent
+	saveUser(user)
+	return user.clone(), nil
 }
 
 // UpdateUserName 更新用户名
 func UpdateUserName(id, name string) (*User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	user, exists := users[id]
 	if !exists {
 		return nil, fmt.Errorf("user not found")
 	}
 
 	user.Name = name
-	saveUsers()
-	return user, nil
+	saveUser(user)
+	return user.clone(), nil
 }
 
-// saveUsers 保存用户数据到文件
-func saveUsers() {
-	// 确保数据目录存在
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.Printf("[警告] 创建用户数据目录失败: %v", err)
-		return
+// UpdateUserLanguage 更新用户的语言偏好
+func UpdateUserLanguage(id, language string) (*User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	user, exists := users[id]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
 	}
 
-	data, err := json.MarshalIndent(users, "", "  ")
-	if err != nil {
-		log.Printf("[警告] 序列化用户数据失败: %v", err)
+	user.Language = language
+	saveUser(user)
+	return user.clone(), nil
+}
+
+// saveUser 保存用户数据，调用者需持有锁
+func saveUser(user *User) {
+	if user.pending {
 		return
 	}
-
-	if err := os.WriteFile(dataFile, data, 0644); err != nil {
+	stored := &StoredUser{User: user.clone(), PasswordHash: user.passwordHash, HasBankroll: true}
+	if err := userStore.SaveUser(stored); err != nil {
 		log.Printf("[警告] 保存用户数据失败: %v", err)
 	}
 }
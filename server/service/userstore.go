@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+)
+
+// StoredUser 持久化的用户记录，比 User 多保存密码哈希
+type StoredUser struct {
+	*User
+	PasswordHash string `json:"password_hash,omitempty"`
+	HasBankroll  bool   `json:"has_bankroll,omitempty"` // 是否已发放初始资金，旧版本的用户没有
+}
+
+// UserStore 用户数据和资金流水的存储
+type UserStore interface {
+	// LoadUsers 读取所有用户
+	LoadUsers() ([]*StoredUser, error)
+	// SaveUser 新增或更新一个用户
+	SaveUser(user *StoredUser) error
+	// RecordTransaction 保存资金变化后的用户并追加一条资金流水
+	RecordTransaction(user *StoredUser, tx *Transaction) error
+	// ListTransactions 获取用户最近的资金流水，按时间倒序，limit 不大于 0 时不限制数量
+	ListTransactions(userID string, limit int) ([]*Transaction, error)
+	// ListTableTransactions 获取牌桌上从 from 开始的资金流水，按时间正序
+	ListTableTransactions(tableID string, from time.Time) ([]*Transaction, error)
+}
+
+// FileUserStore 把所有用户保存在一个 JSON 文件中，每次修改都重写整个文件
+// 资金流水追加到同目录下的 ledger.jsonl，每行一条
+type FileUserStore struct {
+	path       string
+	ledgerPath string
+
+	mu    sync.Mutex
+	users map[string]*StoredUser
+}
+
+// NewFileUserStore 创建以 path 为数据文件的用户存储
+func NewFileUserStore(path string) *FileUserStore {
+	return &FileUserStore{
+		path:       path,
+		ledgerPath: filepath.Join(filepath.Dir(path), "ledger.jsonl"),
+		users:      make(map[string]*StoredUser),
+	}
+}
+
+// LoadUsers 读取数据文件，文件不存在时返回空列表
+func (s *FileUserStore) LoadUsers() ([]*StoredUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取用户数据失败: %v", err)
+	}
+
+	stored := make(map[string]*StoredUser)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("解析用户数据失败: %v", err)
+	}
+
+	result := make([]*StoredUser, 0, len(stored))
+	for id, record := range stored {
+		if record.User == nil {
+			continue
+		}
+		s.users[id] = record
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// SaveUser 更新内存中的用户后重写整个数据文件
+func (s *FileUserStore) SaveUser(user *StoredUser) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveUser(user)
+}
+
+// saveUser 重写整个数据文件，调用者需持有锁
+func (s *FileUserStore) saveUser(user *StoredUser) error {
+	s.users[user.ID] = user
+	data, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化用户数据失败: %v", err)
+	}
+
+	if err := poker.WriteFileAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("保存用户数据失败: %v", err)
+	}
+	return nil
+}
+
+// RecordTransaction 先追加流水再保存用户
+// 两步之间中断时流水中的余额是准确的，可以据此修正用户数据
+func (s *FileUserStore) RecordTransaction(user *StoredUser, tx *Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("序列化资金流水失败: %v", err)
+	}
+
+	file, err := os.OpenFile(s.ledgerPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开资金流水失败: %v", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("写入资金流水失败: %v", err)
+	}
+
+	return s.saveUser(user)
+}
+
+// ListTransactions 读取整个流水文件，筛选出用户的流水
+func (s *FileUserStore) ListTransactions(userID string, limit int) ([]*Transaction, error) {
+	transactions, err := s.AllTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Transaction, 0)
+	for i := len(transactions) - 1; i >= 0; i-- {
+		if transactions[i].UserID != userID {
+			continue
+		}
+		result = append(result, transactions[i])
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// ListTableTransactions 读取整个流水文件，筛选出牌桌的流水
+func (s *FileUserStore) ListTableTransactions(tableID string, from time.Time) ([]*Transaction, error) {
+	transactions, err := s.AllTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Transaction, 0)
+	for _, tx := range transactions {
+		if tx.TableID == tableID && tx.Time >= from.UnixMilli() {
+			result = append(result, tx)
+		}
+	}
+	return result, nil
+}
+
+// AllTransactions 按写入顺序读取所有资金流水
+func (s *FileUserStore) AllTransactions() ([]*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.ledgerPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取资金流水失败: %v", err)
+	}
+	defer file.Close()
+
+	transactions := make([]*Transaction, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var tx Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+			continue
+		}
+		transactions = append(transactions, &tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取资金流水失败: %v", err)
+	}
+	return transactions, nil
+}
@@ -3,10 +3,11 @@ package service
 import (
 	"log"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/lllllan02/holdem/i18n"
 )
 
 var upgrader = websocket.Upgrader{
@@ -15,30 +16,88 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin:     func(r *http.Request) bool { return true }, // 仅用于测试，生产环境需要proper的源检查
 }
 
-// 全局 hub 实例
-var globalHub = NewHub()
-
-func init() {
-	// 启动 hub
-	go globalHub.Run()
-}
-
 // WebSocketHandler 处理 WebSocket 连接
+// 通过路由参数 /ws/:tableId、查询参数 ?table= 或邀请码 ?code= 选择牌桌，未指定时加入默认牌桌
+// 私人牌桌需要使用邀请码加入，房主和已经用邀请码加入过的用户可以直接使用牌桌ID
+// 设置了密码的牌桌需要通过 ?password= 提供密码，房主除外
+// 用户身份由 ?token= 中的会话令牌确定，断线重连时通过 ?resume= 带上恢复令牌
+// ?protocol= 指定协议版本，不支持的版本在升级连接前返回 400，连接后的 session 消息确认使用的版本
+// 版本 2 使用增量协议，加入时发送完整状态，之后只发送变化的事件
 func WebSocketHandler(c *gin.Context) {
 	ip := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	log.Printf("[WS] 连接请求 - IP: %s, UserAgent: %s", ip, userAgent)
 
-	// 获取用户信息
-	user := GetOrCreateUser(ip, userAgent)
-	if user == nil {
-		log.Printf("[WS] 用户识别失败 - IP: %s", ip)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未能识别用户"})
+	var hub *Hub
+	var ok bool
+	byCode := c.Query("code") != ""
+	if byCode {
+		code := c.Query("code")
+		hub, ok = tables.GetByInviteCode(code)
+	} else {
+		tableID := c.Param("tableId")
+		if tableID == "" {
+			tableID = c.DefaultQuery("table", DefaultTableID)
+		}
+		hub, ok = tables.Get(tableID)
+	}
+	if !ok {
+		log.Printf("[WS] 牌桌不存在 - %s", c.Request.URL.RawQuery)
+		c.JSON(http.StatusNotFound, gin.H{"error": "牌桌不存在"})
+		return
+	}
+	tableID := hub.id
+
+	// 获取通过令牌校验的用户
+	user := currentUser(c)
+
+	// 检查访问权限、封禁和密码
+	var allowed, banned, isHost bool
+	hub.call(func() {
+		allowed = byCode || hub.canAccess(user.ID)
+		banned = hub.banned[user.ID]
+		isHost = hub.isHost(user.ID)
+	})
+	if !allowed {
+		// 与牌桌不存在时的返回相同，不暴露私人牌桌
+		log.Printf("[WS] 私人牌桌需要邀请码 - %s, 牌桌: %s", user, tableID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "牌桌不存在"})
+		return
+	}
+	if banned {
+		log.Printf("[WS] 用户已被房主封禁 - %s, 牌桌: %s", user, tableID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "你已被房主移出该牌桌"})
 		return
 	}
+	if !isHost && !hub.checkPassword(c.Query("password")) {
+		log.Printf("[WS] 牌桌密码错误 - %s, 牌桌: %s", user, tableID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "牌桌密码错误"})
+		return
+	}
+	if byCode {
+		hub.call(func() {
+			hub.members[user.ID] = true
+		})
+	}
 
 	log.Printf("[WS] 收到连接请求 - %s", user)
 
+	protocol := ProtocolSnapshot
+	if value := c.Query("protocol"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < ProtocolSnapshot || version > ProtocolLatest {
+			log.Printf("[WS] 不支持的协议版本 - %s, 版本: %s", user, value)
+			lang := i18n.Resolve(user.Language, c.GetHeader("Accept-Language"))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":       i18n.T(lang, string(i18n.ErrUnsupportedProtocol)),
+				"code":        i18n.ErrUnsupportedProtocol,
+				"maxProtocol": ProtocolLatest,
+			})
+			return
+		}
+		protocol = version
+	}
+
 	// 升级 HTTP 连接为 WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -48,30 +107,26 @@ func WebSocketHandler(c *gin.Context) {
 
 	// 创建新的客户端
 	client := &Client{
-		hub:  globalHub,
-		user: user,
-		send: make(chan []byte, 256),
-		conn: conn,
+		hub:            hub,
+		user:           user,
+		send:           make(chan []byte, 256),
+		conn:           conn,
+		resumeToken:    c.Query("resume"),
+		protocol:       protocol,
+		acceptLanguage: c.GetHeader("Accept-Language"),
 	}
 
-	// 注册客户端到 hub
-	client.hub.register <- client
+	// 注册客户端到 hub（牌桌可能已经关闭），注册后 hub 会发送当前游戏状态
+	select {
+	case client.hub.register <- client:
+	case <-client.hub.quit:
+		conn.Close()
+		return
+	}
 
 	// 启动读写协程
 	go client.writePump()
 	go client.readPump()
 
-	// 发送当前游戏状态给新连接的客户端
-	// 放在最后，确保客户端已经完全初始化
-	go func() {
-		// 等待一小段时间确保客户端完全准备好
-		time.Sleep(200 * time.Millisecond)
-
-		// 检查客户端是否仍然在hub中（避免竞态条件）
-		if _, exists := globalHub.clients[user.ID]; exists {
-			client.sendGameState()
-		}
-	}()
-
-	log.Printf("[WS] 连接建立成功 - %s, 当前在线人数: %d\n", user, len(globalHub.clients))
+	log.Printf("[WS] 连接建立成功 - %s, 牌桌: %s\n", user, tableID)
 }
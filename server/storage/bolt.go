@@ -0,0 +1,487 @@
+// Package storage 提供基于 bbolt 嵌入式数据库的存储实现
+// 同时实现 service.UserStore 和 poker.RecordStore，用户、资金流水、对局、行动记录、筹码流水和聊天消息保存在同一个数据库文件中
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+	"github.com/lllllan02/holdem/service"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultPath 默认的数据库文件路径
+const DefaultPath = "data/holdem.db"
+
+// 数据库中的 bucket
+var (
+	bucketUsers      = []byte("users")              // 用户ID -> 用户
+	bucketTxs        = []byte("transactions")       // 用户ID+序号 -> 资金流水
+	bucketTableTxs   = []byte("table_transactions") // 牌桌ID+序号 -> 资金流水
+	bucketRounds     = []byte("rounds")             // 对局ID -> 对局记录（不含行动）
+	bucketActions    = []byte("actions")            // 对局ID -> 行动记录
+	bucketRoundTimes = []byte("round_times")        // 开始时间+对局ID，按时间查询对局
+	bucketLedger     = []byte("ledger")             // 用户ID+开始时间+对局ID -> 该局的筹码变化
+	bucketHands      = []byte("hands")              // 牌桌ID -> 已分配的最大手牌编号
+	bucketChat       = []byte("chat")               // 牌桌ID+对局ID+序号 -> 聊天消息
+)
+
+// LedgerEntry 筹码流水，记录用户在一局中的筹码变化
+type LedgerEntry struct {
+	UserID     string `json:"userId"`     // 用户ID
+	RoundID    string `json:"roundId"`    // 对局ID
+	Time       int64  `json:"time"`       // 对局开始时间
+	Position   int    `json:"position"`   // 座位位置
+	InitChips  int    `json:"initChips"`  // 开局时的筹码
+	FinalChips int    `json:"finalChips"` // 结束时的筹码
+	Change     int    `json:"change"`     // 筹码变化
+}
+
+// BoltStore 基于 bbolt 的存储
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBolt 打开或创建数据库文件
+func OpenBolt(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		indexed := tx.Bucket(bucketTableTxs) != nil
+		for _, name := range [][]byte{bucketUsers, bucketTxs, bucketTableTxs, bucketRounds, bucketActions, bucketRoundTimes, bucketLedger, bucketHands, bucketChat} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if !indexed {
+			return indexTableTransactions(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库失败: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// LoadUsers 读取所有用户
+func (s *BoltStore) LoadUsers() ([]*service.StoredUser, error) {
+	users := make([]*service.StoredUser, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketUsers).ForEach(func(k, v []byte) error {
+			var user service.StoredUser
+			if err := json.Unmarshal(v, &user); err != nil {
+				return fmt.Errorf("解析用户 %s 失败: %v", k, err)
+			}
+			if user.User != nil {
+				users = append(users, &user)
+			}
+			return nil
+		})
+	})
+	return users, err
+}
+
+// SaveUser 新增或更新一个用户
+func (s *BoltStore) SaveUser(user *service.StoredUser) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("序列化用户数据失败: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketUsers).Put([]byte(user.ID), data)
+	})
+}
+
+// RecordTransaction 在一个事务中保存用户和资金流水
+func (s *BoltStore) RecordTransaction(user *service.StoredUser, record *service.Transaction) error {
+	userData, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("序列化用户数据失败: %v", err)
+	}
+	txData, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化资金流水失败: %v", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketUsers).Put([]byte(user.ID), userData); err != nil {
+			return err
+		}
+		return putTransaction(tx, record, txData)
+	})
+}
+
+// appendTransaction 只追加资金流水，用于导入
+func (s *BoltStore) appendTransaction(record *service.Transaction) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化资金流水失败: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putTransaction(tx, record, data)
+	})
+}
+
+// emptyTransactions 数据库中是否还没有资金流水
+func (s *BoltStore) emptyTransactions() (bool, error) {
+	empty := true
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(bucketTxs).Cursor().First()
+		empty = k == nil
+		return nil
+	})
+	return empty, err
+}
+
+// ListTableTransactions 顺序遍历牌桌的资金流水
+func (s *BoltStore) ListTableTransactions(tableID string, from time.Time) ([]*service.Transaction, error) {
+	prefix := keyPrefix(tableID)
+	transactions := make([]*service.Transaction, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketTableTxs).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var record service.Transaction
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("解析资金流水失败: %v", err)
+			}
+			if record.Time >= from.UnixMilli() {
+				transactions = append(transactions, &record)
+			}
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+// putTransaction 以 用户ID+序号 为键保存资金流水，有牌桌时同时以 牌桌ID+序号 保存一份
+// 序号在整个 bucket 内递增，同一用户或牌桌的流水按写入顺序排列
+func putTransaction(tx *bolt.Tx, record *service.Transaction, data []byte) error {
+	txs := tx.Bucket(bucketTxs)
+	seq, err := txs.NextSequence()
+	if err != nil {
+		return err
+	}
+	if err := txs.Put(append(keyPrefix(record.UserID), encodeUint64(seq)...), data); err != nil {
+		return err
+	}
+	if record.TableID == "" {
+		return nil
+	}
+	return tx.Bucket(bucketTableTxs).Put(append(keyPrefix(record.TableID), encodeUint64(seq)...), data)
+}
+
+// indexTableTransactions 为没有牌桌索引的旧数据库建立索引
+func indexTableTransactions(tx *bolt.Tx) error {
+	tableTxs := tx.Bucket(bucketTableTxs)
+	return tx.Bucket(bucketTxs).ForEach(func(k, v []byte) error {
+		var record service.Transaction
+		if err := json.Unmarshal(v, &record); err != nil || record.TableID == "" || len(k) < 8 {
+			return nil
+		}
+		return tableTxs.Put(append(keyPrefix(record.TableID), k[len(k)-8:]...), v)
+	})
+}
+
+// ListTransactions 倒序遍历用户的资金流水
+func (s *BoltStore) ListTransactions(userID string, limit int) ([]*service.Transaction, error) {
+	prefix := keyPrefix(userID)
+	transactions := make([]*service.Transaction, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketTxs).Cursor()
+
+		// 定位到用户的最后一条流水
+		k, v := c.Seek(append(keyPrefix(userID), encodeUint64(math.MaxUint64)...))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Prev() {
+			var record service.Transaction
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("解析资金流水失败: %v", err)
+			}
+			transactions = append(transactions, &record)
+			if limit > 0 && len(transactions) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+// NextRoundID 在事务中递增牌桌的手牌编号
+func (s *BoltStore) NextRoundID(tableID string) (string, error) {
+	var number uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		hands := tx.Bucket(bucketHands)
+		if v := hands.Get([]byte(tableID)); v != nil {
+			number = binary.BigEndian.Uint64(v)
+		}
+		number++
+		return hands.Put([]byte(tableID), encodeUint64(number))
+	})
+	if err != nil {
+		return "", err
+	}
+	return poker.FormatRoundID(tableID, int(number)), nil
+}
+
+// SaveRound 在一个事务中保存对局、行动、时间索引和每个玩家的筹码流水
+// 覆盖已有的同ID对局时先删除旧的索引
+func (s *BoltStore) SaveRound(record *poker.GameRound) error {
+	round := *record
+	round.Actions = nil
+	roundData, err := json.Marshal(poker.NewStoredRound(&round))
+	if err != nil {
+		return fmt.Errorf("序列化对局记录失败: %v", err)
+	}
+	actionData, err := json.Marshal(record.Actions)
+	if err != nil {
+		return fmt.Errorf("序列化行动记录失败: %v", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rounds := tx.Bucket(bucketRounds)
+		id := []byte(record.RoundID)
+
+		if old := rounds.Get(id); old != nil {
+			var previous poker.GameRound
+			if err := json.Unmarshal(old, &previous); err == nil {
+				if err := deleteRoundIndexes(tx, &previous); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := rounds.Put(id, roundData); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketActions).Put(id, actionData); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketRoundTimes).Put(timeKey(nil, record.StartTime, record.RoundID), nil); err != nil {
+			return err
+		}
+
+		ledger := tx.Bucket(bucketLedger)
+		for _, player := range record.Players {
+			entry, err := json.Marshal(LedgerEntry{
+				UserID:     player.UserId,
+				RoundID:    record.RoundID,
+				Time:       record.StartTime,
+				Position:   player.Position,
+				InitChips:  player.InitChips,
+				FinalChips: player.FinalChips,
+				Change:     player.ChipsChange,
+			})
+			if err != nil {
+				return err
+			}
+			if err := ledger.Put(timeKey(keyPrefix(player.UserId), record.StartTime, record.RoundID), entry); err != nil {
+				return err
+			}
+		}
+
+		// 导入的记录可能来自其他存储，保证之后分配的编号不会与之重复
+		if tableID, number, ok := poker.ParseRoundID(record.RoundID); ok {
+			hands := tx.Bucket(bucketHands)
+			if v := hands.Get([]byte(tableID)); v == nil || binary.BigEndian.Uint64(v) < uint64(number) {
+				if err := hands.Put([]byte(tableID), encodeUint64(uint64(number))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// deleteRoundIndexes 删除对局的时间索引和筹码流水
+func deleteRoundIndexes(tx *bolt.Tx, record *poker.GameRound) error {
+	if err := tx.Bucket(bucketRoundTimes).Delete(timeKey(nil, record.StartTime, record.RoundID)); err != nil {
+		return err
+	}
+	ledger := tx.Bucket(bucketLedger)
+	for _, player := range record.Players {
+		if err := ledger.Delete(timeKey(keyPrefix(player.UserId), record.StartTime, record.RoundID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRound 根据对局ID获取记录
+func (s *BoltStore) GetRound(roundID string) (*poker.GameRound, error) {
+	var record *poker.GameRound
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = getRound(tx, []byte(roundID))
+		return err
+	})
+	return record, err
+}
+
+// getRound 读取对局和行动记录
+func getRound(tx *bolt.Tx, id []byte) (*poker.GameRound, error) {
+	data := tx.Bucket(bucketRounds).Get(id)
+	if data == nil {
+		return nil, fmt.Errorf("对局记录不存在: %s", id)
+	}
+
+	stored := poker.StoredRound{GameRound: &poker.GameRound{}}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("解析对局记录失败: %s, %v", id, err)
+	}
+	record := stored.Round()
+
+	record.Actions = make([]poker.Action, 0)
+	if actions := tx.Bucket(bucketActions).Get(id); actions != nil {
+		if err := json.Unmarshal(actions, &record.Actions); err != nil {
+			return nil, fmt.Errorf("解析行动记录失败: %s, %v", id, err)
+		}
+	}
+	return record, nil
+}
+
+// ListRounds 通过时间索引倒序查询
+func (s *BoltStore) ListRounds(from, to time.Time, limit int) ([]*poker.GameRound, error) {
+	return s.listRounds(bucketRoundTimes, nil, from, to, limit)
+}
+
+// ListUserRounds 通过用户的筹码流水倒序查询
+func (s *BoltStore) ListUserRounds(userID string, from, to time.Time, limit int) ([]*poker.GameRound, error) {
+	return s.listRounds(bucketLedger, keyPrefix(userID), from, to, limit)
+}
+
+// listRounds 在 prefix+开始时间+对局ID 形式的索引中，从 to 开始向前遍历到 from
+func (s *BoltStore) listRounds(bucket []byte, prefix []byte, from, to time.Time, limit int) ([]*poker.GameRound, error) {
+	records := make([]*poker.GameRound, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+
+		// 定位到最后一个小于 to 的键
+		k, _ := c.Seek(timeKey(prefix, to.Unix(), ""))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Prev() {
+			start, roundID := splitTimeKey(k[len(prefix):])
+			if start < from.Unix() {
+				break
+			}
+
+			record, err := getRound(tx, []byte(roundID))
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+			if limit > 0 && len(records) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+// SaveChat 以 牌桌ID+对局ID+序号 为键保存聊天消息，同一对局的消息按写入顺序排列
+func (s *BoltStore) SaveChat(message *poker.ChatMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("序列化聊天消息失败: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		chat := tx.Bucket(bucketChat)
+		seq, err := chat.NextSequence()
+		if err != nil {
+			return err
+		}
+		return chat.Put(append(chatPrefix(message.TableID, message.RoundID), encodeUint64(seq)...), data)
+	})
+}
+
+// ListChat 顺序遍历对局的聊天消息
+func (s *BoltStore) ListChat(roundID string) ([]*poker.ChatMessage, error) {
+	messages := make([]*poker.ChatMessage, 0)
+	tableID, _, ok := poker.ParseRoundID(roundID)
+	if !ok {
+		return messages, nil
+	}
+
+	prefix := chatPrefix(tableID, roundID)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketChat).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var message poker.ChatMessage
+			if err := json.Unmarshal(v, &message); err != nil {
+				return fmt.Errorf("解析聊天消息失败: %v", err)
+			}
+			messages = append(messages, &message)
+		}
+		return nil
+	})
+	return messages, err
+}
+
+// emptyChat 数据库中是否还没有聊天消息
+func (s *BoltStore) emptyChat() (bool, error) {
+	empty := true
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(bucketChat).Cursor().First()
+		empty = k == nil
+		return nil
+	})
+	return empty, err
+}
+
+// chatPrefix 牌桌ID+对局ID 形式的聊天消息键前缀，第一局开始前的消息对局ID为空
+func chatPrefix(tableID, roundID string) []byte {
+	return append(keyPrefix(tableID), keyPrefix(roundID)...)
+}
+
+// userPrefix 以用户ID或牌桌ID开头的键的前缀
+func keyPrefix(id string) []byte {
+	return append([]byte(id), 0)
+}
+
+// timeKey 生成 prefix+8字节大端开始时间+对局ID 形式的键，按字节序即按时间排序
+func timeKey(prefix []byte, start int64, roundID string) []byte {
+	key := make([]byte, 0, len(prefix)+8+len(roundID))
+	key = append(key, prefix...)
+	key = append(key, encodeUint64(uint64(start))...)
+	return append(key, roundID...)
+}
+
+// splitTimeKey 拆分去掉前缀后的时间键
+func splitTimeKey(key []byte) (int64, string) {
+	if len(key) < 8 {
+		return 0, ""
+	}
+	return int64(binary.BigEndian.Uint64(key[:8])), string(key[8:])
+}
+
+func encodeUint64(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
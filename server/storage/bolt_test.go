@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+	"github.com/lllllan02/holdem/service"
+)
+
+// openTestBolt 在临时目录中打开数据库，测试结束时关闭
+func openTestBolt(t *testing.T, path string) *BoltStore {
+	t.Helper()
+	store, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testRound 构造一局，开始时间为 start，userIDs 为参与的用户
+func testRound(roundID string, start time.Time, userIDs ...string) *poker.GameRound {
+	record := &poker.GameRound{
+		RoundID:   roundID,
+		StartTime: start.Unix(),
+		Deck:      make([]poker.Card, 52),
+		Actions:   []poker.Action{{UserId: "u1", Type: poker.ActionFold}},
+	}
+	for i, userID := range userIDs {
+		record.Players = append(record.Players, poker.PlayerRoundInfo{UserId: userID, Position: i})
+	}
+	return record
+}
+
+// roundIDs 返回记录的对局ID
+func roundIDs(records []*poker.GameRound) []string {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.RoundID)
+	}
+	return ids
+}
+
+func TestBoltStoreNextRoundID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holdem.db")
+	store, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+
+	next := func(store *BoltStore, tableID string) string {
+		t.Helper()
+		id, err := store.NextRoundID(tableID)
+		if err != nil {
+			t.Fatalf("分配对局ID失败: %v", err)
+		}
+		return id
+	}
+
+	var ids []string
+	for _, tableID := range []string{"a", "a", "b"} {
+		ids = append(ids, next(store, tableID))
+	}
+	// 从其他存储导入的记录编号更大时，之后从它继续
+	if err := store.SaveRound(testRound(poker.FormatRoundID("b", 7), time.Now(), "u1")); err != nil {
+		t.Fatalf("保存对局记录失败: %v", err)
+	}
+	store.Close()
+
+	store = openTestBolt(t, path)
+	ids = append(ids, next(store, "a"), next(store, "b"))
+
+	want := []string{"a-00000001", "a-00000002", "b-00000001", "a-00000003", "b-00000008"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("对局ID = %v, 期望 %v", ids, want)
+	}
+}
+
+func TestBoltStoreRounds(t *testing.T) {
+	store := openTestBolt(t, filepath.Join(t.TempDir(), "holdem.db"))
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+	rounds := []*poker.GameRound{
+		testRound("t-00000001", day, "u1", "u2"),
+		testRound("t-00000002", day.Add(time.Hour), "u2", "u3"),
+		testRound("t-00000003", day.AddDate(0, 0, 1), "u1", "u3"),
+		// 覆盖已有的对局时旧的时间和玩家索引一起替换
+		testRound("t-00000001", day.Add(2*time.Hour), "u3", "u4"),
+	}
+	for _, record := range rounds {
+		if err := store.SaveRound(record); err != nil {
+			t.Fatalf("保存对局记录失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		from   time.Time
+		to     time.Time
+		limit  int
+		want   []string
+	}{
+		{
+			name: "全部对局按时间倒序",
+			from: day.AddDate(0, 0, -1),
+			to:   day.AddDate(0, 0, 2),
+			want: []string{"t-00000003", "t-00000001", "t-00000002"},
+		},
+		{
+			name:  "限制数量",
+			from:  day.AddDate(0, 0, -1),
+			to:    day.AddDate(0, 0, 2),
+			limit: 2,
+			want:  []string{"t-00000003", "t-00000001"},
+		},
+		{
+			name: "时间范围包含开始不包含结束",
+			from: day,
+			to:   day.Add(2 * time.Hour),
+			want: []string{"t-00000002"},
+		},
+		{
+			name:   "用户参与的对局",
+			userID: "u1",
+			from:   day.AddDate(0, 0, -1),
+			to:     day.AddDate(0, 0, 2),
+			want:   []string{"t-00000003"},
+		},
+		{
+			name:   "覆盖后的玩家",
+			userID: "u4",
+			from:   day.AddDate(0, 0, -1),
+			to:     day.AddDate(0, 0, 2),
+			want:   []string{"t-00000001"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records []*poker.GameRound
+			var err error
+			if tt.userID == "" {
+				records, err = store.ListRounds(tt.from, tt.to, tt.limit)
+			} else {
+				records, err = store.ListUserRounds(tt.userID, tt.from, tt.to, tt.limit)
+			}
+			if err != nil {
+				t.Fatalf("查询对局记录失败: %v", err)
+			}
+			if got := roundIDs(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("对局 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+
+	// 行动记录单独保存，读取时合并回对局
+	record, err := store.GetRound("t-00000002")
+	if err != nil {
+		t.Fatalf("读取对局记录失败: %v", err)
+	}
+	if len(record.Actions) != 1 || len(record.Deck) != 52 {
+		t.Errorf("行动 %d 条，牌堆 %d 张，期望 1 条和 52 张", len(record.Actions), len(record.Deck))
+	}
+	if _, err := store.GetRound("t-00000009"); err == nil {
+		t.Errorf("读取不存在的对局没有返回错误")
+	}
+}
+
+func TestBoltStoreTransactions(t *testing.T) {
+	store := openTestBolt(t, filepath.Join(t.TempDir(), "holdem.db"))
+	user := &service.StoredUser{User: &service.User{ID: "u1", Bankroll: 10000}}
+
+	txs := []*service.Transaction{
+		{UserID: "u1", Type: service.TxGrant, Amount: 10000, Balance: 10000, Time: 1},
+		{UserID: "u1", Type: service.TxBuyIn, Amount: -500, Balance: 9500, TableID: "t1", Time: 2},
+		{UserID: "u2", Type: service.TxBuyIn, Amount: -300, Balance: 9700, TableID: "t1", Time: 3},
+		{UserID: "u1", Type: service.TxCashOut, Amount: 700, Balance: 10200, TableID: "t1", Time: 4},
+	}
+	for _, tx := range txs {
+		if err := store.RecordTransaction(user, tx); err != nil {
+			t.Fatalf("保存资金流水失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		list  func() ([]*service.Transaction, error)
+		times []int64
+	}{
+		{
+			name:  "用户流水倒序",
+			list:  func() ([]*service.Transaction, error) { return store.ListTransactions("u1", 0) },
+			times: []int64{4, 2, 1},
+		},
+		{
+			name:  "限制数量",
+			list:  func() ([]*service.Transaction, error) { return store.ListTransactions("u1", 2) },
+			times: []int64{4, 2},
+		},
+		{
+			name:  "没有流水的用户",
+			list:  func() ([]*service.Transaction, error) { return store.ListTransactions("u", 0) },
+			times: []int64{},
+		},
+		{
+			name:  "牌桌流水正序",
+			list:  func() ([]*service.Transaction, error) { return store.ListTableTransactions("t1", time.UnixMilli(0)) },
+			times: []int64{2, 3, 4},
+		},
+		{
+			name:  "牌桌流水从指定时间开始",
+			list:  func() ([]*service.Transaction, error) { return store.ListTableTransactions("t1", time.UnixMilli(3)) },
+			times: []int64{3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, err := tt.list()
+			if err != nil {
+				t.Fatalf("查询资金流水失败: %v", err)
+			}
+			times := make([]int64, 0, len(transactions))
+			for _, tx := range transactions {
+				times = append(times, tx.Time)
+			}
+			if !reflect.DeepEqual(times, tt.times) {
+				t.Errorf("流水时间 = %v, 期望 %v", times, tt.times)
+			}
+		})
+	}
+
+	users, err := store.LoadUsers()
+	if err != nil || len(users) != 1 || users[0].Bankroll != 10000 {
+		t.Errorf("LoadUsers = %v, %v, 期望一个资金为 10000 的用户", users, err)
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+	"github.com/lllllan02/holdem/service"
+)
+
+// ImportResult 导入的数据数量
+type ImportResult struct {
+	Users   int // 导入的用户数
+	Txs     int // 导入的资金流水数
+	Rounds  int // 导入的对局数
+	Renamed int // 因ID重复而重新命名的旧对局数
+	Chat    int // 导入的聊天消息数
+}
+
+// ImportFiles 把 JSON 文件中的用户、资金流水和对局记录导入数据库，已存在的同ID数据会被覆盖，
+// 资金流水没有ID，只在数据库中还没有流水时导入，因此可以重复执行
+// 旧版本的对局ID是时分秒，不同日期会重复，重复的旧对局改用 日期+时分秒 作为ID
+// 聊天消息随所属的对局导入，同样只在数据库中还没有消息时导入，第一局开始前的消息不导入
+func ImportFiles(db *BoltStore, users *service.FileUserStore, records poker.RecordStore) (ImportResult, error) {
+	var result ImportResult
+
+	storedUsers, err := users.LoadUsers()
+	if err != nil {
+		return result, err
+	}
+	for _, user := range storedUsers {
+		if err := db.SaveUser(user); err != nil {
+			return result, fmt.Errorf("导入用户 %s 失败: %v", user.ID, err)
+		}
+		result.Users++
+	}
+	log.Printf("[导入] 已导入用户 %d 个", result.Users)
+
+	empty, err := db.emptyTransactions()
+	if err != nil {
+		return result, err
+	}
+	if empty {
+		transactions, err := users.AllTransactions()
+		if err != nil {
+			return result, err
+		}
+		for _, tx := range transactions {
+			if err := db.appendTransaction(tx); err != nil {
+				return result, fmt.Errorf("导入资金流水失败: %v", err)
+			}
+			result.Txs++
+		}
+		log.Printf("[导入] 已导入资金流水 %d 条", result.Txs)
+	} else {
+		log.Printf("[导入] 数据库中已有资金流水，跳过")
+	}
+
+	rounds, err := records.ListRounds(time.Unix(0, 0), time.Now().AddDate(1, 0, 0), 0)
+	if err != nil {
+		return result, err
+	}
+
+	// 记录按时间倒序，最新的一局保留原ID，与文件索引中同ID时指向最新一条的规则一致
+	seen := make(map[string]bool, len(rounds))
+	for _, round := range rounds {
+		if seen[round.RoundID] {
+			renamed := time.Unix(round.StartTime, 0).Format("20060102") + round.RoundID
+			log.Printf("[导入] 对局ID重复，%s 重新命名为 %s", round.RoundID, renamed)
+			round.RoundID = renamed
+			result.Renamed++
+		}
+		seen[round.RoundID] = true
+
+		if err := db.SaveRound(round); err != nil {
+			return result, fmt.Errorf("导入对局 %s 失败: %v", round.RoundID, err)
+		}
+		result.Rounds++
+	}
+	log.Printf("[导入] 已导入对局 %d 局，其中重新命名 %d 局", result.Rounds, result.Renamed)
+
+	// 聊天消息和资金流水一样没有ID，只在数据库中还没有消息时导入
+	empty, err = db.emptyChat()
+	if err != nil {
+		return result, err
+	}
+	if !empty {
+		log.Printf("[导入] 数据库中已有聊天消息，跳过")
+		return result, nil
+	}
+	for roundID := range seen {
+		messages, err := records.ListChat(roundID)
+		if err != nil {
+			return result, err
+		}
+		for _, message := range messages {
+			if err := db.SaveChat(message); err != nil {
+				return result, fmt.Errorf("导入聊天消息失败: %v", err)
+			}
+			result.Chat++
+		}
+	}
+	log.Printf("[导入] 已导入聊天消息 %d 条", result.Chat)
+
+	return result, nil
+}
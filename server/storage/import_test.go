@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lllllan02/holdem/poker"
+	"github.com/lllllan02/holdem/service"
+)
+
+// newTestFiles 在临时目录中准备 JSON 文件存储的用户、资金流水、对局和聊天消息
+// 旧版本的对局ID 143025 在两天中各出现一次
+func newTestFiles(t *testing.T) (*service.FileUserStore, poker.RecordStore) {
+	t.Helper()
+	dir := t.TempDir()
+	users := service.NewFileUserStore(filepath.Join(dir, "users.json"))
+	records := poker.NewFileRecordStore(filepath.Join(dir, "records"))
+
+	user := &service.StoredUser{User: &service.User{ID: "u1", Name: "玩家1", Bankroll: 9500}, HasBankroll: true}
+	for _, tx := range []*service.Transaction{
+		{UserID: "u1", Type: service.TxGrant, Amount: 10000, Balance: 10000, Time: 1},
+		{UserID: "u1", Type: service.TxBuyIn, Amount: -500, Balance: 9500, TableID: "t", Time: 2},
+	} {
+		if err := users.RecordTransaction(user, tx); err != nil {
+			t.Fatalf("保存资金流水失败: %v", err)
+		}
+	}
+
+	day := time.Date(2024, 5, 1, 14, 30, 25, 0, time.Local)
+	for _, record := range []*poker.GameRound{
+		testRound("143025", day, "u1"),
+		testRound("143025", day.AddDate(0, 0, 1), "u1"),
+		testRound("t-00000001", day.AddDate(0, 0, 2), "u1"),
+	} {
+		if err := records.SaveRound(record); err != nil {
+			t.Fatalf("保存对局记录失败: %v", err)
+		}
+	}
+
+	for _, message := range []*poker.ChatMessage{
+		{TableID: "t", Text: "开局前", Time: 1},
+		{TableID: "t", RoundID: "t-00000001", Text: "你好", Time: 2},
+		{TableID: "t", RoundID: "t-00000001", Text: "再见", Time: 3},
+	} {
+		if err := records.SaveChat(message); err != nil {
+			t.Fatalf("保存聊天消息失败: %v", err)
+		}
+	}
+	return users, records
+}
+
+func TestImportFiles(t *testing.T) {
+	users, records := newTestFiles(t)
+	db := openTestBolt(t, filepath.Join(t.TempDir(), "holdem.db"))
+
+	tests := []struct {
+		name string
+		want ImportResult
+	}{
+		{name: "首次导入", want: ImportResult{Users: 1, Txs: 2, Rounds: 3, Renamed: 1, Chat: 2}},
+		// 没有ID的资金流水和聊天消息不会重复导入
+		{name: "重复导入", want: ImportResult{Users: 1, Rounds: 3, Renamed: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ImportFiles(db, users, records)
+			if err != nil {
+				t.Fatalf("导入失败: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("导入结果 = %+v, 期望 %+v", result, tt.want)
+			}
+
+			// 最新的一局保留原ID，较早的一局加上日期
+			rounds, err := db.ListUserRounds("u1", time.Unix(0, 0), time.Now(), 0)
+			if err != nil {
+				t.Fatalf("查询对局记录失败: %v", err)
+			}
+			want := []string{"t-00000001", "143025", "20240501143025"}
+			if got := roundIDs(rounds); !reflect.DeepEqual(got, want) {
+				t.Errorf("对局 = %v, 期望 %v", got, want)
+			}
+
+			transactions, err := db.ListTransactions("u1", 0)
+			if err != nil || len(transactions) != 2 {
+				t.Errorf("资金流水 %d 条, %v, 期望 2 条", len(transactions), err)
+			}
+			messages, err := db.ListChat("t-00000001")
+			if err != nil || len(messages) != 2 {
+				t.Errorf("聊天消息 %d 条, %v, 期望 2 条", len(messages), err)
+			}
+		})
+	}
+}